@@ -1,10 +1,11 @@
 create table if not exists products (
   id bigserial primary key,
   name varchar(255) not null,
-  "type" varchar(10) not null,
   price integer not null,
-  stocks integer not null,
+  stock integer not null,
   sold integer default 0 not null,
   created_time timestamp with time zone default now() not null,
   updated_time timestamp with time zone default now() not null
 );
+
+create index if not exists products_sold_id_idx on products (sold desc, id desc);
@@ -0,0 +1,11 @@
+-- products was created with a stocks column while the code always used
+-- stock, rename it on databases created before 01_products.sql was fixed
+do $$
+begin
+  if exists (
+    select 1 from information_schema.columns
+    where table_name = 'products' and column_name = 'stocks'
+  ) then
+    alter table products rename column stocks to stock;
+  end if;
+end $$;
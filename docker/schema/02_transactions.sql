@@ -10,3 +10,6 @@ create table if not exists transactions (
   constraint transactions_product_id_fk foreign key (product_id)
     references products(id)
 );
+
+create index if not exists transactions_created_time_id_idx on transactions (created_time desc, id desc);
+create index if not exists transactions_user_id_created_time_idx on transactions (user_id, created_time desc, id desc);
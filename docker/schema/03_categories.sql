@@ -0,0 +1,53 @@
+create table if not exists categories (
+  id bigserial primary key,
+  parent_id bigint,
+  name varchar(255) not null,
+  slug varchar(100) not null,
+  created_time timestamp with time zone default now() not null,
+  updated_time timestamp with time zone default now() not null,
+  constraint categories_slug_key unique (slug),
+  constraint categories_parent_id_fk foreign key (parent_id)
+    references categories(id)
+);
+
+create index if not exists categories_parent_id_idx on categories (parent_id);
+
+create table if not exists product_categories (
+  product_id bigint not null,
+  category_id bigint not null,
+  created_time timestamp with time zone default now() not null,
+  constraint product_categories_pk primary key (product_id, category_id),
+  constraint product_categories_product_id_fk foreign key (product_id)
+    references products(id) on delete cascade,
+  constraint product_categories_category_id_fk foreign key (category_id)
+    references categories(id)
+);
+
+create index if not exists product_categories_category_id_idx on product_categories (category_id);
+
+-- seed the categories that used to be hard coded as product type
+insert into categories (name, slug)
+values ('Hats', 'hats'), ('Tops', 'tops'), ('Shorts', 'shorts')
+on conflict (slug) do nothing;
+
+-- migrate existing product type into categories
+do $$
+begin
+  if exists (
+    select 1 from information_schema.columns
+    where table_name = 'products' and column_name = 'type'
+  ) then
+    insert into categories (name, slug)
+    select distinct initcap(p."type"), lower(p."type")
+    from products p
+    on conflict (slug) do nothing;
+
+    insert into product_categories (product_id, category_id)
+    select p.id, c.id
+    from products p
+    inner join categories c on c.slug = lower(p."type")
+    on conflict do nothing;
+
+    alter table products drop column "type";
+  end if;
+end $$;
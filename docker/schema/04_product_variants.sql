@@ -0,0 +1,30 @@
+create table if not exists product_variants (
+  id bigserial primary key,
+  product_id bigint not null,
+  sku varchar(64) not null,
+  options jsonb default '{}'::jsonb not null,
+  price integer,
+  stock integer default 0 not null,
+  sold integer default 0 not null,
+  created_time timestamp with time zone default now() not null,
+  updated_time timestamp with time zone default now() not null,
+  constraint product_variants_sku_key unique (sku),
+  constraint product_variants_stock_check check (stock >= 0),
+  constraint product_variants_product_id_fk foreign key (product_id)
+    references products(id) on delete cascade
+);
+
+create index if not exists product_variants_product_id_idx on product_variants (product_id);
+
+alter table transactions add column if not exists variant_id bigint;
+
+do $$
+begin
+  if not exists (
+    select 1 from information_schema.table_constraints
+    where constraint_name = 'transactions_variant_id_fk'
+  ) then
+    alter table transactions add constraint transactions_variant_id_fk
+      foreign key (variant_id) references product_variants(id);
+  end if;
+end $$;
@@ -0,0 +1,16 @@
+create table if not exists product_images (
+  id bigserial primary key,
+  product_id bigint not null,
+  storage_key varchar(255) not null,
+  thumbnail_key varchar(255) not null,
+  url varchar(1024) not null,
+  thumbnail_url varchar(1024) not null,
+  content_type varchar(50) not null,
+  size integer not null,
+  position integer default 0 not null,
+  created_time timestamp with time zone default now() not null,
+  constraint product_images_product_id_fk foreign key (product_id)
+    references products(id) on delete cascade
+);
+
+create index if not exists product_images_product_id_position_idx on product_images (product_id, position, id);
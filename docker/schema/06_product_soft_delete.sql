@@ -0,0 +1,5 @@
+alter table products add column if not exists deleted_at timestamp with time zone;
+
+drop index if exists products_sold_id_idx;
+create index if not exists products_sold_id_idx on products (sold desc, id desc) where deleted_at is null;
+create index if not exists products_deleted_at_id_idx on products (deleted_at desc, id desc) where deleted_at is not null;
@@ -0,0 +1,32 @@
+create table if not exists product_audits (
+  id bigserial primary key,
+  product_id bigint not null,
+  user_id bigint,
+  username varchar(50) default '' not null,
+  action varchar(30) not null,
+  before jsonb,
+  after jsonb,
+  diff jsonb default '{}'::jsonb not null,
+  created_time timestamp with time zone default now() not null
+);
+
+create index if not exists product_audits_product_id_idx on product_audits (product_id, created_time desc, id desc);
+
+-- price series, variant_id null is the product base price and a variant row
+-- with null price means the variant follows the base price
+create table if not exists product_prices (
+  id bigserial primary key,
+  product_id bigint not null,
+  variant_id bigint,
+  price integer,
+  valid_from timestamp with time zone default now() not null,
+  valid_to timestamp with time zone
+);
+
+create index if not exists product_prices_lookup_idx on product_prices (product_id, variant_id, valid_from desc);
+
+-- seed the current prices so history starts from the existing catalog
+insert into product_prices (product_id, price, valid_from)
+select p.id, p.price, p.created_time
+from products p
+where not exists (select 1 from product_prices pp where pp.product_id = p.id);
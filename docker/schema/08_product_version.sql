@@ -0,0 +1 @@
+alter table products add column if not exists version integer default 1 not null;
@@ -0,0 +1,31 @@
+-- every stock change as signed delta, the stock of a product without
+-- variants is the sum of its rows with variant_id null, the stock of a
+-- variant the sum of its own rows
+create table if not exists stock_movements (
+  id bigserial primary key,
+  product_id bigint not null,
+  variant_id bigint,
+  delta integer not null,
+  reason varchar(30) not null,
+  note text default '' not null,
+  user_id bigint,
+  username varchar(50) default '' not null,
+  created_time timestamp with time zone default now() not null
+);
+
+create index if not exists stock_movements_product_id_idx on stock_movements (product_id, created_time desc, id desc);
+create index if not exists stock_movements_variant_id_idx on stock_movements (variant_id) where variant_id is not null;
+
+-- opening balance so the log reconciles with the existing stock
+insert into stock_movements (product_id, delta, reason)
+select p.id, p.stock, 'opening'
+from products p
+where p.stock <> 0
+  and not exists (select 1 from product_variants v where v.product_id = p.id)
+  and not exists (select 1 from stock_movements m where m.product_id = p.id and m.variant_id is null);
+
+insert into stock_movements (product_id, variant_id, delta, reason)
+select v.product_id, v.id, v.stock, 'opening'
+from product_variants v
+where v.stock <> 0
+  and not exists (select 1 from stock_movements m where m.variant_id = v.id);
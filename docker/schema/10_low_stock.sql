@@ -0,0 +1,7 @@
+-- reorder_threshold 0 disable the alert, low_stock_alerted remember an alert
+-- was sent so it fires once per drop below the threshold
+alter table products add column if not exists reorder_threshold integer default 0 not null;
+alter table products add column if not exists low_stock_alerted boolean default false not null;
+
+create index if not exists products_low_stock_idx on products ((reorder_threshold - stock) desc, id desc)
+  where stock < reorder_threshold and deleted_at is null;
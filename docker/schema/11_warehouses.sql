@@ -0,0 +1,63 @@
+create table if not exists warehouses (
+  id bigserial primary key,
+  code varchar(30) not null,
+  name varchar(255) not null,
+  latitude double precision,
+  longitude double precision,
+  priority integer default 0 not null,
+  active boolean default true not null,
+  created_time timestamp with time zone default now() not null,
+  updated_time timestamp with time zone default now() not null,
+  constraint warehouses_code_key unique (code)
+);
+
+-- stock of a product (variant_id null) or of a SKU in one warehouse, the
+-- stock of products and product_variants is the sum over warehouses
+create table if not exists warehouse_stocks (
+  id bigserial primary key,
+  warehouse_id bigint not null,
+  product_id bigint not null,
+  variant_id bigint,
+  stock integer default 0 not null,
+  constraint warehouse_stocks_stock_check check (stock >= 0),
+  constraint warehouse_stocks_warehouse_id_fk foreign key (warehouse_id)
+    references warehouses(id),
+  constraint warehouse_stocks_product_id_fk foreign key (product_id)
+    references products(id) on delete cascade,
+  constraint warehouse_stocks_variant_id_fk foreign key (variant_id)
+    references product_variants(id) on delete cascade
+);
+
+create unique index if not exists warehouse_stocks_item_idx on warehouse_stocks (warehouse_id, product_id, (coalesce(variant_id, 0)));
+create index if not exists warehouse_stocks_product_id_idx on warehouse_stocks (product_id, variant_id);
+
+alter table stock_movements add column if not exists warehouse_id bigint;
+alter table transactions add column if not exists warehouse_id bigint;
+
+-- everything in stock so far was in one place
+insert into warehouses (code, name)
+select 'main', 'Main warehouse'
+where not exists (select 1 from warehouses);
+
+insert into warehouse_stocks (warehouse_id, product_id, stock)
+select w.id, p.id, p.stock
+from products p
+cross join (select id from warehouses order by priority, id limit 1) w
+where p.stock > 0
+  and not exists (select 1 from product_variants v where v.product_id = p.id)
+  and not exists (select 1 from warehouse_stocks ws where ws.product_id = p.id);
+
+insert into warehouse_stocks (warehouse_id, product_id, variant_id, stock)
+select w.id, v.product_id, v.id, v.stock
+from product_variants v
+cross join (select id from warehouses order by priority, id limit 1) w
+where v.stock > 0
+  and not exists (select 1 from warehouse_stocks ws where ws.variant_id = v.id);
+
+update stock_movements
+  set warehouse_id = (select id from warehouses order by priority, id limit 1)
+where warehouse_id is null;
+
+update transactions
+  set warehouse_id = (select id from warehouses order by priority, id limit 1)
+where warehouse_id is null;
@@ -0,0 +1,40 @@
+-- reserved is the part of stock held for checkouts, only stock - reserved can
+-- be sold or reserved again
+alter table warehouse_stocks add column if not exists reserved integer default 0 not null;
+
+do $$
+begin
+  if not exists (
+    select 1 from information_schema.table_constraints
+    where constraint_name = 'warehouse_stocks_reserved_check'
+  ) then
+    alter table warehouse_stocks add constraint warehouse_stocks_reserved_check
+      check (reserved >= 0 and reserved <= stock);
+  end if;
+end $$;
+
+create table if not exists stock_reservations (
+  id bigserial primary key,
+  user_id bigint not null,
+  product_id bigint not null,
+  variant_id bigint,
+  warehouse_id bigint not null,
+  quantity integer not null,
+  status varchar(20) default 'held' not null,
+  expires_time timestamp with time zone not null,
+  created_time timestamp with time zone default now() not null,
+  updated_time timestamp with time zone default now() not null,
+  constraint stock_reservations_quantity_check check (quantity > 0),
+  constraint stock_reservations_user_id_fk foreign key (user_id)
+    references users(id),
+  constraint stock_reservations_product_id_fk foreign key (product_id)
+    references products(id) on delete cascade,
+  constraint stock_reservations_warehouse_id_fk foreign key (warehouse_id)
+    references warehouses(id)
+);
+
+-- the sweeper only looks at holds
+create index if not exists stock_reservations_held_idx on stock_reservations (expires_time)
+  where status = 'held';
+
+alter table transactions add column if not exists reservation_id bigint;
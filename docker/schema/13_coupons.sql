@@ -0,0 +1,64 @@
+create table if not exists coupons (
+  id bigserial primary key,
+  code varchar(50) not null,
+  description text default '' not null,
+  type varchar(20) not null,
+  value integer not null,
+  max_discount integer,
+  min_spend integer default 0 not null,
+  usage_limit integer,
+  per_user_limit integer,
+  used_count integer default 0 not null,
+  valid_from timestamp with time zone,
+  valid_until timestamp with time zone,
+  active boolean default true not null,
+  created_time timestamp with time zone default now() not null,
+  updated_time timestamp with time zone default now() not null,
+  constraint coupons_code_key unique (code),
+  constraint coupons_value_check check (value > 0)
+);
+
+create index if not exists coupons_created_time_id_idx on coupons (created_time desc, id desc);
+
+-- eligibility, a coupon without products and categories applies to everything
+create table if not exists coupon_products (
+  coupon_id bigint not null,
+  product_id bigint not null,
+  primary key (coupon_id, product_id),
+  constraint coupon_products_coupon_id_fk foreign key (coupon_id)
+    references coupons(id) on delete cascade,
+  constraint coupon_products_product_id_fk foreign key (product_id)
+    references products(id) on delete cascade
+);
+
+create table if not exists coupon_categories (
+  coupon_id bigint not null,
+  category_id bigint not null,
+  primary key (coupon_id, category_id),
+  constraint coupon_categories_coupon_id_fk foreign key (coupon_id)
+    references coupons(id) on delete cascade,
+  constraint coupon_categories_category_id_fk foreign key (category_id)
+    references categories(id) on delete cascade
+);
+
+alter table transactions add column if not exists subtotal integer default 0 not null;
+alter table transactions add column if not exists discount integer default 0 not null;
+alter table transactions add column if not exists total integer default 0 not null;
+alter table transactions add column if not exists coupon_id bigint;
+
+create table if not exists coupon_redemptions (
+  id bigserial primary key,
+  coupon_id bigint not null,
+  user_id bigint not null,
+  transaction_id bigint not null,
+  discount integer not null,
+  created_time timestamp with time zone default now() not null,
+  constraint coupon_redemptions_coupon_id_fk foreign key (coupon_id)
+    references coupons(id),
+  constraint coupon_redemptions_transaction_id_fk foreign key (transaction_id)
+    references transactions(id),
+  constraint coupon_redemptions_transaction_id_key unique (transaction_id)
+);
+
+create index if not exists coupon_redemptions_coupon_id_idx on coupon_redemptions (coupon_id, created_time desc, id desc);
+create index if not exists coupon_redemptions_user_idx on coupon_redemptions (coupon_id, user_id);
@@ -0,0 +1,48 @@
+create table if not exists promotions (
+  id bigserial primary key,
+  name varchar(255) not null,
+  kind varchar(20) not null,
+  -- sale: percent_off or a fixed sale_price, bundle: buy_quantity get free_quantity
+  percent_off integer,
+  sale_price integer,
+  buy_quantity integer,
+  free_quantity integer,
+  starts_at timestamp with time zone not null,
+  ends_at timestamp with time zone not null,
+  active boolean default true not null,
+  -- last schedule boundary handled by the scheduler: scheduled, running or ended
+  state varchar(20) default 'scheduled' not null,
+  created_time timestamp with time zone default now() not null,
+  updated_time timestamp with time zone default now() not null,
+  constraint promotions_kind_check check (kind in ('sale', 'bundle')),
+  constraint promotions_schedule_check check (ends_at > starts_at)
+);
+
+create index if not exists promotions_created_time_id_idx on promotions (created_time desc, id desc);
+create index if not exists promotions_pending_idx on promotions (starts_at, ends_at) where state <> 'ended';
+
+create table if not exists promotion_products (
+  promotion_id bigint not null,
+  product_id bigint not null,
+  primary key (promotion_id, product_id),
+  constraint promotion_products_promotion_id_fk foreign key (promotion_id)
+    references promotions(id) on delete cascade,
+  constraint promotion_products_product_id_fk foreign key (product_id)
+    references products(id) on delete cascade
+);
+
+create index if not exists promotion_products_product_id_idx on promotion_products (product_id);
+
+create table if not exists promotion_categories (
+  promotion_id bigint not null,
+  category_id bigint not null,
+  primary key (promotion_id, category_id),
+  constraint promotion_categories_promotion_id_fk foreign key (promotion_id)
+    references promotions(id) on delete cascade,
+  constraint promotion_categories_category_id_fk foreign key (category_id)
+    references categories(id) on delete cascade
+);
+
+-- price actually charged per item and the items given for free by a bundle
+alter table transactions add column if not exists unit_price integer default 0 not null;
+alter table transactions add column if not exists free_items integer default 0 not null;
@@ -0,0 +1,29 @@
+create table if not exists product_reviews (
+  id bigserial primary key,
+  product_id bigint not null,
+  user_id bigint not null,
+  rating smallint not null,
+  body text default '' not null,
+  status varchar(20) default 'pending' not null,
+  moderation_note text default '' not null,
+  moderated_by bigint,
+  created_time timestamp with time zone default now() not null,
+  updated_time timestamp with time zone default now() not null,
+  constraint product_reviews_product_id_fk foreign key (product_id)
+    references products(id) on delete cascade,
+  constraint product_reviews_user_id_fk foreign key (user_id)
+    references users(id),
+  constraint product_reviews_product_user_key unique (product_id, user_id),
+  constraint product_reviews_rating_check check (rating between 1 and 5),
+  constraint product_reviews_status_check check (status in ('pending', 'approved', 'rejected'))
+);
+
+create index if not exists product_reviews_product_idx on product_reviews (product_id, status, created_time desc, id desc);
+create index if not exists product_reviews_status_idx on product_reviews (status, created_time desc, id desc);
+
+-- aggregate of the approved reviews, kept up to date on every moderation
+alter table products add column if not exists rating_average numeric(3,2) default 0 not null;
+alter table products add column if not exists rating_count integer default 0 not null;
+
+create index if not exists products_rating_idx on products (((rating_average * 100)::bigint) desc, id desc)
+  where deleted_at is null;
@@ -0,0 +1,48 @@
+-- optional, back in stock notifications are mailed to it
+alter table users add column if not exists email varchar(255);
+
+create table if not exists wishlists (
+  user_id bigint not null,
+  product_id bigint not null,
+  created_time timestamp with time zone default now() not null,
+  primary key (user_id, product_id),
+  constraint wishlists_user_id_fk foreign key (user_id)
+    references users(id) on delete cascade,
+  constraint wishlists_product_id_fk foreign key (product_id)
+    references products(id) on delete cascade
+);
+
+create index if not exists wishlists_product_id_idx on wishlists (product_id);
+create index if not exists wishlists_user_created_idx on wishlists (user_id, created_time desc, product_id desc);
+
+-- in_stock follow stock > 0, every flip to true is a new restock
+alter table products add column if not exists in_stock boolean default true not null;
+alter table products add column if not exists restock_count integer default 0 not null;
+update products set in_stock = stock > 0 where in_stock <> (stock > 0);
+
+-- pending notifications are claimed as sending, delivered outside of any
+-- transaction and then marked sent, or pending again until next_attempt_time
+create table if not exists restock_notifications (
+  id bigserial primary key,
+  product_id bigint not null,
+  user_id bigint not null,
+  restock integer not null,
+  status varchar(20) default 'pending' not null,
+  attempts integer default 0 not null,
+  last_error text default '' not null,
+  next_attempt_time timestamp with time zone default now() not null,
+  claimed_time timestamp with time zone,
+  created_time timestamp with time zone default now() not null,
+  sent_time timestamp with time zone,
+  constraint restock_notifications_product_id_fk foreign key (product_id)
+    references products(id) on delete cascade,
+  constraint restock_notifications_user_id_fk foreign key (user_id)
+    references users(id) on delete cascade,
+  -- at most one notification per user and restock
+  constraint restock_notifications_once_key unique (product_id, user_id, restock)
+);
+
+create index if not exists restock_notifications_pending_idx on restock_notifications (next_attempt_time, id)
+  where status = 'pending';
+create index if not exists restock_notifications_sending_idx on restock_notifications (claimed_time)
+  where status = 'sending';
@@ -0,0 +1,16 @@
+-- frequently bought together, rebuilt from the order history by the
+-- recommendation job
+create table if not exists product_relations (
+  product_id bigint not null,
+  related_id bigint not null,
+  score integer not null,
+  rank integer not null,
+  updated_time timestamp with time zone default now() not null,
+  primary key (product_id, related_id),
+  constraint product_relations_product_id_fk foreign key (product_id)
+    references products(id) on delete cascade,
+  constraint product_relations_related_id_fk foreign key (related_id)
+    references products(id) on delete cascade
+);
+
+create index if not exists product_relations_rank_idx on product_relations (product_id, rank);
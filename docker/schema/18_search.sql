@@ -0,0 +1,22 @@
+-- trigram index on product names, it serves the search and the name
+-- completions and follows every insert, rename and delete by itself
+create extension if not exists pg_trgm;
+
+create index if not exists products_name_trgm_idx on products using gin (lower(name) gin_trgm_ops)
+  where deleted_at is null;
+
+-- search queries, normalized, with how often they were searched and found nothing
+create table if not exists search_queries (
+  id bigserial primary key,
+  query varchar(100) not null,
+  searches integer default 0 not null,
+  zero_results integer default 0 not null,
+  last_results integer default 0 not null,
+  last_searched_time timestamp with time zone default now() not null,
+  constraint search_queries_query_key unique (query)
+);
+
+create index if not exists search_queries_prefix_idx on search_queries (query text_pattern_ops, searches desc)
+  where last_results > 0;
+create index if not exists search_queries_zero_results_idx on search_queries (zero_results desc, id desc)
+  where zero_results > 0;
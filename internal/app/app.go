@@ -1,27 +1,46 @@
 package app
 
 import (
+	"context"
 	"net/http"
 
 	"ordent/internal/config"
+	"ordent/internal/pkg/cursor"
+	"ordent/internal/pkg/etag"
 
 	"ordent/internal/server"
+	serverMid "ordent/internal/server/middleware"
 
 	// Repositories
 	productRepo "ordent/internal/repository/product"
 	userRepo "ordent/internal/repository/user"
   transactionRepo "ordent/internal/repository/transaction"
+  categoryRepo "ordent/internal/repository/category"
+  warehouseRepo "ordent/internal/repository/warehouse"
+  couponRepo "ordent/internal/repository/coupon"
+  promotionRepo "ordent/internal/repository/promotion"
+  wishlistRepo "ordent/internal/repository/wishlist"
 
 	// Usecases
 	userUsc "ordent/internal/usecase/user"
   productUsc "ordent/internal/usecase/product"
   transactionUsc "ordent/internal/usecase/transaction"
+  categoryUsc "ordent/internal/usecase/category"
+  inventoryUsc "ordent/internal/usecase/inventory"
+  couponUsc "ordent/internal/usecase/coupon"
+  promotionUsc "ordent/internal/usecase/promotion"
+  wishlistUsc "ordent/internal/usecase/wishlist"
 
 	// Controllers
 	ctrls "ordent/internal/controller"
 	userCtrl "ordent/internal/controller/user"
   productCtrl "ordent/internal/controller/product"
   transactionCtrl "ordent/internal/controller/transaction"
+  categoryCtrl "ordent/internal/controller/category"
+  inventoryCtrl "ordent/internal/controller/inventory"
+  couponCtrl "ordent/internal/controller/coupon"
+  promotionCtrl "ordent/internal/controller/promotion"
+  wishlistCtrl "ordent/internal/controller/wishlist"
 
 	"github.com/labstack/echo/v4"
 	echoMid "github.com/labstack/echo/v4/middleware"
@@ -31,31 +50,66 @@ func InitHTTPServer(cfg config.Config) server.HTTPServerItf {
   // Drivers
   db := connectDatabase(cfg.Database)
   redis := connectRedis(cfg.Redis)
+  blobStorage := connectStorage(cfg.Storage)
+  notify := connectNotifier(cfg.Notifier)
+  cursorSigner := cursor.NewSigner(cfg.Pagination)
 
   // Initialize Repositories
   userRepository := userRepo.NewRepository(db, redis, cfg.JWT)
   productRepository := productRepo.NewRepository(db, redis)
-  transactionRepository := transactionRepo.NewRepository(db, redis)
+  transactionRepository := transactionRepo.NewRepository(db, redis, productRepository)
+  categoryRepository := categoryRepo.NewRepository(db)
+  warehouseRepository := warehouseRepo.NewRepository(db)
+  couponRepository := couponRepo.NewRepository(db)
+  promotionRepository := promotionRepo.NewRepository(db)
+  wishlistRepository := wishlistRepo.NewRepository(db)
 
 
   // Initialize Usecases
   userUsecase := userUsc.NewUsecase(userRepository)
-  productUsecase := productUsc.NewUsecase(productRepository)
-  transactionUsecase := transactionUsc.NewUsecase(transactionRepository, productRepository)
+  productUsecase := productUsc.NewUsecase(productRepository, categoryRepository, blobStorage, cfg.Storage, cfg.Recommendation, cursorSigner)
+  wishlistUsecase := wishlistUsc.NewUsecase(wishlistRepository, productRepository, notify, cfg.Wishlist, cursorSigner)
+  inventoryUsecase := inventoryUsc.NewUsecase(productRepository, warehouseRepository, wishlistUsecase, notify, cfg.Inventory, cursorSigner)
+  couponUsecase := couponUsc.NewUsecase(couponRepository, productRepository, cursorSigner)
+  promotionUsecase := promotionUsc.NewUsecase(promotionRepository, productRepository, cfg.Promotion, cursorSigner)
+  transactionUsecase := transactionUsc.NewUsecase(transactionRepository, productRepository, inventoryUsecase, wishlistUsecase, couponUsecase, cfg.Inventory, cursorSigner)
+  categoryUsecase := categoryUsc.NewUsecase(categoryRepository, productRepository)
+
+  // release expired stock reservations in the background
+  go inventoryUsecase.RunReservationSweeper(context.Background())
+  // refresh cached prices when promotions start and end
+  go promotionUsecase.RunPromotionScheduler(context.Background())
+  // deliver back in stock notifications to wishlists
+  go wishlistUsecase.RunRestockNotifier(context.Background())
+  // rebuild frequently bought together products from the order history
+  go productUsecase.RunRelatedJob(context.Background())
 
   // Initialize Controllers
   userController := userCtrl.NewController(userUsecase)
   productController := productCtrl.NewController(userUsecase, productUsecase)
   transactionController := transactionCtrl.NewController(userUsecase, transactionUsecase)
+  categoryController := categoryCtrl.NewController(userUsecase, categoryUsecase)
+  inventoryController := inventoryCtrl.NewController(userUsecase, inventoryUsecase)
+  couponController := couponCtrl.NewController(userUsecase, couponUsecase)
+  promotionController := promotionCtrl.NewController(userUsecase, promotionUsecase)
+  wishlistController := wishlistCtrl.NewController(userUsecase, wishlistUsecase)
 
 
   controllers := ctrls.NewControllers(
     userController,
     productController,
     transactionController,
+    categoryController,
+    inventoryController,
+    couponController,
+    promotionController,
+    wishlistController,
   )
 
   preMiddlewares := []echo.MiddlewareFunc{
+		// before routing so every response, errors included, carries the id
+		// the error handler reports
+		echoMid.RequestID(),
 		echoMid.RemoveTrailingSlashWithConfig(echoMid.TrailingSlashConfig{
 			RedirectCode: http.StatusMovedPermanently,
 		}),
@@ -68,9 +122,9 @@ func InitHTTPServer(cfg config.Config) server.HTTPServerItf {
 			Skipper:      echoMid.DefaultSkipper,
 			AllowOrigins: []string{"*"}, 
 			AllowMethods: []string{http.MethodGet, http.MethodHead, http.MethodPut, http.MethodPatch, http.MethodPost, http.MethodDelete},
+			ExposeHeaders: []string{etag.HeaderETag, serverMid.HeaderDeprecation, serverMid.HeaderLink, echo.HeaderXRequestID},
 		}),
 		echoMid.Recover(),
-		echoMid.RequestID(),
 	}
 
   httpServerItf := server.NewHTTPServer(cfg, controllers, preMiddlewares, allMiddlewares)
@@ -0,0 +1,117 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"ordent/internal/config"
+	"ordent/internal/pkg/cursor"
+
+	productRepo "ordent/internal/repository/product"
+	categoryRepo "ordent/internal/repository/category"
+
+	productUsc "ordent/internal/usecase/product"
+)
+
+// RunCommand run a command line task instead of the http server, e.g.
+//
+//	ordent import -file products.csv -dry-run
+//	ordent export -format jsonl -out products.jsonl
+//	ordent related
+func RunCommand(cfg config.Config, args []string) error {
+  ctx := context.Background()
+
+  switch args[0] {
+  case "import":
+    flags := flag.NewFlagSet("import", flag.ContinueOnError)
+    file := flags.String("file", "", "catalog file to import")
+    format := flags.String("format", "", "csv or jsonl, default from file extension")
+    dryRun := flags.Bool("dry-run", false, "only validate the file")
+    if err := flags.Parse(args[1:]); err != nil {
+      return err
+    }
+
+    if *file == "" {
+      return errors.New("import requires -file")
+    }
+
+    if *format == "" {
+      *format = strings.TrimPrefix(strings.ToLower(filepath.Ext(*file)), ".")
+    }
+
+    reader, err := os.Open(*file)
+    if err != nil {
+      return err
+    }
+    defer reader.Close()
+
+    result, err := newProductUsecase(cfg).ImportProducts(ctx, *format, reader, *dryRun)
+    if err != nil {
+      return err
+    }
+
+    encoder := json.NewEncoder(os.Stdout)
+    encoder.SetIndent("", "  ")
+    if err = encoder.Encode(result); err != nil {
+      return err
+    }
+
+    if len(result.Errors) > 0 {
+      return errors.New("import finished with errors")
+    }
+
+    return nil
+
+  case "export":
+    flags := flag.NewFlagSet("export", flag.ContinueOnError)
+    format := flags.String("format", "csv", "csv or jsonl")
+    out := flags.String("out", "", "output file, default stdout")
+    if err := flags.Parse(args[1:]); err != nil {
+      return err
+    }
+
+    var writer io.Writer = os.Stdout
+    if *out != "" {
+      file, err := os.Create(*out)
+      if err != nil {
+        return err
+      }
+      defer file.Close()
+      writer = file
+    }
+
+    return newProductUsecase(cfg).ExportProducts(ctx, *format, writer)
+
+  case "related":
+    built, err := newProductUsecase(cfg).BuildRelated(ctx)
+    if err != nil {
+      return err
+    }
+
+    log.Printf("stored %d related products", built)
+    return nil
+  }
+
+  return errors.New("unknown command " + args[0])
+}
+
+func newProductUsecase(cfg config.Config) *productUsc.Usecase {
+  db := connectDatabase(cfg.Database)
+  redis := connectRedis(cfg.Redis)
+
+  return productUsc.NewUsecase(
+    productRepo.NewRepository(db, redis),
+    categoryRepo.NewRepository(db),
+    connectStorage(cfg.Storage),
+    cfg.Storage,
+    cfg.Recommendation,
+    cursor.NewSigner(cfg.Pagination),
+  )
+}
@@ -9,6 +9,8 @@ import (
 	_ "github.com/lib/pq"
 
   "ordent/internal/pkg/redigo"
+  "ordent/internal/pkg/storage"
+  "ordent/internal/pkg/notifier"
 	"ordent/internal/config"
 
 	"github.com/jmoiron/sqlx"
@@ -50,3 +52,25 @@ func connectRedis(config config.Redis) *redigo.Redis {
   log.Print("Connecting Redis")
   return redigo.New(config)
 }
+
+func connectStorage(config config.Storage) storage.Storage {
+  log.Printf("Connecting %s Storage", config.Driver)
+
+  blobStorage, err := storage.New(config)
+  if err != nil {
+    log.Fatal("failed to init storage", err)
+  }
+
+  return blobStorage
+}
+
+func connectNotifier(config config.Notifier) notifier.Notifier {
+  log.Printf("Connecting %s Notifier", config.Driver)
+
+  notify, err := notifier.New(config)
+  if err != nil {
+    log.Fatal("failed to init notifier", err)
+  }
+
+  return notify
+}
@@ -8,10 +8,17 @@ import (
 
 type (
 	Config struct {
-		HTTPServer HTTPServer
-		Database   Database
-		Redis      Redis
-		JWT        JWT
+		HTTPServer     HTTPServer
+		Database       Database
+		Redis          Redis
+		JWT            JWT
+		Pagination     Pagination
+		Storage        Storage
+		Notifier       Notifier
+		Inventory      Inventory
+		Promotion      Promotion
+		Wishlist       Wishlist
+		Recommendation Recommendation
 	}
 
 	HTTPServer struct {
@@ -42,6 +49,82 @@ type (
 	JWT struct {
 		Secret string
 	}
+
+	Pagination struct {
+		Secret string
+	}
+
+	Storage struct {
+		Driver         string
+		MaxUploadSize  int64
+		ThumbnailWidth int
+		Local          LocalStorage
+		S3             S3Storage
+	}
+
+	LocalStorage struct {
+		Directory string
+		BaseURL   string
+	}
+
+	S3Storage struct {
+		Endpoint  string
+		Region    string
+		Bucket    string
+		AccessKey string
+		SecretKey string
+		BaseURL   string
+	}
+
+	// Inventory.Allocation is the warehouse allocation strategy at checkout:
+	// priority, most_stock or nearest. Reservations hold stock for
+	// ReservationTTL and expired ones are released every SweepInterval.
+	Inventory struct {
+		Allocation     string
+		ReservationTTL time.Duration
+		SweepInterval  time.Duration
+	}
+
+	// Promotion.SweepInterval is how often promotions that started or ended
+	// are picked up to refresh the cached prices of their products.
+	Promotion struct {
+		SweepInterval time.Duration
+	}
+
+	// Wishlist.NotifyInterval is how often queued back in stock notifications
+	// are delivered, BatchSize of them per run.
+	Wishlist struct {
+		NotifyInterval time.Duration
+		BatchSize      int
+	}
+
+	// Recommendation.Interval is how often the frequently bought together
+	// products are rebuilt from the order history, TopN kept per product.
+	Recommendation struct {
+		Interval time.Duration
+		TopN     int
+	}
+
+	Notifier struct {
+		Driver  string
+		Webhook WebhookNotifier
+		Email   EmailNotifier
+	}
+
+	WebhookNotifier struct {
+		URL     string
+		Secret  string
+		Timeout time.Duration
+	}
+
+	EmailNotifier struct {
+		Host     string
+		Port     int
+		Username string
+		Password string
+		From     string
+		To       []string
+	}
 )
 
 func Init() (*Config, error) {
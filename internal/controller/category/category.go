@@ -0,0 +1,167 @@
+package category
+
+import (
+	"context"
+	"net/http"
+	enCategory "ordent/internal/entity/category"
+	enUser "ordent/internal/entity/user"
+	"ordent/internal/pkg/apperror"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+)
+
+type (
+	userUsecase interface {
+		GetUserSession(sess enUser.Session) *enUser.SessionData
+	}
+
+	categoryUsecase interface {
+		InsertCategory(ctx context.Context, form enCategory.CategoryRequest) (int64, error)
+		UpdateCategory(ctx context.Context, form enCategory.CategoryRequest) error
+		DeleteCategory(ctx context.Context, categoryID int64) error
+		GetCategory(ctx context.Context, categoryID int64) (*enCategory.Category, error)
+		GetCategories(ctx context.Context) ([]enCategory.Category, error)
+	}
+)
+
+type Controller struct {
+	userUsc     userUsecase
+	categoryUsc categoryUsecase
+}
+
+func NewController(
+	userUsc userUsecase,
+	categoryUsc categoryUsecase,
+) *Controller {
+	return &Controller{
+		userUsc:     userUsc,
+		categoryUsc: categoryUsc,
+	}
+}
+
+func (c *Controller) InsertCategory(ctx echo.Context) error {
+
+	session := ctx.Get(enUser.SessionContextKey).(enUser.Session)
+
+	sessionData := c.userUsc.GetUserSession(session)
+	if sessionData == nil {
+		return apperror.ErrUnauthorized
+	}
+
+	if !session.IsAdmin {
+		return apperror.ErrNotAdmin
+	}
+
+	form := enCategory.CategoryRequest{}
+
+	if err := ctx.Bind(&form); err != nil {
+		return apperror.ErrBadRequest
+	}
+
+	categoryID, err := c.categoryUsc.InsertCategory(ctx.Request().Context(), form)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(http.StatusOK,
+		map[string]interface{}{
+			"Status": "Success",
+			"ID":     categoryID,
+		},
+	)
+}
+
+func (c *Controller) UpdateCategory(ctx echo.Context) error {
+
+	session := ctx.Get(enUser.SessionContextKey).(enUser.Session)
+
+	sessionData := c.userUsc.GetUserSession(session)
+	if sessionData == nil {
+		return apperror.ErrUnauthorized
+	}
+
+	if !session.IsAdmin {
+		return apperror.ErrNotAdmin
+	}
+
+	form := enCategory.CategoryRequest{}
+
+	if err := ctx.Bind(&form); err != nil {
+		return apperror.ErrBadRequest
+	}
+
+	err := c.categoryUsc.UpdateCategory(ctx.Request().Context(), form)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(http.StatusOK,
+		map[string]interface{}{
+			"Status": "Success",
+		},
+	)
+}
+
+func (c *Controller) DeleteCategory(ctx echo.Context) error {
+
+	session := ctx.Get(enUser.SessionContextKey).(enUser.Session)
+
+	sessionData := c.userUsc.GetUserSession(session)
+	if sessionData == nil {
+		return apperror.ErrUnauthorized
+	}
+
+	if !session.IsAdmin {
+		return apperror.ErrNotAdmin
+	}
+
+	categoryID, err := strconv.ParseInt(ctx.QueryParam("categoryID"), 0, 64)
+	if err != nil {
+		return apperror.ErrBadRequest
+	}
+
+	err = c.categoryUsc.DeleteCategory(ctx.Request().Context(), categoryID)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(http.StatusOK,
+		map[string]interface{}{
+			"Status": "Success",
+		},
+	)
+}
+
+func (c *Controller) GetCategory(ctx echo.Context) error {
+	categoryID, err := strconv.ParseInt(ctx.QueryParam("categoryID"), 0, 64)
+	if err != nil {
+		return apperror.ErrBadRequest
+	}
+
+	category, err := c.categoryUsc.GetCategory(ctx.Request().Context(), categoryID)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(http.StatusOK,
+		map[string]interface{}{
+			"Status": "Success",
+			"Data":   category,
+		},
+	)
+}
+
+func (c *Controller) GetCategories(ctx echo.Context) error {
+	categories, err := c.categoryUsc.GetCategories(ctx.Request().Context())
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(http.StatusOK,
+		map[string]interface{}{
+			"Status": "Success",
+			"Data":   categories,
+		},
+	)
+}
@@ -4,22 +4,42 @@ import (
   "ordent/internal/controller/user"
   "ordent/internal/controller/product"
   "ordent/internal/controller/transaction"
+  "ordent/internal/controller/category"
+  "ordent/internal/controller/inventory"
+  "ordent/internal/controller/coupon"
+  "ordent/internal/controller/promotion"
+  "ordent/internal/controller/wishlist"
 )
 
 type Controllers struct {
   User *user.Controller
   Product *product.Controller
   Transcation *transaction.Controller
+  Category *category.Controller
+  Inventory *inventory.Controller
+  Coupon *coupon.Controller
+  Promotion *promotion.Controller
+  Wishlist *wishlist.Controller
 }
 
 func NewControllers(
   user *user.Controller,
   product *product.Controller,
   transaction *transaction.Controller,
+  category *category.Controller,
+  inventory *inventory.Controller,
+  coupon *coupon.Controller,
+  promotion *promotion.Controller,
+  wishlist *wishlist.Controller,
 ) *Controllers {
   return &Controllers{
     User: user,
     Product: product,
     Transcation: transaction,
+    Category: category,
+    Inventory: inventory,
+    Coupon: coupon,
+    Promotion: promotion,
+    Wishlist: wishlist,
   }
 }
@@ -0,0 +1,347 @@
+package coupon
+
+import (
+	"context"
+	"net/http"
+	enCoupon "ordent/internal/entity/coupon"
+	enPagination "ordent/internal/entity/pagination"
+	enUser "ordent/internal/entity/user"
+	"ordent/internal/pkg/apperror"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+type (
+	userUsecase interface {
+		GetUserSession(sess enUser.Session) *enUser.SessionData
+	}
+
+	couponUsecase interface {
+		InsertCoupon(ctx context.Context, form enCoupon.CouponRequest) (int64, error)
+		UpdateCoupon(ctx context.Context, form enCoupon.CouponRequest) error
+		DeleteCoupon(ctx context.Context, couponID int64) error
+		GetCoupon(ctx context.Context, couponID int64) (*enCoupon.Coupon, error)
+		GetCoupons(ctx context.Context, page enPagination.Request) ([]enCoupon.Coupon, enPagination.Paging, error)
+		GetRedemptions(ctx context.Context, couponID int64, page enPagination.Request) ([]enCoupon.Redemption, enPagination.Paging, error)
+		GetReport(ctx context.Context, from, to time.Time) ([]enCoupon.Report, error)
+		ApplyCoupon(ctx context.Context, form enCoupon.ApplyRequest) (*enCoupon.Quote, error)
+	}
+)
+
+// reportPeriod is the default window of the redemption report
+const reportPeriod = 30 * 24 * time.Hour
+
+type Controller struct {
+	userUsc   userUsecase
+	couponUsc couponUsecase
+}
+
+func NewController(
+	userUsc userUsecase,
+	couponUsc couponUsecase,
+) *Controller {
+	return &Controller{
+		userUsc:   userUsc,
+		couponUsc: couponUsc,
+	}
+}
+
+// ApplyCoupon price an order line with a coupon, nothing is redeemed until
+// the order is placed
+func (c *Controller) ApplyCoupon(ctx echo.Context) error {
+
+	session := ctx.Get(enUser.SessionContextKey).(enUser.Session)
+
+	sessionData := c.userUsc.GetUserSession(session)
+	if sessionData == nil {
+		return apperror.ErrUnauthorized
+	}
+
+	form := enCoupon.ApplyRequest{}
+
+	if err := ctx.Bind(&form); err != nil {
+		return apperror.ErrBadRequest
+	}
+
+	form.UserID = session.ID
+
+	quote, err := c.couponUsc.ApplyCoupon(ctx.Request().Context(), form)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(http.StatusOK,
+		map[string]interface{}{
+			"Status": "Success",
+			"Data":   quote,
+		},
+	)
+}
+
+func (c *Controller) InsertCoupon(ctx echo.Context) error {
+
+	session := ctx.Get(enUser.SessionContextKey).(enUser.Session)
+
+	sessionData := c.userUsc.GetUserSession(session)
+	if sessionData == nil {
+		return apperror.ErrUnauthorized
+	}
+
+	if !session.IsAdmin {
+		return apperror.ErrNotAdmin
+	}
+
+	form := enCoupon.CouponRequest{}
+
+	if err := ctx.Bind(&form); err != nil {
+		return apperror.ErrBadRequest
+	}
+
+	couponID, err := c.couponUsc.InsertCoupon(ctx.Request().Context(), form)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(http.StatusOK,
+		map[string]interface{}{
+			"Status": "Success",
+			"ID":     couponID,
+		},
+	)
+}
+
+func (c *Controller) UpdateCoupon(ctx echo.Context) error {
+
+	session := ctx.Get(enUser.SessionContextKey).(enUser.Session)
+
+	sessionData := c.userUsc.GetUserSession(session)
+	if sessionData == nil {
+		return apperror.ErrUnauthorized
+	}
+
+	if !session.IsAdmin {
+		return apperror.ErrNotAdmin
+	}
+
+	form := enCoupon.CouponRequest{}
+
+	if err := ctx.Bind(&form); err != nil {
+		return apperror.ErrBadRequest
+	}
+
+	err := c.couponUsc.UpdateCoupon(ctx.Request().Context(), form)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(http.StatusOK,
+		map[string]interface{}{
+			"Status": "Success",
+		},
+	)
+}
+
+func (c *Controller) DeleteCoupon(ctx echo.Context) error {
+
+	session := ctx.Get(enUser.SessionContextKey).(enUser.Session)
+
+	sessionData := c.userUsc.GetUserSession(session)
+	if sessionData == nil {
+		return apperror.ErrUnauthorized
+	}
+
+	if !session.IsAdmin {
+		return apperror.ErrNotAdmin
+	}
+
+	couponID, err := strconv.ParseInt(ctx.QueryParam("couponID"), 0, 64)
+	if err != nil {
+		return apperror.ErrBadRequest
+	}
+
+	err = c.couponUsc.DeleteCoupon(ctx.Request().Context(), couponID)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(http.StatusOK,
+		map[string]interface{}{
+			"Status": "Success",
+		},
+	)
+}
+
+func (c *Controller) GetCoupon(ctx echo.Context) error {
+
+	session := ctx.Get(enUser.SessionContextKey).(enUser.Session)
+
+	sessionData := c.userUsc.GetUserSession(session)
+	if sessionData == nil {
+		return apperror.ErrUnauthorized
+	}
+
+	if !session.IsAdmin {
+		return apperror.ErrNotAdmin
+	}
+
+	couponID, err := strconv.ParseInt(ctx.QueryParam("couponID"), 0, 64)
+	if err != nil {
+		return apperror.ErrBadRequest
+	}
+
+	coupon, err := c.couponUsc.GetCoupon(ctx.Request().Context(), couponID)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(http.StatusOK,
+		map[string]interface{}{
+			"Status": "Success",
+			"Data":   coupon,
+		},
+	)
+}
+
+func (c *Controller) GetCoupons(ctx echo.Context) error {
+
+	session := ctx.Get(enUser.SessionContextKey).(enUser.Session)
+
+	sessionData := c.userUsc.GetUserSession(session)
+	if sessionData == nil {
+		return apperror.ErrUnauthorized
+	}
+
+	if !session.IsAdmin {
+		return apperror.ErrNotAdmin
+	}
+
+	page, err := parsePage(ctx)
+	if err != nil {
+		return apperror.ErrBadRequest
+	}
+
+	coupons, paging, err := c.couponUsc.GetCoupons(ctx.Request().Context(), page)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(http.StatusOK,
+		map[string]interface{}{
+			"Status": "Success",
+			"Data":   coupons,
+			"Paging": paging,
+		},
+	)
+}
+
+func (c *Controller) GetRedemptions(ctx echo.Context) error {
+
+	session := ctx.Get(enUser.SessionContextKey).(enUser.Session)
+
+	sessionData := c.userUsc.GetUserSession(session)
+	if sessionData == nil {
+		return apperror.ErrUnauthorized
+	}
+
+	if !session.IsAdmin {
+		return apperror.ErrNotAdmin
+	}
+
+	couponID, err := strconv.ParseInt(ctx.QueryParam("couponID"), 0, 64)
+	if err != nil {
+		return apperror.ErrBadRequest
+	}
+
+	page, err := parsePage(ctx)
+	if err != nil {
+		return apperror.ErrBadRequest
+	}
+
+	redemptions, paging, err := c.couponUsc.GetRedemptions(ctx.Request().Context(), couponID, page)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(http.StatusOK,
+		map[string]interface{}{
+			"Status": "Success",
+			"Data":   redemptions,
+			"Paging": paging,
+		},
+	)
+}
+
+// GetReport sum the redemptions between from and to, RFC3339 or YYYY-MM-DD,
+// by default over the last 30 days
+func (c *Controller) GetReport(ctx echo.Context) error {
+
+	session := ctx.Get(enUser.SessionContextKey).(enUser.Session)
+
+	sessionData := c.userUsc.GetUserSession(session)
+	if sessionData == nil {
+		return apperror.ErrUnauthorized
+	}
+
+	if !session.IsAdmin {
+		return apperror.ErrNotAdmin
+	}
+
+	to := time.Now()
+	if ctx.QueryParam("to") != "" {
+		parsed, err := parseTime(ctx.QueryParam("to"))
+		if err != nil {
+			return apperror.ErrBadRequest
+		}
+		to = parsed
+	}
+
+	from := to.Add(-reportPeriod)
+	if ctx.QueryParam("from") != "" {
+		parsed, err := parseTime(ctx.QueryParam("from"))
+		if err != nil {
+			return apperror.ErrBadRequest
+		}
+		from = parsed
+	}
+
+	reports, err := c.couponUsc.GetReport(ctx.Request().Context(), from, to)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(http.StatusOK,
+		map[string]interface{}{
+			"Status": "Success",
+			"Data":   reports,
+		},
+	)
+}
+
+func parseTime(value string) (time.Time, error) {
+	if parsed, err := time.Parse(time.RFC3339, value); err == nil {
+		return parsed, nil
+	}
+
+	return time.Parse("2006-01-02", value)
+}
+
+func parsePage(ctx echo.Context) (enPagination.Request, error) {
+	page := enPagination.Request{
+		Cursor: ctx.QueryParam("cursor"),
+	}
+
+	limit := ctx.QueryParam("limit")
+	if limit == "" {
+		return page, nil
+	}
+
+	limitInt, err := strconv.Atoi(limit)
+	if err != nil {
+		return page, err
+	}
+	page.Limit = limitInt
+
+	return page, nil
+}
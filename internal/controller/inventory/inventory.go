@@ -0,0 +1,236 @@
+package inventory
+
+import (
+	"context"
+	"net/http"
+	enInventory "ordent/internal/entity/inventory"
+	enPagination "ordent/internal/entity/pagination"
+	enUser "ordent/internal/entity/user"
+	"ordent/internal/pkg/apperror"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+)
+
+type (
+	userUsecase interface {
+		GetUserSession(sess enUser.Session) *enUser.SessionData
+	}
+
+	inventoryUsecase interface {
+		AdjustStock(ctx context.Context, form enInventory.AdjustmentRequest) (*enInventory.Movement, error)
+		GetStockMovements(ctx context.Context, productID int64, page enPagination.Request) ([]enInventory.Movement, enPagination.Paging, error)
+		ReconcileStock(ctx context.Context, productID int64) ([]enInventory.Reconciliation, error)
+		SetReorderThreshold(ctx context.Context, form enInventory.ThresholdRequest) error
+		GetLowStockProducts(ctx context.Context, page enPagination.Request) ([]enInventory.LowStock, enPagination.Paging, error)
+		InsertWarehouse(ctx context.Context, form enInventory.WarehouseRequest) (int64, error)
+		UpdateWarehouse(ctx context.Context, form enInventory.WarehouseRequest) error
+		GetWarehouses(ctx context.Context) ([]enInventory.Warehouse, error)
+		ReserveStock(ctx context.Context, form enInventory.ReservationRequest) (*enInventory.Reservation, error)
+		GetReservation(ctx context.Context, userID, reservationID int64) (*enInventory.Reservation, error)
+		ReleaseReservation(ctx context.Context, userID, reservationID int64) error
+	}
+)
+
+type Controller struct {
+	userUsc      userUsecase
+	inventoryUsc inventoryUsecase
+}
+
+func NewController(
+	userUsc userUsecase,
+	inventoryUsc inventoryUsecase,
+) *Controller {
+	return &Controller{
+		userUsc:      userUsc,
+		inventoryUsc: inventoryUsc,
+	}
+}
+
+func (c *Controller) AdjustStock(ctx echo.Context) error {
+
+	session := ctx.Get(enUser.SessionContextKey).(enUser.Session)
+
+	sessionData := c.userUsc.GetUserSession(session)
+	if sessionData == nil {
+		return apperror.ErrUnauthorized
+	}
+
+	if !session.IsAdmin {
+		return apperror.ErrNotAdmin
+	}
+
+	form := enInventory.AdjustmentRequest{}
+
+	if err := ctx.Bind(&form); err != nil {
+		return apperror.ErrBadRequest
+	}
+
+	movement, err := c.inventoryUsc.AdjustStock(ctx.Request().Context(), form)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(http.StatusOK,
+		map[string]interface{}{
+			"Status": "Success",
+			"Data":   movement,
+		},
+	)
+}
+
+func (c *Controller) GetStockMovements(ctx echo.Context) error {
+
+	session := ctx.Get(enUser.SessionContextKey).(enUser.Session)
+
+	sessionData := c.userUsc.GetUserSession(session)
+	if sessionData == nil {
+		return apperror.ErrUnauthorized
+	}
+
+	if !session.IsAdmin {
+		return apperror.ErrNotAdmin
+	}
+
+	productID, err := strconv.ParseInt(ctx.QueryParam("productID"), 0, 64)
+	if err != nil {
+		return apperror.ErrBadRequest
+	}
+
+	page, err := parsePage(ctx)
+	if err != nil {
+		return apperror.ErrBadRequest
+	}
+
+	movements, paging, err := c.inventoryUsc.GetStockMovements(ctx.Request().Context(), productID, page)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(http.StatusOK,
+		map[string]interface{}{
+			"Status": "Success",
+			"Data":   movements,
+			"Paging": paging,
+		},
+	)
+}
+
+// ReconcileStock check one product with productID, or the whole catalog
+func (c *Controller) ReconcileStock(ctx echo.Context) error {
+
+	session := ctx.Get(enUser.SessionContextKey).(enUser.Session)
+
+	sessionData := c.userUsc.GetUserSession(session)
+	if sessionData == nil {
+		return apperror.ErrUnauthorized
+	}
+
+	if !session.IsAdmin {
+		return apperror.ErrNotAdmin
+	}
+
+	var productID int64
+	if param := ctx.QueryParam("productID"); param != "" {
+		var err error
+		productID, err = strconv.ParseInt(param, 0, 64)
+		if err != nil {
+			return apperror.ErrBadRequest
+		}
+	}
+
+	rows, err := c.inventoryUsc.ReconcileStock(ctx.Request().Context(), productID)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(http.StatusOK,
+		map[string]interface{}{
+			"Status": "Success",
+			"Data":   rows,
+		},
+	)
+}
+
+func (c *Controller) SetReorderThreshold(ctx echo.Context) error {
+
+	session := ctx.Get(enUser.SessionContextKey).(enUser.Session)
+
+	sessionData := c.userUsc.GetUserSession(session)
+	if sessionData == nil {
+		return apperror.ErrUnauthorized
+	}
+
+	if !session.IsAdmin {
+		return apperror.ErrNotAdmin
+	}
+
+	form := enInventory.ThresholdRequest{}
+
+	if err := ctx.Bind(&form); err != nil {
+		return apperror.ErrBadRequest
+	}
+
+	err := c.inventoryUsc.SetReorderThreshold(ctx.Request().Context(), form)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(http.StatusOK,
+		map[string]interface{}{
+			"Status": "Success",
+		},
+	)
+}
+
+func (c *Controller) GetLowStockProducts(ctx echo.Context) error {
+
+	session := ctx.Get(enUser.SessionContextKey).(enUser.Session)
+
+	sessionData := c.userUsc.GetUserSession(session)
+	if sessionData == nil {
+		return apperror.ErrUnauthorized
+	}
+
+	if !session.IsAdmin {
+		return apperror.ErrNotAdmin
+	}
+
+	page, err := parsePage(ctx)
+	if err != nil {
+		return apperror.ErrBadRequest
+	}
+
+	products, paging, err := c.inventoryUsc.GetLowStockProducts(ctx.Request().Context(), page)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(http.StatusOK,
+		map[string]interface{}{
+			"Status": "Success",
+			"Data":   products,
+			"Paging": paging,
+		},
+	)
+}
+
+// parsePage read cursor and optional limit from query params
+func parsePage(ctx echo.Context) (enPagination.Request, error) {
+	page := enPagination.Request{
+		Cursor: ctx.QueryParam("cursor"),
+	}
+
+	limit := ctx.QueryParam("limit")
+	if limit == "" {
+		return page, nil
+	}
+
+	limitInt, err := strconv.Atoi(limit)
+	if err != nil {
+		return page, err
+	}
+	page.Limit = limitInt
+
+	return page, nil
+}
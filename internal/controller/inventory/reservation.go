@@ -0,0 +1,93 @@
+package inventory
+
+import (
+	"net/http"
+	enInventory "ordent/internal/entity/inventory"
+	enUser "ordent/internal/entity/user"
+	"ordent/internal/pkg/apperror"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+)
+
+func (c *Controller) ReserveStock(ctx echo.Context) error {
+
+	session := ctx.Get(enUser.SessionContextKey).(enUser.Session)
+
+	sessionData := c.userUsc.GetUserSession(session)
+	if sessionData == nil {
+		return apperror.ErrUnauthorized
+	}
+
+	form := enInventory.ReservationRequest{}
+
+	if err := ctx.Bind(&form); err != nil {
+		return apperror.ErrBadRequest
+	}
+	form.UserID = session.ID
+
+	reservation, err := c.inventoryUsc.ReserveStock(ctx.Request().Context(), form)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(http.StatusOK,
+		map[string]interface{}{
+			"Status": "Success",
+			"Data":   reservation,
+		},
+	)
+}
+
+func (c *Controller) GetReservation(ctx echo.Context) error {
+
+	session := ctx.Get(enUser.SessionContextKey).(enUser.Session)
+
+	sessionData := c.userUsc.GetUserSession(session)
+	if sessionData == nil {
+		return apperror.ErrUnauthorized
+	}
+
+	reservationID, err := strconv.ParseInt(ctx.QueryParam("reservationID"), 0, 64)
+	if err != nil {
+		return apperror.ErrBadRequest
+	}
+
+	reservation, err := c.inventoryUsc.GetReservation(ctx.Request().Context(), session.ID, reservationID)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(http.StatusOK,
+		map[string]interface{}{
+			"Status": "Success",
+			"Data":   reservation,
+		},
+	)
+}
+
+func (c *Controller) ReleaseReservation(ctx echo.Context) error {
+
+	session := ctx.Get(enUser.SessionContextKey).(enUser.Session)
+
+	sessionData := c.userUsc.GetUserSession(session)
+	if sessionData == nil {
+		return apperror.ErrUnauthorized
+	}
+
+	reservationID, err := strconv.ParseInt(ctx.QueryParam("reservationID"), 0, 64)
+	if err != nil {
+		return apperror.ErrBadRequest
+	}
+
+	err = c.inventoryUsc.ReleaseReservation(ctx.Request().Context(), session.ID, reservationID)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(http.StatusOK,
+		map[string]interface{}{
+			"Status": "Success",
+		},
+	)
+}
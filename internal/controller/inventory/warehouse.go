@@ -0,0 +1,99 @@
+package inventory
+
+import (
+	"net/http"
+	enInventory "ordent/internal/entity/inventory"
+	enUser "ordent/internal/entity/user"
+	"ordent/internal/pkg/apperror"
+
+	"github.com/labstack/echo/v4"
+)
+
+func (c *Controller) InsertWarehouse(ctx echo.Context) error {
+
+	session := ctx.Get(enUser.SessionContextKey).(enUser.Session)
+
+	sessionData := c.userUsc.GetUserSession(session)
+	if sessionData == nil {
+		return apperror.ErrUnauthorized
+	}
+
+	if !session.IsAdmin {
+		return apperror.ErrNotAdmin
+	}
+
+	form := enInventory.WarehouseRequest{}
+
+	if err := ctx.Bind(&form); err != nil {
+		return apperror.ErrBadRequest
+	}
+
+	warehouseID, err := c.inventoryUsc.InsertWarehouse(ctx.Request().Context(), form)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(http.StatusOK,
+		map[string]interface{}{
+			"Status": "Success",
+			"ID":     warehouseID,
+		},
+	)
+}
+
+func (c *Controller) UpdateWarehouse(ctx echo.Context) error {
+
+	session := ctx.Get(enUser.SessionContextKey).(enUser.Session)
+
+	sessionData := c.userUsc.GetUserSession(session)
+	if sessionData == nil {
+		return apperror.ErrUnauthorized
+	}
+
+	if !session.IsAdmin {
+		return apperror.ErrNotAdmin
+	}
+
+	form := enInventory.WarehouseRequest{}
+
+	if err := ctx.Bind(&form); err != nil {
+		return apperror.ErrBadRequest
+	}
+
+	err := c.inventoryUsc.UpdateWarehouse(ctx.Request().Context(), form)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(http.StatusOK,
+		map[string]interface{}{
+			"Status": "Success",
+		},
+	)
+}
+
+func (c *Controller) GetWarehouses(ctx echo.Context) error {
+
+	session := ctx.Get(enUser.SessionContextKey).(enUser.Session)
+
+	sessionData := c.userUsc.GetUserSession(session)
+	if sessionData == nil {
+		return apperror.ErrUnauthorized
+	}
+
+	if !session.IsAdmin {
+		return apperror.ErrNotAdmin
+	}
+
+	warehouses, err := c.inventoryUsc.GetWarehouses(ctx.Request().Context())
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(http.StatusOK,
+		map[string]interface{}{
+			"Status": "Success",
+			"Data":   warehouses,
+		},
+	)
+}
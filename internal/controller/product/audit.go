@@ -0,0 +1,106 @@
+package product
+
+import (
+	"net/http"
+	enPagination "ordent/internal/entity/pagination"
+	enUser "ordent/internal/entity/user"
+	"ordent/internal/pkg/apperror"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+func (c *Controller) GetProductHistory(ctx echo.Context) error {
+
+	session := ctx.Get(enUser.SessionContextKey).(enUser.Session)
+
+	sessionData := c.userUsc.GetUserSession(session)
+	if sessionData == nil {
+		return apperror.ErrUnauthorized
+	}
+
+	if !session.IsAdmin {
+		return apperror.ErrNotAdmin
+	}
+
+	productID, err := strconv.ParseInt(ctx.Param("id"), 0, 64)
+	if err != nil {
+		return apperror.ErrBadRequest
+	}
+
+	page := enPagination.Request{
+		Cursor: ctx.QueryParam("cursor"),
+	}
+
+	if limit := ctx.QueryParam("limit"); limit != "" {
+		page.Limit, err = strconv.Atoi(limit)
+		if err != nil {
+			return apperror.ErrBadRequest
+		}
+	}
+
+	audits, paging, err := c.productUsc.GetProductHistory(ctx.Request().Context(), productID, page)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(http.StatusOK,
+		map[string]interface{}{
+			"Status": "Success",
+			"Data":   audits,
+			"Paging": paging,
+		},
+	)
+}
+
+func (c *Controller) GetPriceAt(ctx echo.Context) error {
+
+	session := ctx.Get(enUser.SessionContextKey).(enUser.Session)
+
+	sessionData := c.userUsc.GetUserSession(session)
+	if sessionData == nil {
+		return apperror.ErrUnauthorized
+	}
+
+	if !session.IsAdmin {
+		return apperror.ErrNotAdmin
+	}
+
+	productID, err := strconv.ParseInt(ctx.Param("id"), 0, 64)
+	if err != nil {
+		return apperror.ErrBadRequest
+	}
+
+	// at accepts RFC3339 time or a plain date
+	at := time.Now()
+	if value := ctx.QueryParam("at"); value != "" {
+		at, err = time.Parse(time.RFC3339, value)
+		if err != nil {
+			at, err = time.Parse("2006-01-02", value)
+		}
+		if err != nil {
+			return apperror.ErrBadRequest
+		}
+	}
+
+	var variantID int64
+	if value := ctx.QueryParam("variantID"); value != "" {
+		variantID, err = strconv.ParseInt(value, 0, 64)
+		if err != nil {
+			return apperror.ErrBadRequest
+		}
+	}
+
+	price, err := c.productUsc.GetPriceAt(ctx.Request().Context(), productID, variantID, at)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(http.StatusOK,
+		map[string]interface{}{
+			"Status": "Success",
+			"Data":   price,
+		},
+	)
+}
@@ -0,0 +1,33 @@
+package product
+
+import (
+	"encoding/json"
+	"net/http"
+	"ordent/internal/pkg/etag"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// respondCacheable send data as JSON tagged with the ETag tag builds from the
+// body, or a bare 304 when the client already has that body. A non zero
+// modified is sent as Last-Modified. How long browsers and the CDN may keep
+// the response is up to the Cache-Control set on the route.
+func respondCacheable(ctx echo.Context, data map[string]interface{}, tag func(body []byte) string, modified time.Time) error {
+	body, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	header := ctx.Response().Header()
+	header.Set(etag.HeaderETag, tag(body))
+	if !modified.IsZero() {
+		header.Set(etag.HeaderLastModified, modified.UTC().Format(http.TimeFormat))
+	}
+
+	if etag.NotModified(ctx.Request(), header.Get(etag.HeaderETag), modified) {
+		return ctx.NoContent(http.StatusNotModified)
+	}
+
+	return ctx.JSONBlob(http.StatusOK, body)
+}
@@ -0,0 +1,101 @@
+package product
+
+import (
+	"net/http"
+	enProduct "ordent/internal/entity/product"
+	enUser "ordent/internal/entity/user"
+	"ordent/internal/pkg/apperror"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+func (c *Controller) ImportProducts(ctx echo.Context) error {
+
+	session := ctx.Get(enUser.SessionContextKey).(enUser.Session)
+
+	sessionData := c.userUsc.GetUserSession(session)
+	if sessionData == nil {
+		return apperror.ErrUnauthorized
+	}
+
+	if !session.IsAdmin {
+		return apperror.ErrNotAdmin
+	}
+
+	dryRun := false
+	if value := ctx.QueryParam("dryRun"); value != "" {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			return apperror.ErrBadRequest
+		}
+		dryRun = parsed
+	}
+
+	fileHeader, err := ctx.FormFile("file")
+	if err != nil {
+		return apperror.ErrBadRequest
+	}
+
+	// format falls back to the file extension, e.g. products.csv
+	format := ctx.QueryParam("format")
+	if format == "" {
+		format = strings.TrimPrefix(strings.ToLower(filepath.Ext(fileHeader.Filename)), ".")
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		return apperror.ErrBadRequest
+	}
+	defer file.Close()
+
+	result, err := c.productUsc.ImportProducts(ctx.Request().Context(), format, file, dryRun)
+	if err != nil {
+		return err
+	}
+
+	status := http.StatusOK
+	if len(result.Errors) > 0 {
+		status = http.StatusUnprocessableEntity
+	}
+
+	return ctx.JSON(status,
+		map[string]interface{}{
+			"Status": "Success",
+			"Data":   result,
+		},
+	)
+}
+
+func (c *Controller) ExportProducts(ctx echo.Context) error {
+
+	session := ctx.Get(enUser.SessionContextKey).(enUser.Session)
+
+	sessionData := c.userUsc.GetUserSession(session)
+	if sessionData == nil {
+		return apperror.ErrUnauthorized
+	}
+
+	if !session.IsAdmin {
+		return apperror.ErrNotAdmin
+	}
+
+	format := ctx.QueryParam("format")
+	contentType := map[string]string{
+		enProduct.CatalogFormatCSV:   "text/csv",
+		enProduct.CatalogFormatJSONL: "application/x-ndjson",
+	}[format]
+	if contentType == "" {
+		return apperror.ErrBadRequest
+	}
+
+	response := ctx.Response()
+	response.Header().Set(echo.HeaderContentType, contentType)
+	response.Header().Set(echo.HeaderContentDisposition, "attachment; filename=products."+format)
+	response.WriteHeader(http.StatusOK)
+
+	// headers are already sent, a failure can only cut the stream short
+	return c.productUsc.ExportProducts(ctx.Request().Context(), format, response)
+}
@@ -0,0 +1,110 @@
+package product
+
+import (
+	"net/http"
+	enPagination "ordent/internal/entity/pagination"
+	enUser "ordent/internal/entity/user"
+	"ordent/internal/pkg/apperror"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+)
+
+func (c *Controller) GetDeletedProducts(ctx echo.Context) error {
+
+	session := ctx.Get(enUser.SessionContextKey).(enUser.Session)
+
+	sessionData := c.userUsc.GetUserSession(session)
+	if sessionData == nil {
+		return apperror.ErrUnauthorized
+	}
+
+	if !session.IsAdmin {
+		return apperror.ErrNotAdmin
+	}
+
+	page := enPagination.Request{
+		Cursor: ctx.QueryParam("cursor"),
+	}
+
+	if limit := ctx.QueryParam("limit"); limit != "" {
+		limitInt, err := strconv.Atoi(limit)
+		if err != nil {
+			return apperror.ErrBadRequest
+		}
+		page.Limit = limitInt
+	}
+
+	products, paging, err := c.productUsc.GetDeletedProducts(ctx.Request().Context(), page)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(http.StatusOK,
+		map[string]interface{}{
+			"Status": "Success",
+			"Data":   products,
+			"Paging": paging,
+		},
+	)
+}
+
+func (c *Controller) RestoreProduct(ctx echo.Context) error {
+
+	session := ctx.Get(enUser.SessionContextKey).(enUser.Session)
+
+	sessionData := c.userUsc.GetUserSession(session)
+	if sessionData == nil {
+		return apperror.ErrUnauthorized
+	}
+
+	if !session.IsAdmin {
+		return apperror.ErrNotAdmin
+	}
+
+	productID, err := strconv.ParseInt(ctx.QueryParam("productID"), 0, 64)
+	if err != nil {
+		return apperror.ErrBadRequest
+	}
+
+	err = c.productUsc.RestoreProduct(ctx.Request().Context(), productID)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(http.StatusOK,
+		map[string]interface{}{
+			"Status": "Success",
+		},
+	)
+}
+
+func (c *Controller) PurgeProduct(ctx echo.Context) error {
+
+	session := ctx.Get(enUser.SessionContextKey).(enUser.Session)
+
+	sessionData := c.userUsc.GetUserSession(session)
+	if sessionData == nil {
+		return apperror.ErrUnauthorized
+	}
+
+	if !session.IsAdmin {
+		return apperror.ErrNotAdmin
+	}
+
+	productID, err := strconv.ParseInt(ctx.QueryParam("productID"), 0, 64)
+	if err != nil {
+		return apperror.ErrBadRequest
+	}
+
+	err = c.productUsc.PurgeProduct(ctx.Request().Context(), productID)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(http.StatusOK,
+		map[string]interface{}{
+			"Status": "Success",
+		},
+	)
+}
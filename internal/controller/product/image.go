@@ -0,0 +1,132 @@
+package product
+
+import (
+	"io"
+	"net/http"
+	enProduct "ordent/internal/entity/product"
+	enUser "ordent/internal/entity/user"
+	"ordent/internal/pkg/apperror"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+)
+
+func (c *Controller) UploadImage(ctx echo.Context) error {
+
+	session := ctx.Get(enUser.SessionContextKey).(enUser.Session)
+
+	sessionData := c.userUsc.GetUserSession(session)
+	if sessionData == nil {
+		return apperror.ErrUnauthorized
+	}
+
+	if !session.IsAdmin {
+		return apperror.ErrNotAdmin
+	}
+
+	productID, err := strconv.ParseInt(ctx.FormValue("productID"), 0, 64)
+	if err != nil {
+		return apperror.ErrBadRequest
+	}
+
+	form := enProduct.ImageUpload{
+		ProductID: productID,
+	}
+
+	if position := ctx.FormValue("position"); position != "" {
+		positionInt, err := strconv.Atoi(position)
+		if err != nil {
+			return apperror.ErrBadRequest
+		}
+		form.Position = &positionInt
+	}
+
+	fileHeader, err := ctx.FormFile("image")
+	if err != nil {
+		return apperror.ErrBadRequest
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		return apperror.ErrBadRequest
+	}
+	defer file.Close()
+
+	form.Data, err = io.ReadAll(file)
+	if err != nil {
+		return apperror.ErrBadRequest
+	}
+
+	image, err := c.productUsc.UploadImage(ctx.Request().Context(), form)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(http.StatusOK,
+		map[string]interface{}{
+			"Status": "Success",
+			"Data":   image,
+		},
+	)
+}
+
+func (c *Controller) DeleteImage(ctx echo.Context) error {
+
+	session := ctx.Get(enUser.SessionContextKey).(enUser.Session)
+
+	sessionData := c.userUsc.GetUserSession(session)
+	if sessionData == nil {
+		return apperror.ErrUnauthorized
+	}
+
+	if !session.IsAdmin {
+		return apperror.ErrNotAdmin
+	}
+
+	imageID, err := strconv.ParseInt(ctx.QueryParam("imageID"), 0, 64)
+	if err != nil {
+		return apperror.ErrBadRequest
+	}
+
+	err = c.productUsc.DeleteImage(ctx.Request().Context(), imageID)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(http.StatusOK,
+		map[string]interface{}{
+			"Status": "Success",
+		},
+	)
+}
+
+func (c *Controller) ReorderImages(ctx echo.Context) error {
+
+	session := ctx.Get(enUser.SessionContextKey).(enUser.Session)
+
+	sessionData := c.userUsc.GetUserSession(session)
+	if sessionData == nil {
+		return apperror.ErrUnauthorized
+	}
+
+	if !session.IsAdmin {
+		return apperror.ErrNotAdmin
+	}
+
+	form := enProduct.ImageOrderRequest{}
+
+	if err := ctx.Bind(&form); err != nil {
+		return apperror.ErrBadRequest
+	}
+
+	err := c.productUsc.ReorderImages(ctx.Request().Context(), form)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(http.StatusOK,
+		map[string]interface{}{
+			"Status": "Success",
+		},
+	)
+}
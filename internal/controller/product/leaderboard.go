@@ -0,0 +1,86 @@
+package product
+
+import (
+	"net/http"
+	"ordent/internal/pkg/apperror"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+)
+
+// GetBestsellers list the best selling products of all time, of the whole
+// catalog or of the optional category
+func (c *Controller) GetBestsellers(ctx echo.Context) error {
+	limit, err := parseLimit(ctx)
+	if err != nil {
+		return apperror.ErrBadRequest
+	}
+
+	rankings, err := c.productUsc.GetBestsellers(ctx.Request().Context(), ctx.QueryParam("category"), limit)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(http.StatusOK,
+		map[string]interface{}{
+			"Status": "Success",
+			"Data":   rankings,
+		},
+	)
+}
+
+// GetTrending list the best selling products over the last 24h or 7d
+func (c *Controller) GetTrending(ctx echo.Context) error {
+	limit, err := parseLimit(ctx)
+	if err != nil {
+		return apperror.ErrBadRequest
+	}
+
+	rankings, err := c.productUsc.GetTrending(ctx.Request().Context(), ctx.QueryParam("window"), ctx.QueryParam("category"), limit)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(http.StatusOK,
+		map[string]interface{}{
+			"Status": "Success",
+			"Data":   rankings,
+		},
+	)
+}
+
+// GetRelated list the products frequently bought with the product
+func (c *Controller) GetRelated(ctx echo.Context) error {
+	productID, err := strconv.ParseInt(ctx.Param("id"), 0, 64)
+	if err != nil {
+		return apperror.ErrBadRequest
+	}
+
+	limit, err := parseLimit(ctx)
+	if err != nil {
+		return apperror.ErrBadRequest
+	}
+
+	products, err := c.productUsc.GetRelated(ctx.Request().Context(), productID, limit)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(http.StatusOK,
+		map[string]interface{}{
+			"Status": "Success",
+			"Data":   products,
+		},
+	)
+}
+
+// parseLimit read the optional limit query param, 0 when absent so the
+// usecase applies its default
+func parseLimit(ctx echo.Context) (int, error) {
+	limit := ctx.QueryParam("limit")
+	if limit == "" {
+		return 0, nil
+	}
+
+	return strconv.Atoi(limit)
+}
@@ -2,10 +2,16 @@ package product
 
 import (
 	"context"
+	"io"
 	"net/http"
+	enPagination "ordent/internal/entity/pagination"
 	enProduct "ordent/internal/entity/product"
 	enUser "ordent/internal/entity/user"
+	"ordent/internal/pkg/apperror"
+	"ordent/internal/pkg/etag"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/labstack/echo/v4"
 )
@@ -17,18 +23,45 @@ type (
 
 	productUsecase interface {
 		InsertProduct(ctx context.Context, form enProduct.ProductRequest) (int64, error)
-		UpdateProduct(ctx context.Context, form enProduct.Product) error
+		UpdateProduct(ctx context.Context, form enProduct.Product) (*enProduct.Product, error)
+		PatchProduct(ctx context.Context, productID int64, patch enProduct.ProductPatch, version int64) (*enProduct.Product, error)
 		DeleteProduct(ctx context.Context, productID int64) error
+		RestoreProduct(ctx context.Context, productID int64) error
+		PurgeProduct(ctx context.Context, productID int64) error
+		GetDeletedProducts(ctx context.Context, page enPagination.Request) ([]enProduct.Product, enPagination.Paging, error)
 		GetProduct(ctx context.Context, productID int64) (*enProduct.Product, error)
-		GetProducts(ctx context.Context, page, limit int) ([]enProduct.Product, error)
-		GetProductsByType(ctx context.Context, page, limit int, productType enProduct.ProductType) ([]enProduct.Product, error)
-		SearchProduct(ctx context.Context, page, limit int, query string) ([]enProduct.Product, error)
+		GetProductsByIDs(ctx context.Context, productIDs []int64) ([]enProduct.Product, error)
+		GetProducts(ctx context.Context, page enPagination.Request) ([]enProduct.Product, enPagination.Paging, error)
+		GetProductsByCategory(ctx context.Context, page enPagination.Request, slug string) ([]enProduct.Product, enPagination.Paging, error)
+		SearchProduct(ctx context.Context, page enPagination.Request, query string) ([]enProduct.Product, enPagination.Paging, error)
+		InsertVariant(ctx context.Context, form enProduct.VariantRequest) (int64, error)
+		UpdateVariant(ctx context.Context, form enProduct.VariantRequest) error
+		DeleteVariant(ctx context.Context, variantID int64) error
+		UploadImage(ctx context.Context, form enProduct.ImageUpload) (*enProduct.Image, error)
+		DeleteImage(ctx context.Context, imageID int64) error
+		ReorderImages(ctx context.Context, form enProduct.ImageOrderRequest) error
+		ImportProducts(ctx context.Context, format string, file io.Reader, dryRun bool) (*enProduct.ImportResult, error)
+		ExportProducts(ctx context.Context, format string, w io.Writer) error
+		GetProductHistory(ctx context.Context, productID int64, page enPagination.Request) ([]enProduct.Audit, enPagination.Paging, error)
+		GetPriceAt(ctx context.Context, productID, variantID int64, at time.Time) (*enProduct.PriceAt, error)
+		ReviewProduct(ctx context.Context, form enProduct.ReviewRequest) (int64, error)
+		ModerateReview(ctx context.Context, form enProduct.ModerationRequest) error
+		GetReviews(ctx context.Context, productID int64, page enPagination.Request) ([]enProduct.Review, enPagination.Paging, error)
+		GetReviewsByStatus(ctx context.Context, status string, page enPagination.Request) ([]enProduct.Review, enPagination.Paging, error)
+		GetBestsellers(ctx context.Context, slug string, limit int) ([]enProduct.Ranking, error)
+		GetTrending(ctx context.Context, window, slug string, limit int) ([]enProduct.Ranking, error)
+		GetRelated(ctx context.Context, productID int64, limit int) ([]enProduct.Product, error)
+		TrackView(ctx context.Context, userID, productID int64)
+		GetRecentlyViewed(ctx context.Context, userID int64, limit int) ([]enProduct.Product, error)
+		Suggest(ctx context.Context, prefix string, limit int) (*enProduct.Suggestion, error)
+		GetZeroResultQueries(ctx context.Context, page enPagination.Request) ([]enProduct.SearchQuery, enPagination.Paging, error)
 	}
 )
 
 type Controller struct {
 	userUsc    userUsecase
 	productUsc productUsecase
+	viewSlots  chan struct{}
 }
 
 func NewController(
@@ -38,6 +71,7 @@ func NewController(
 	return &Controller{
 		userUsc:    userUsc,
 		productUsc: productUsc,
+		viewSlots:  make(chan struct{}, maxTrackingViews),
 	}
 }
 
@@ -47,38 +81,22 @@ func (c *Controller) InsertProduct(ctx echo.Context) error {
 
 	sessionData := c.userUsc.GetUserSession(session)
 	if sessionData == nil {
-		return ctx.JSON(http.StatusUnauthorized,
-			map[string]interface{}{
-				"Error": "Unauthorized",
-			},
-		)
+		return apperror.ErrUnauthorized
 	}
 
 	if !session.IsAdmin {
-		return ctx.JSON(http.StatusUnauthorized,
-			map[string]interface{}{
-				"Error": "Not Admin",
-			},
-		)
+		return apperror.ErrNotAdmin
 	}
 
 	form := enProduct.ProductRequest{}
 
 	if err := ctx.Bind(&form); err != nil {
-		return ctx.JSON(http.StatusBadRequest,
-			map[string]interface{}{
-				"Error": "Bad Request",
-			},
-		)
+		return apperror.ErrBadRequest
 	}
 
 	productId, err := c.productUsc.InsertProduct(ctx.Request().Context(), form)
 	if err != nil {
-		return ctx.JSON(http.StatusInternalServerError,
-			map[string]interface{}{
-				"Error": err.Error(),
-			},
-		)
+		return err
 	}
 
 	return ctx.JSON(http.StatusOK,
@@ -95,40 +113,35 @@ func (c *Controller) UpdateProduct(ctx echo.Context) error {
 
 	sessionData := c.userUsc.GetUserSession(session)
 	if sessionData == nil {
-		return ctx.JSON(http.StatusUnauthorized,
-			map[string]interface{}{
-				"Error": "Unauthorized",
-			},
-		)
+		return apperror.ErrUnauthorized
 	}
 
 	if !session.IsAdmin {
-		return ctx.JSON(http.StatusUnauthorized,
-			map[string]interface{}{
-				"Error": "Not Admin",
-			},
-		)
+		return apperror.ErrNotAdmin
 	}
 
 	form := enProduct.Product{}
 
 	if err := ctx.Bind(&form); err != nil {
-		return ctx.JSON(http.StatusBadRequest,
-			map[string]interface{}{
-				"Error": "Bad Request",
-			},
-		)
+		return apperror.ErrBadRequest
 	}
 
-	err := c.productUsc.UpdateProduct(ctx.Request().Context(), form)
+	version, err := etag.ParseVersion(ctx.Request().Header.Get(etag.HeaderIfMatch))
 	if err != nil {
-		return ctx.JSON(http.StatusInternalServerError,
-			map[string]interface{}{
-				"Error": err.Error(),
-			},
-		)
+		return err
 	}
 
+	// If-Match takes precedence over the version in the body
+	if version != 0 {
+		form.Version = version
+	}
+
+	product, err := c.productUsc.UpdateProduct(ctx.Request().Context(), form)
+	if err != nil {
+		return err
+	}
+
+	ctx.Response().Header().Set(etag.HeaderETag, etag.Version(product.Version))
 	return ctx.JSON(http.StatusOK,
 		map[string]interface{}{
 			"Status": "Success",
@@ -136,241 +149,244 @@ func (c *Controller) UpdateProduct(ctx echo.Context) error {
 	)
 }
 
-func (c *Controller) DeleteProduct(ctx echo.Context) error {
+// PatchProduct update only the fields present in the body. The If-Match
+// header guards against overwriting changes made since the product was read.
+func (c *Controller) PatchProduct(ctx echo.Context) error {
 
 	session := ctx.Get(enUser.SessionContextKey).(enUser.Session)
 
 	sessionData := c.userUsc.GetUserSession(session)
 	if sessionData == nil {
-		return ctx.JSON(http.StatusUnauthorized,
-			map[string]interface{}{
-				"Error": "Unauthorized",
-			},
-		)
+		return apperror.ErrUnauthorized
 	}
 
 	if !session.IsAdmin {
-		return ctx.JSON(http.StatusUnauthorized,
-			map[string]interface{}{
-				"Error": "Not Admin",
-			},
-		)
+		return apperror.ErrNotAdmin
 	}
 
-	productID := ctx.QueryParam("productID")
-	if productID == "" {
-		return ctx.JSON(http.StatusBadRequest,
-			map[string]interface{}{
-				"Error": "Bad Request",
-			},
-		)
+	productID, err := strconv.ParseInt(ctx.Param("id"), 0, 64)
+	if err != nil {
+		return apperror.ErrBadRequest
 	}
 
-	productIDInt, err := strconv.ParseInt(productID, 0, 64)
+	version, err := etag.ParseVersion(ctx.Request().Header.Get(etag.HeaderIfMatch))
 	if err != nil {
-		return ctx.JSON(http.StatusBadRequest,
-			map[string]interface{}{
-				"Error": "Bad Request",
-			},
-		)
+		return err
 	}
 
-	err = c.productUsc.DeleteProduct(ctx.Request().Context(), productIDInt)
+	patch := enProduct.ProductPatch{}
+
+	if err := ctx.Bind(&patch); err != nil {
+		return apperror.ErrBadRequest
+	}
+
+	product, err := c.productUsc.PatchProduct(ctx.Request().Context(), productID, patch, version)
 	if err != nil {
-		return ctx.JSON(http.StatusInternalServerError,
-			map[string]interface{}{
-				"Error": err.Error(),
-			},
-		)
+		return err
 	}
 
+	ctx.Response().Header().Set(etag.HeaderETag, etag.Version(product.Version))
 	return ctx.JSON(http.StatusOK,
 		map[string]interface{}{
 			"Status": "Success",
+			"Data":   product,
 		},
 	)
 }
 
-func (c *Controller) GetProduct(ctx echo.Context) error {
-	productID := ctx.QueryParam("productID")
-	if productID == "" {
-		return ctx.JSON(http.StatusBadRequest,
-			map[string]interface{}{
-				"Error": "Bad Request",
-			},
-		)
+// DeleteProduct serve DELETE /api/v1/products/:id, the legacy route passes
+// productID in the query
+func (c *Controller) DeleteProduct(ctx echo.Context) error {
+
+	session := ctx.Get(enUser.SessionContextKey).(enUser.Session)
+
+	sessionData := c.userUsc.GetUserSession(session)
+	if sessionData == nil {
+		return apperror.ErrUnauthorized
+	}
+
+	if !session.IsAdmin {
+		return apperror.ErrNotAdmin
 	}
 
-	productIDInt, err := strconv.ParseInt(productID, 0, 64)
+	productIDInt, err := strconv.ParseInt(ctx.Param("id"), 0, 64)
 	if err != nil {
-		return ctx.JSON(http.StatusBadRequest,
-			map[string]interface{}{
-				"Error": "Bad Request",
-			},
-		)
+		return apperror.ErrBadRequest
 	}
 
-	product, err := c.productUsc.GetProduct(ctx.Request().Context(), productIDInt)
+	err = c.productUsc.DeleteProduct(ctx.Request().Context(), productIDInt)
 	if err != nil {
-		return ctx.JSON(http.StatusInternalServerError,
-			map[string]interface{}{
-				"Error": err.Error(),
-			},
-		)
+		return err
 	}
 
 	return ctx.JSON(http.StatusOK,
 		map[string]interface{}{
 			"Status": "Success",
-			"Data":   product,
 		},
 	)
 }
 
-func (c *Controller) GetProducts(ctx echo.Context) error {
-	page := ctx.QueryParam("page")
-	limit := ctx.QueryParam("limit")
-
-	pageInt, err := strconv.Atoi(page)
+// GetProduct serve GET /api/v1/products/:id, the legacy route passes
+// productID in the query
+func (c *Controller) GetProduct(ctx echo.Context) error {
+	productIDInt, err := strconv.ParseInt(ctx.Param("id"), 0, 64)
 	if err != nil {
-		return ctx.JSON(http.StatusBadRequest,
-			map[string]interface{}{
-				"Error": "Bad Request",
-			},
-		)
+		return apperror.ErrBadRequest
 	}
 
-	limitInt, err := strconv.Atoi(limit)
+	product, err := c.productUsc.GetProduct(ctx.Request().Context(), productIDInt)
 	if err != nil {
-		return ctx.JSON(http.StatusBadRequest,
-			map[string]interface{}{
-				"Error": "Bad Request",
-			},
-		)
+		return err
 	}
 
-	if pageInt == 0 {
-		pageInt = 1
+	if product.ID == 0 {
+		return enProduct.ErrNotFound
 	}
 
-	if limitInt == 0 {
-		limitInt = 10
-	}
+	c.trackView(ctx, product.ID)
 
-	product, err := c.productUsc.GetProducts(ctx.Request().Context(), pageInt, limitInt)
-	if err != nil {
-		return ctx.JSON(http.StatusInternalServerError,
-			map[string]interface{}{
-				"Error": err.Error(),
-			},
-		)
+	// the tag keeps the version for If-Match, the body part covers stock,
+	// prices and ratings which change without a new version
+	modified := time.Time{}
+	if product.UpdatedTime != nil {
+		modified = *product.UpdatedTime
 	}
 
-	return ctx.JSON(http.StatusOK,
+	return respondCacheable(ctx,
 		map[string]interface{}{
 			"Status": "Success",
 			"Data":   product,
 		},
+		func(body []byte) string {
+			return etag.VersionContent(product.Version, body)
+		},
+		modified,
 	)
 }
 
-func (c *Controller) GetProductsByType(ctx echo.Context) error {
-	page := ctx.QueryParam("page")
-	limit := ctx.QueryParam("limit")
+// GetProductsByIDs return several products in one request, ids are comma
+// separated, e.g. ids=3,1,2
+func (c *Controller) GetProductsByIDs(ctx echo.Context) error {
+	productIDs := make([]int64, 0)
+	for _, id := range strings.Split(ctx.QueryParam("ids"), ",") {
+		id = strings.TrimSpace(id)
+		if id == "" {
+			continue
+		}
 
-	pageInt, err := strconv.Atoi(page)
-	if err != nil {
-		return ctx.JSON(http.StatusBadRequest,
-			map[string]interface{}{
-				"Error": "Bad Request",
-			},
-		)
+		productID, err := strconv.ParseInt(id, 0, 64)
+		if err != nil {
+			return apperror.ErrBadRequest
+		}
+		productIDs = append(productIDs, productID)
+	}
+
+	if len(productIDs) == 0 {
+		return apperror.ErrBadRequest
 	}
 
-	limitInt, err := strconv.Atoi(limit)
+	products, err := c.productUsc.GetProductsByIDs(ctx.Request().Context(), productIDs)
 	if err != nil {
-		return ctx.JSON(http.StatusBadRequest,
-			map[string]interface{}{
-				"Error": "Bad Request",
-			},
-		)
+		return err
 	}
 
-	productType := ctx.QueryParam("type")
-	if productType == "" {
-		productType = string(enProduct.HatsProduct)
+	return ctx.JSON(http.StatusOK,
+		map[string]interface{}{
+			"Status": "Success",
+			"Data":   products,
+		},
+	)
+}
+
+// ListProducts serve GET /api/v1/products, searched with query or filtered
+// by category, otherwise the whole catalog
+func (c *Controller) ListProducts(ctx echo.Context) error {
+	if ctx.QueryParam("query") != "" {
+		return c.SearchProduct(ctx)
 	}
 
-	if pageInt == 0 {
-		pageInt = 1
+	if ctx.QueryParam("category") != "" {
+		return c.GetProductsByCategory(ctx)
 	}
 
-	if limitInt == 0 {
-		limitInt = 10
+	return c.GetProducts(ctx)
+}
+
+func (c *Controller) GetProducts(ctx echo.Context) error {
+	cursor := ctx.QueryParam("cursor")
+	limitInt, err := parseLimit(ctx)
+	if err != nil {
+		return apperror.ErrBadRequest
 	}
 
-	product, err := c.productUsc.GetProductsByType(ctx.Request().Context(), pageInt, limitInt, enProduct.ProductType(productType))
+	product, paging, err := c.productUsc.GetProducts(ctx.Request().Context(), enPagination.Request{Cursor: cursor, Limit: limitInt, Sort: ctx.QueryParam("sort")})
 	if err != nil {
-		return ctx.JSON(http.StatusInternalServerError,
-			map[string]interface{}{
-				"Error": err.Error(),
-			},
-		)
+		return err
 	}
 
-	return ctx.JSON(http.StatusOK,
+	return respondCacheable(ctx,
 		map[string]interface{}{
 			"Status": "Success",
 			"Data":   product,
+			"Paging": paging,
 		},
+		etag.Content,
+		time.Time{},
 	)
 }
 
-func (c *Controller) SearchProduct(ctx echo.Context) error {
-	page := ctx.QueryParam("page")
-	limit := ctx.QueryParam("limit")
-
-	pageInt, err := strconv.Atoi(page)
+func (c *Controller) GetProductsByCategory(ctx echo.Context) error {
+	cursor := ctx.QueryParam("cursor")
+	limitInt, err := parseLimit(ctx)
 	if err != nil {
-		return ctx.JSON(http.StatusBadRequest,
-			map[string]interface{}{
-				"Error": "Bad Request",
-			},
-		)
+		return apperror.ErrBadRequest
 	}
 
-	limitInt, err := strconv.Atoi(limit)
-	if err != nil {
-		return ctx.JSON(http.StatusBadRequest,
-			map[string]interface{}{
-				"Error": "Bad Request",
-			},
-		)
+	// type is kept for clients built before categories existed
+	slug := ctx.QueryParam("category")
+	if slug == "" {
+		slug = ctx.QueryParam("type")
 	}
 
-	query := ctx.QueryParam("query")
+	if slug == "" {
+		return apperror.ErrBadRequest
+	}
 
-	if pageInt == 0 {
-		pageInt = 1
+	product, paging, err := c.productUsc.GetProductsByCategory(ctx.Request().Context(), enPagination.Request{Cursor: cursor, Limit: limitInt, Sort: ctx.QueryParam("sort")}, slug)
+	if err != nil {
+		return err
 	}
 
-	if limitInt == 0 {
-		limitInt = 10
+	return respondCacheable(ctx,
+		map[string]interface{}{
+			"Status": "Success",
+			"Data":   product,
+			"Paging": paging,
+		},
+		etag.Content,
+		time.Time{},
+	)
+}
+
+func (c *Controller) SearchProduct(ctx echo.Context) error {
+	cursor := ctx.QueryParam("cursor")
+	limitInt, err := parseLimit(ctx)
+	if err != nil {
+		return apperror.ErrBadRequest
 	}
 
-	product, err := c.productUsc.SearchProduct(ctx.Request().Context(), pageInt, limitInt, query)
+	query := ctx.QueryParam("query")
+
+	product, paging, err := c.productUsc.SearchProduct(ctx.Request().Context(), enPagination.Request{Cursor: cursor, Limit: limitInt, Sort: ctx.QueryParam("sort")}, query)
 	if err != nil {
-		return ctx.JSON(http.StatusInternalServerError,
-			map[string]interface{}{
-				"Error": err.Error(),
-			},
-		)
+		return err
 	}
 
 	return ctx.JSON(http.StatusOK,
 		map[string]interface{}{
 			"Status": "Success",
 			"Data":   product,
+			"Paging": paging,
 		},
 	)
 }
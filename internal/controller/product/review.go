@@ -0,0 +1,154 @@
+package product
+
+import (
+	"net/http"
+	enPagination "ordent/internal/entity/pagination"
+	enProduct "ordent/internal/entity/product"
+	enUser "ordent/internal/entity/user"
+	"ordent/internal/pkg/apperror"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+)
+
+// ReviewProduct rate the product with id, only for users who bought it
+func (c *Controller) ReviewProduct(ctx echo.Context) error {
+
+	session := ctx.Get(enUser.SessionContextKey).(enUser.Session)
+
+	sessionData := c.userUsc.GetUserSession(session)
+	if sessionData == nil {
+		return apperror.ErrUnauthorized
+	}
+
+	productID, err := strconv.ParseInt(ctx.Param("id"), 0, 64)
+	if err != nil {
+		return apperror.ErrBadRequest
+	}
+
+	form := enProduct.ReviewRequest{}
+
+	if err := ctx.Bind(&form); err != nil {
+		return apperror.ErrBadRequest
+	}
+
+	form.ProductID = productID
+	form.UserID = session.ID
+
+	reviewID, err := c.productUsc.ReviewProduct(ctx.Request().Context(), form)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(http.StatusOK,
+		map[string]interface{}{
+			"Status": "Success",
+			"ID":     reviewID,
+		},
+	)
+}
+
+// GetReviews list the approved reviews of the product with id
+func (c *Controller) GetReviews(ctx echo.Context) error {
+	productID, err := strconv.ParseInt(ctx.Param("id"), 0, 64)
+	if err != nil {
+		return apperror.ErrBadRequest
+	}
+
+	page := enPagination.Request{
+		Cursor: ctx.QueryParam("cursor"),
+	}
+
+	if limit := ctx.QueryParam("limit"); limit != "" {
+		page.Limit, err = strconv.Atoi(limit)
+		if err != nil {
+			return apperror.ErrBadRequest
+		}
+	}
+
+	reviews, paging, err := c.productUsc.GetReviews(ctx.Request().Context(), productID, page)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(http.StatusOK,
+		map[string]interface{}{
+			"Status": "Success",
+			"Data":   reviews,
+			"Paging": paging,
+		},
+	)
+}
+
+// GetReviewsByStatus list the reviews to moderate, status is pending by default
+func (c *Controller) GetReviewsByStatus(ctx echo.Context) error {
+
+	session := ctx.Get(enUser.SessionContextKey).(enUser.Session)
+
+	sessionData := c.userUsc.GetUserSession(session)
+	if sessionData == nil {
+		return apperror.ErrUnauthorized
+	}
+
+	if !session.IsAdmin {
+		return apperror.ErrNotAdmin
+	}
+
+	var err error
+	page := enPagination.Request{
+		Cursor: ctx.QueryParam("cursor"),
+	}
+
+	if limit := ctx.QueryParam("limit"); limit != "" {
+		page.Limit, err = strconv.Atoi(limit)
+		if err != nil {
+			return apperror.ErrBadRequest
+		}
+	}
+
+	reviews, paging, err := c.productUsc.GetReviewsByStatus(ctx.Request().Context(), ctx.QueryParam("status"), page)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(http.StatusOK,
+		map[string]interface{}{
+			"Status": "Success",
+			"Data":   reviews,
+			"Paging": paging,
+		},
+	)
+}
+
+func (c *Controller) ModerateReview(ctx echo.Context) error {
+
+	session := ctx.Get(enUser.SessionContextKey).(enUser.Session)
+
+	sessionData := c.userUsc.GetUserSession(session)
+	if sessionData == nil {
+		return apperror.ErrUnauthorized
+	}
+
+	if !session.IsAdmin {
+		return apperror.ErrNotAdmin
+	}
+
+	form := enProduct.ModerationRequest{}
+
+	if err := ctx.Bind(&form); err != nil {
+		return apperror.ErrBadRequest
+	}
+
+	form.ModeratorID = session.ID
+
+	err := c.productUsc.ModerateReview(ctx.Request().Context(), form)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(http.StatusOK,
+		map[string]interface{}{
+			"Status": "Success",
+		},
+	)
+}
@@ -0,0 +1,72 @@
+package product
+
+import (
+	"net/http"
+	enPagination "ordent/internal/entity/pagination"
+	enUser "ordent/internal/entity/user"
+	"ordent/internal/pkg/apperror"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Suggest complete the search box, queries shorter than 2 characters get
+// empty suggestions
+func (c *Controller) Suggest(ctx echo.Context) error {
+	limit, err := parseLimit(ctx)
+	if err != nil {
+		return apperror.ErrBadRequest
+	}
+
+	suggestion, err := c.productUsc.Suggest(ctx.Request().Context(), ctx.QueryParam("q"), limit)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(http.StatusOK,
+		map[string]interface{}{
+			"Status": "Success",
+			"Data":   suggestion,
+		},
+	)
+}
+
+// GetZeroResultQueries report the searches that found nothing
+func (c *Controller) GetZeroResultQueries(ctx echo.Context) error {
+
+	session := ctx.Get(enUser.SessionContextKey).(enUser.Session)
+
+	sessionData := c.userUsc.GetUserSession(session)
+	if sessionData == nil {
+		return apperror.ErrUnauthorized
+	}
+
+	if !session.IsAdmin {
+		return apperror.ErrNotAdmin
+	}
+
+	var err error
+	page := enPagination.Request{
+		Cursor: ctx.QueryParam("cursor"),
+	}
+
+	if limit := ctx.QueryParam("limit"); limit != "" {
+		page.Limit, err = strconv.Atoi(limit)
+		if err != nil {
+			return apperror.ErrBadRequest
+		}
+	}
+
+	queries, paging, err := c.productUsc.GetZeroResultQueries(ctx.Request().Context(), page)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(http.StatusOK,
+		map[string]interface{}{
+			"Status": "Success",
+			"Data":   queries,
+			"Paging": paging,
+		},
+	)
+}
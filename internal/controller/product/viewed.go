@@ -0,0 +1,74 @@
+package product
+
+import (
+	"context"
+	"net/http"
+	enUser "ordent/internal/entity/user"
+	"ordent/internal/pkg/apperror"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// views are tracked after the product was served, at most maxTrackingViews
+// at a time and each within trackViewTimeout
+const (
+	maxTrackingViews = 64
+	trackViewTimeout = 5 * time.Second
+)
+
+// trackView record the view of a logged in user in the background so the
+// product endpoint never waits on it. Tracking is best effort, views coming
+// while every slot is busy are dropped.
+func (c *Controller) trackView(ctx echo.Context, productID int64) {
+	session, ok := ctx.Get(enUser.SessionContextKey).(enUser.Session)
+	if !ok {
+		return
+	}
+
+	select {
+	case c.viewSlots <- struct{}{}:
+	default:
+		return
+	}
+
+	go func() {
+		defer func() { <-c.viewSlots }()
+
+		ctx, cancel := context.WithTimeout(context.Background(), trackViewTimeout)
+		defer cancel()
+
+		if c.userUsc.GetUserSession(session) == nil {
+			return
+		}
+
+		c.productUsc.TrackView(ctx, session.ID, productID)
+	}()
+}
+
+func (c *Controller) GetRecentlyViewed(ctx echo.Context) error {
+
+	session := ctx.Get(enUser.SessionContextKey).(enUser.Session)
+
+	sessionData := c.userUsc.GetUserSession(session)
+	if sessionData == nil {
+		return apperror.ErrUnauthorized
+	}
+
+	limit, err := parseLimit(ctx)
+	if err != nil {
+		return apperror.ErrBadRequest
+	}
+
+	products, err := c.productUsc.GetRecentlyViewed(ctx.Request().Context(), session.ID, limit)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(http.StatusOK,
+		map[string]interface{}{
+			"Status": "Success",
+			"Data":   products,
+		},
+	)
+}
@@ -0,0 +1,217 @@
+package promotion
+
+import (
+	"context"
+	"net/http"
+	enPagination "ordent/internal/entity/pagination"
+	enPromotion "ordent/internal/entity/promotion"
+	enUser "ordent/internal/entity/user"
+	"ordent/internal/pkg/apperror"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+)
+
+type (
+	userUsecase interface {
+		GetUserSession(sess enUser.Session) *enUser.SessionData
+	}
+
+	promotionUsecase interface {
+		InsertPromotion(ctx context.Context, form enPromotion.PromotionRequest) (int64, error)
+		UpdatePromotion(ctx context.Context, form enPromotion.PromotionRequest) error
+		DeletePromotion(ctx context.Context, promotionID int64) error
+		GetPromotion(ctx context.Context, promotionID int64) (*enPromotion.Promotion, error)
+		GetPromotions(ctx context.Context, page enPagination.Request) ([]enPromotion.Promotion, enPagination.Paging, error)
+	}
+)
+
+type Controller struct {
+	userUsc      userUsecase
+	promotionUsc promotionUsecase
+}
+
+func NewController(
+	userUsc userUsecase,
+	promotionUsc promotionUsecase,
+) *Controller {
+	return &Controller{
+		userUsc:      userUsc,
+		promotionUsc: promotionUsc,
+	}
+}
+
+func (c *Controller) InsertPromotion(ctx echo.Context) error {
+
+	session := ctx.Get(enUser.SessionContextKey).(enUser.Session)
+
+	sessionData := c.userUsc.GetUserSession(session)
+	if sessionData == nil {
+		return apperror.ErrUnauthorized
+	}
+
+	if !session.IsAdmin {
+		return apperror.ErrNotAdmin
+	}
+
+	form := enPromotion.PromotionRequest{}
+
+	if err := ctx.Bind(&form); err != nil {
+		return apperror.ErrBadRequest
+	}
+
+	promotionID, err := c.promotionUsc.InsertPromotion(ctx.Request().Context(), form)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(http.StatusOK,
+		map[string]interface{}{
+			"Status": "Success",
+			"ID":     promotionID,
+		},
+	)
+}
+
+func (c *Controller) UpdatePromotion(ctx echo.Context) error {
+
+	session := ctx.Get(enUser.SessionContextKey).(enUser.Session)
+
+	sessionData := c.userUsc.GetUserSession(session)
+	if sessionData == nil {
+		return apperror.ErrUnauthorized
+	}
+
+	if !session.IsAdmin {
+		return apperror.ErrNotAdmin
+	}
+
+	form := enPromotion.PromotionRequest{}
+
+	if err := ctx.Bind(&form); err != nil {
+		return apperror.ErrBadRequest
+	}
+
+	err := c.promotionUsc.UpdatePromotion(ctx.Request().Context(), form)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(http.StatusOK,
+		map[string]interface{}{
+			"Status": "Success",
+		},
+	)
+}
+
+func (c *Controller) DeletePromotion(ctx echo.Context) error {
+
+	session := ctx.Get(enUser.SessionContextKey).(enUser.Session)
+
+	sessionData := c.userUsc.GetUserSession(session)
+	if sessionData == nil {
+		return apperror.ErrUnauthorized
+	}
+
+	if !session.IsAdmin {
+		return apperror.ErrNotAdmin
+	}
+
+	promotionID, err := strconv.ParseInt(ctx.QueryParam("promotionID"), 0, 64)
+	if err != nil {
+		return apperror.ErrBadRequest
+	}
+
+	err = c.promotionUsc.DeletePromotion(ctx.Request().Context(), promotionID)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(http.StatusOK,
+		map[string]interface{}{
+			"Status": "Success",
+		},
+	)
+}
+
+func (c *Controller) GetPromotion(ctx echo.Context) error {
+
+	session := ctx.Get(enUser.SessionContextKey).(enUser.Session)
+
+	sessionData := c.userUsc.GetUserSession(session)
+	if sessionData == nil {
+		return apperror.ErrUnauthorized
+	}
+
+	if !session.IsAdmin {
+		return apperror.ErrNotAdmin
+	}
+
+	promotionID, err := strconv.ParseInt(ctx.QueryParam("promotionID"), 0, 64)
+	if err != nil {
+		return apperror.ErrBadRequest
+	}
+
+	promotion, err := c.promotionUsc.GetPromotion(ctx.Request().Context(), promotionID)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(http.StatusOK,
+		map[string]interface{}{
+			"Status": "Success",
+			"Data":   promotion,
+		},
+	)
+}
+
+func (c *Controller) GetPromotions(ctx echo.Context) error {
+
+	session := ctx.Get(enUser.SessionContextKey).(enUser.Session)
+
+	sessionData := c.userUsc.GetUserSession(session)
+	if sessionData == nil {
+		return apperror.ErrUnauthorized
+	}
+
+	if !session.IsAdmin {
+		return apperror.ErrNotAdmin
+	}
+
+	page, err := parsePage(ctx)
+	if err != nil {
+		return apperror.ErrBadRequest
+	}
+
+	promotions, paging, err := c.promotionUsc.GetPromotions(ctx.Request().Context(), page)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(http.StatusOK,
+		map[string]interface{}{
+			"Status": "Success",
+			"Data":   promotions,
+			"Paging": paging,
+		},
+	)
+}
+
+func parsePage(ctx echo.Context) (enPagination.Request, error) {
+	page := enPagination.Request{
+		Cursor: ctx.QueryParam("cursor"),
+	}
+
+	limit := ctx.QueryParam("limit")
+	if limit == "" {
+		return page, nil
+	}
+
+	limitInt, err := strconv.Atoi(limit)
+	if err != nil {
+		return page, err
+	}
+	page.Limit = limitInt
+
+	return page, nil
+}
@@ -3,8 +3,11 @@ package transaction
 import (
 	"context"
 	"net/http"
+	enPagination "ordent/internal/entity/pagination"
 	enTransaction "ordent/internal/entity/transaction"
 	enUser "ordent/internal/entity/user"
+	"ordent/internal/pkg/apperror"
+	"strconv"
 
 	"github.com/labstack/echo/v4"
 )
@@ -16,8 +19,8 @@ type (
 
 	transactionUsecase interface {
 		CreateTransaction(ctx context.Context, form enTransaction.TransactionRequest) (int64, error)
-		GetTransactionsByUser(ctx context.Context, userID int64) ([]enTransaction.Transaction, error)
-		GetAllTransactions(ctx context.Context) ([]enTransaction.Transaction, error)
+		GetTransactionsByUser(ctx context.Context, userID int64, page enPagination.Request) ([]enTransaction.Transaction, enPagination.Paging, error)
+		GetAllTransactions(ctx context.Context, page enPagination.Request) ([]enTransaction.Transaction, enPagination.Paging, error)
 	}
 )
 
@@ -42,30 +45,21 @@ func (c *Controller) CreateTransaction(ctx echo.Context) error {
 
 	sessionData := c.userUc.GetUserSession(session)
 	if sessionData == nil {
-		return ctx.JSON(http.StatusUnauthorized,
-			map[string]interface{}{
-				"Error": "Unauthorized",
-			},
-		)
+		return apperror.ErrUnauthorized
 	}
 
 	form := enTransaction.TransactionRequest{}
 
 	if err := ctx.Bind(&form); err != nil {
-		return ctx.JSON(http.StatusBadRequest,
-			map[string]interface{}{
-				"Error": "Bad Request",
-			},
-		)
+		return apperror.ErrBadRequest
 	}
 
+	// orders are always placed for the logged in user
+	form.UserID = session.ID
+
 	id, err := c.transactionUc.CreateTransaction(ctx.Request().Context(), form)
 	if err != nil {
-		return ctx.JSON(http.StatusInternalServerError,
-			map[string]interface{}{
-				"Error": err.Error(),
-			},
-		)
+		return err
 	}
 
 	return ctx.JSON(http.StatusOK,
@@ -82,26 +76,24 @@ func (c *Controller) GetTransactionsByUser(ctx echo.Context) error {
 
 	sessionData := c.userUc.GetUserSession(session)
 	if sessionData == nil {
-		return ctx.JSON(http.StatusUnauthorized,
-			map[string]interface{}{
-				"Error": "Unauthorized",
-			},
-		)
+		return apperror.ErrUnauthorized
 	}
 
-  response, err := c.transactionUc.GetTransactionsByUser(ctx.Request().Context(), session.ID)
+  page, err := parsePage(ctx)
+  if err != nil {
+    return apperror.ErrBadRequest
+  }
+
+  response, paging, err := c.transactionUc.GetTransactionsByUser(ctx.Request().Context(), session.ID, page)
   if err != nil {
-    return ctx.JSON(http.StatusInternalServerError,
-			map[string]interface{}{
-				"Error": err.Error(),
-			},
-		)
+    return err
   }
 
   return ctx.JSON(http.StatusOK,
 		map[string]interface{}{
 			"Status": "Success",
 			"Data":     response,
+			"Paging":   paging,
 		},
 	)
 }
@@ -112,34 +104,48 @@ func (c *Controller) GetAllTransactions(ctx echo.Context) error {
 
 	sessionData := c.userUc.GetUserSession(session)
 	if sessionData == nil {
-		return ctx.JSON(http.StatusUnauthorized,
-			map[string]interface{}{
-				"Error": "Unauthorized",
-			},
-		)
+		return apperror.ErrUnauthorized
 	}
   
   if !session.IsAdmin {
-		return ctx.JSON(http.StatusUnauthorized,
-			map[string]interface{}{
-				"Error": "Not Admin",
-			},
-		)
+		return apperror.ErrNotAdmin
 	}
 
-  response, err := c.transactionUc.GetAllTransactions(ctx.Request().Context())
+  page, err := parsePage(ctx)
   if err != nil {
-    return ctx.JSON(http.StatusInternalServerError,
-			map[string]interface{}{
-				"Error": err.Error(),
-			},
-		)
+    return apperror.ErrBadRequest
+  }
+
+  response, paging, err := c.transactionUc.GetAllTransactions(ctx.Request().Context(), page)
+  if err != nil {
+    return err
   }
 
   return ctx.JSON(http.StatusOK,
 		map[string]interface{}{
 			"Status": "Success",
 			"Data":     response,
+			"Paging":   paging,
 		},
 	)
 }
+
+// parsePage read cursor and optional limit from query params
+func parsePage(ctx echo.Context) (enPagination.Request, error) {
+	page := enPagination.Request{
+		Cursor: ctx.QueryParam("cursor"),
+	}
+
+	limit := ctx.QueryParam("limit")
+	if limit == "" {
+		return page, nil
+	}
+
+	limitInt, err := strconv.Atoi(limit)
+	if err != nil {
+		return page, err
+	}
+	page.Limit = limitInt
+
+	return page, nil
+}
@@ -5,6 +5,7 @@ import (
 	"net/http"
 
 	enUser "ordent/internal/entity/user"
+	"ordent/internal/pkg/apperror"
 
 	"github.com/labstack/echo/v4"
 )
@@ -33,20 +34,12 @@ func (c *Controller) Register (ctx echo.Context) error {
   form := enUser.RegisterForm{}
 
   if err := ctx.Bind(&form); err != nil {
-    return ctx.JSON(http.StatusBadRequest,
-      map[string]interface{}{
-        "Error": "Bad Request",
-      },
-    )
+    return apperror.ErrBadRequest
   }
 
   response, err := c.user.RegisterUser(ctx.Request().Context(), form)
   if err != nil {
-    return ctx.JSON(http.StatusInternalServerError,
-      map[string]interface{}{
-        "Error": err.Error(),
-      },
-    )
+    return err
   }
 
   return ctx.JSON(http.StatusOK,
@@ -62,20 +55,12 @@ func (c *Controller) Login (ctx echo.Context) error {
   form := enUser.LoginRequest{}
 
   if err := ctx.Bind(&form); err != nil {
-    return ctx.JSON(http.StatusBadRequest,
-      map[string]interface{}{
-        "Error": "Bad Request",
-      },
-    )
+    return apperror.ErrBadRequest
   }
 
   response, err := c.user.Login(ctx.Request().Context(), form)
   if err != nil {
-    return ctx.JSON(http.StatusInternalServerError,
-      map[string]interface{}{
-        "Error": err.Error(),
-      },
-    )
+    return err
   }
 
   return ctx.JSON(http.StatusOK,
@@ -91,20 +76,12 @@ func (c *Controller) Logout(ctx echo.Context) error {
 
   sessionData := c.user.GetUserSession(session)
   if sessionData == nil {
-    return ctx.JSON(http.StatusUnauthorized,
-      map[string]interface{}{
-        "Error": "Unauthorized",
-      },
-    )
+    return apperror.ErrUnauthorized
   }
 
   err := c.user.Logout(session)
   if err != nil {
-    return ctx.JSON(http.StatusInternalServerError,
-      map[string]interface{}{
-        "Error": err.Error(),
-      },
-    )
+    return err
   }
 
   return ctx.JSON(http.StatusOK, "Success Log out")
@@ -115,20 +92,12 @@ func (c *Controller) GetUserWallet(ctx echo.Context) error {
 
   sessionData := c.user.GetUserSession(session)
   if sessionData == nil {
-    return ctx.JSON(http.StatusUnauthorized,
-      map[string]interface{}{
-        "Error": "Unauthorized",
-      },
-    )
+    return apperror.ErrUnauthorized
   }
 
   response, err := c.user.GetUserWallet(ctx.Request().Context(), session.Username)
   if err != nil {
-    return ctx.JSON(http.StatusInternalServerError,
-      map[string]interface{}{
-        "Error": err.Error(),
-      },
-    )
+    return err
   }
 
   return ctx.JSON(http.StatusOK,
@@ -143,30 +112,18 @@ func (c *Controller) AddWallet(ctx echo.Context) error {
 
   sessionData := c.user.GetUserSession(session)
   if sessionData == nil {
-    return ctx.JSON(http.StatusUnauthorized,
-      map[string]interface{}{
-        "Error": "Unauthorized",
-      },
-    )
+    return apperror.ErrUnauthorized
   }
 
   form := enUser.WalletRequest{}
 
   if err := ctx.Bind(&form); err != nil {
-    return ctx.JSON(http.StatusBadRequest,
-      map[string]interface{}{
-        "Error": "Bad Request",
-      },
-    )
+    return apperror.ErrBadRequest
   }
 
   err := c.user.AddWallet(ctx.Request().Context(), form.Amount, session.ID)
   if err != nil {
-    return ctx.JSON(http.StatusInternalServerError,
-      map[string]interface{}{
-        "Error": err.Error(),
-      },
-    )
+    return err
   }
 
   return ctx.JSON(http.StatusOK, "Success")
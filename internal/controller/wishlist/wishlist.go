@@ -0,0 +1,149 @@
+package wishlist
+
+import (
+	"context"
+	"net/http"
+	enPagination "ordent/internal/entity/pagination"
+	enUser "ordent/internal/entity/user"
+	enWishlist "ordent/internal/entity/wishlist"
+	"ordent/internal/pkg/apperror"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+)
+
+type (
+	userUsecase interface {
+		GetUserSession(sess enUser.Session) *enUser.SessionData
+	}
+
+	wishlistUsecase interface {
+		InsertItem(ctx context.Context, form enWishlist.ItemRequest) error
+		DeleteItem(ctx context.Context, form enWishlist.ItemRequest) error
+		GetItems(ctx context.Context, userID int64, page enPagination.Request) ([]enWishlist.Item, enPagination.Paging, error)
+	}
+)
+
+type Controller struct {
+	userUsc     userUsecase
+	wishlistUsc wishlistUsecase
+}
+
+func NewController(
+	userUsc userUsecase,
+	wishlistUsc wishlistUsecase,
+) *Controller {
+	return &Controller{
+		userUsc:     userUsc,
+		wishlistUsc: wishlistUsc,
+	}
+}
+
+// InsertItem save a product to the wishlist of the user, saving it again
+// changes nothing
+func (c *Controller) InsertItem(ctx echo.Context) error {
+
+	session := ctx.Get(enUser.SessionContextKey).(enUser.Session)
+
+	sessionData := c.userUsc.GetUserSession(session)
+	if sessionData == nil {
+		return apperror.ErrUnauthorized
+	}
+
+	form := enWishlist.ItemRequest{}
+
+	if err := ctx.Bind(&form); err != nil {
+		return apperror.ErrBadRequest
+	}
+
+	form.UserID = session.ID
+
+	err := c.wishlistUsc.InsertItem(ctx.Request().Context(), form)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(http.StatusOK,
+		map[string]interface{}{
+			"Status": "Success",
+		},
+	)
+}
+
+func (c *Controller) DeleteItem(ctx echo.Context) error {
+
+	session := ctx.Get(enUser.SessionContextKey).(enUser.Session)
+
+	sessionData := c.userUsc.GetUserSession(session)
+	if sessionData == nil {
+		return apperror.ErrUnauthorized
+	}
+
+	productID, err := strconv.ParseInt(ctx.QueryParam("productID"), 0, 64)
+	if err != nil {
+		return apperror.ErrBadRequest
+	}
+
+	form := enWishlist.ItemRequest{
+		UserID:    session.ID,
+		ProductID: productID,
+	}
+
+	err = c.wishlistUsc.DeleteItem(ctx.Request().Context(), form)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(http.StatusOK,
+		map[string]interface{}{
+			"Status": "Success",
+		},
+	)
+}
+
+func (c *Controller) GetItems(ctx echo.Context) error {
+
+	session := ctx.Get(enUser.SessionContextKey).(enUser.Session)
+
+	sessionData := c.userUsc.GetUserSession(session)
+	if sessionData == nil {
+		return apperror.ErrUnauthorized
+	}
+
+	page, err := parsePage(ctx)
+	if err != nil {
+		return apperror.ErrBadRequest
+	}
+
+	items, paging, err := c.wishlistUsc.GetItems(ctx.Request().Context(), session.ID, page)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(http.StatusOK,
+		map[string]interface{}{
+			"Status": "Success",
+			"Data":   items,
+			"Paging": paging,
+		},
+	)
+}
+
+func parsePage(ctx echo.Context) (enPagination.Request, error) {
+	page := enPagination.Request{
+		Cursor: ctx.QueryParam("cursor"),
+	}
+
+	limit := ctx.QueryParam("limit")
+	if limit == "" {
+		return page, nil
+	}
+
+	limitInt, err := strconv.Atoi(limit)
+	if err != nil {
+		return page, err
+	}
+	page.Limit = limitInt
+
+	return page, nil
+}
@@ -0,0 +1,28 @@
+package category
+
+import "ordent/internal/pkg/apperror"
+
+var (
+	ErrNotFound       = apperror.NotFound("category_not_found", "Category not existed")
+	ErrParentNotFound = apperror.NotFound("parent_category_not_found", "Parent category not existed")
+)
+
+type Category struct {
+	ID       int64  `json:"id" db:"id"`
+	ParentID *int64 `json:"parentID" db:"parent_id"`
+	Name     string `json:"name" db:"name"`
+	Slug     string `json:"slug" db:"slug"`
+}
+
+type CategoryRequest struct {
+	ID       int64  `json:"id"`
+	ParentID *int64 `json:"parentID"`
+	Name     string `json:"name"`
+	Slug     string `json:"slug"`
+}
+
+// ProductCategory link a product to one of its categories
+type ProductCategory struct {
+	ProductID int64 `db:"product_id"`
+	Category
+}
@@ -0,0 +1,112 @@
+package coupon
+
+import (
+	"time"
+
+	"ordent/internal/pkg/apperror"
+)
+
+var (
+	ErrCouponUnavailable = apperror.Conflict("coupon_unavailable", "coupon is not available anymore")
+	ErrCouponUsed        = apperror.Conflict("coupon_used", "coupon usage limit reached")
+	ErrNotFound          = apperror.NotFound("coupon_not_found", "Coupon not existed")
+)
+
+const (
+	TypePercentage = "percentage"
+	TypeFixed      = "fixed"
+)
+
+// Coupon give a percentage or fixed discount on an order line. Products and
+// categories restrict where it applies, empty means everywhere. Nil limits
+// and validity bounds are unlimited.
+type Coupon struct {
+	ID           int64      `json:"id" db:"id"`
+	Code         string     `json:"code" db:"code"`
+	Description  string     `json:"description" db:"description"`
+	Type         string     `json:"type" db:"type"`
+	Value        int64      `json:"value" db:"value"`
+	MaxDiscount  *int64     `json:"maxDiscount" db:"max_discount"`
+	MinSpend     int64      `json:"minSpend" db:"min_spend"`
+	UsageLimit   *int64     `json:"usageLimit" db:"usage_limit"`
+	PerUserLimit *int64     `json:"perUserLimit" db:"per_user_limit"`
+	UsedCount    int64      `json:"usedCount" db:"used_count"`
+	ValidFrom    *time.Time `json:"validFrom" db:"valid_from"`
+	ValidUntil   *time.Time `json:"validUntil" db:"valid_until"`
+	Active       bool       `json:"active" db:"active"`
+	ProductIDs   []int64    `json:"productIDs" db:"-"`
+	CategoryIDs  []int64    `json:"categoryIDs" db:"-"`
+	CreatedTime  time.Time  `json:"createdTime" db:"created_time"`
+}
+
+type CouponRequest struct {
+	ID           int64      `json:"id"`
+	Code         string     `json:"code"`
+	Description  string     `json:"description"`
+	Type         string     `json:"type"`
+	Value        int64      `json:"value"`
+	MaxDiscount  *int64     `json:"maxDiscount"`
+	MinSpend     int64      `json:"minSpend"`
+	UsageLimit   *int64     `json:"usageLimit"`
+	PerUserLimit *int64     `json:"perUserLimit"`
+	ValidFrom    *time.Time `json:"validFrom"`
+	ValidUntil   *time.Time `json:"validUntil"`
+	Active       *bool      `json:"active"`
+	ProductIDs   []int64    `json:"productIDs"`
+	CategoryIDs  []int64    `json:"categoryIDs"`
+}
+
+// Discount of the coupon on a subtotal, never more than the subtotal
+func (c Coupon) Discount(subtotal int64) int64 {
+	discount := c.Value
+	if c.Type == TypePercentage {
+		discount = subtotal * c.Value / 100
+		if c.MaxDiscount != nil && discount > *c.MaxDiscount {
+			discount = *c.MaxDiscount
+		}
+	}
+
+	if discount > subtotal {
+		discount = subtotal
+	}
+
+	return discount
+}
+
+// ApplyRequest price one order line with a coupon code
+type ApplyRequest struct {
+	Code       string `json:"code"`
+	UserID     int64  `json:"-"`
+	ProductID  int64  `json:"productID"`
+	SKU        string `json:"sku"`
+	VariantID  int64  `json:"-"`
+	ItemAmount int64  `json:"itemAmount"`
+}
+
+type Quote struct {
+	CouponID int64  `json:"couponID"`
+	Code     string `json:"code"`
+	Subtotal int64  `json:"subtotal"`
+	Discount int64  `json:"discount"`
+	Total    int64  `json:"total"`
+}
+
+type Redemption struct {
+	ID            int64     `json:"id" db:"id"`
+	CouponID      int64     `json:"couponID" db:"coupon_id"`
+	UserID        int64     `json:"userID" db:"user_id"`
+	Username      string    `json:"username" db:"username"`
+	TransactionID int64     `json:"transactionID" db:"transaction_id"`
+	Discount      int64     `json:"discount" db:"discount"`
+	CreatedTime   time.Time `json:"createdTime" db:"created_time"`
+}
+
+// Report sum the redemptions of a coupon inside a period
+type Report struct {
+	CouponID    int64  `json:"couponID" db:"coupon_id"`
+	Code        string `json:"code" db:"code"`
+	Redemptions int64  `json:"redemptions" db:"redemptions"`
+	Users       int64  `json:"users" db:"users"`
+	Discount    int64  `json:"discount" db:"discount"`
+	Revenue     int64  `json:"revenue" db:"revenue"`
+}
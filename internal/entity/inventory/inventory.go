@@ -0,0 +1,87 @@
+package inventory
+
+import "time"
+
+const (
+	ReasonReceived  = "received"
+	ReasonDamaged   = "damaged"
+	ReasonReturned  = "returned"
+	ReasonStockTake = "stock_take"
+
+	// recorded by the system only
+	ReasonOpening = "opening"
+	ReasonInitial = "initial"
+	ReasonSale    = "sale"
+	ReasonImport  = "import"
+	// product stock handed to its first variant
+	ReasonTransfer = "transfer"
+)
+
+const EventLowStock = "low_stock"
+
+// AdjustmentReasons are the reasons an admin can post, with the sign the delta
+// must have. Stock-take corrections go both ways.
+var AdjustmentReasons = map[string]int{
+	ReasonReceived:  1,
+	ReasonDamaged:   -1,
+	ReasonReturned:  1,
+	ReasonStockTake: 0,
+}
+
+// Movement is one change of stock, the stock of a product or SKU is the sum
+// of its movements
+type Movement struct {
+	ID          int64     `json:"id" db:"id"`
+	ProductID   int64     `json:"productID" db:"product_id"`
+	VariantID   *int64    `json:"variantID" db:"variant_id"`
+	WarehouseID int64     `json:"warehouseID" db:"warehouse_id"`
+	Delta       int64     `json:"delta" db:"delta"`
+	Reason      string    `json:"reason" db:"reason"`
+	Note        string    `json:"note" db:"note"`
+	UserID      *int64    `json:"userID" db:"user_id"`
+	Username    string    `json:"username" db:"username"`
+	CreatedTime time.Time `json:"createdTime" db:"created_time"`
+}
+
+// AdjustmentRequest change the stock of one warehouse, WarehouseID 0 is the
+// default warehouse
+type AdjustmentRequest struct {
+	ProductID   int64  `json:"productID"`
+	VariantID   int64  `json:"variantID"`
+	SKU         string `json:"sku"`
+	WarehouseID int64  `json:"warehouseID"`
+	Delta       int64  `json:"delta"`
+	Reason      string `json:"reason"`
+	Note        string `json:"note"`
+}
+
+// Reconciliation compare the stored stock of a product or SKU with the sum of
+// its movements and the sum of its warehouse stocks
+type Reconciliation struct {
+	ProductID      int64  `json:"productID" db:"product_id"`
+	VariantID      *int64 `json:"variantID" db:"variant_id"`
+	SKU            string `json:"sku" db:"sku"`
+	Stock          int64  `json:"stock" db:"stock"`
+	MovementTotal  int64  `json:"movementTotal" db:"movement_total"`
+	WarehouseTotal int64  `json:"warehouseTotal" db:"warehouse_total"`
+	Difference     int64  `json:"difference" db:"-"`
+}
+
+// Matched tell whether stock, movements and warehouses agree
+func (r Reconciliation) Matched() bool {
+	return r.Stock == r.MovementTotal && r.Stock == r.WarehouseTotal
+}
+
+type ThresholdRequest struct {
+	ProductID int64 `json:"productID"`
+	Threshold int64 `json:"threshold"`
+}
+
+// LowStock is a product whose stock is below its reorder threshold
+type LowStock struct {
+	ProductID int64  `json:"productID" db:"id"`
+	Name      string `json:"name" db:"name"`
+	Stock     int64  `json:"stock" db:"stock"`
+	Threshold int64  `json:"threshold" db:"reorder_threshold"`
+	Shortfall int64  `json:"shortfall" db:"shortfall"`
+}
@@ -0,0 +1,43 @@
+package inventory
+
+import (
+	"time"
+
+	"ordent/internal/pkg/apperror"
+)
+
+var (
+	ErrReservationNotHeld  = apperror.Conflict("reservation_not_held", "reservation is not held anymore")
+	ErrReservationNotFound = apperror.NotFound("reservation_not_found", "Reservation not exist")
+)
+
+const (
+	ReservationHeld      = "held"
+	ReservationCommitted = "committed"
+	ReservationReleased  = "released"
+	ReservationExpired   = "expired"
+)
+
+// Reservation hold stock of one warehouse for a checkout until it is
+// committed by a transaction, released or expired
+type Reservation struct {
+	ID          int64     `json:"id" db:"id"`
+	UserID      int64     `json:"userID" db:"user_id"`
+	ProductID   int64     `json:"productID" db:"product_id"`
+	VariantID   *int64    `json:"variantID" db:"variant_id"`
+	WarehouseID int64     `json:"warehouseID" db:"warehouse_id"`
+	Quantity    int64     `json:"quantity" db:"quantity"`
+	Status      string    `json:"status" db:"status"`
+	ExpiresTime time.Time `json:"expiresTime" db:"expires_time"`
+	CreatedTime time.Time `json:"createdTime" db:"created_time"`
+}
+
+type ReservationRequest struct {
+	UserID    int64    `json:"-"`
+	ProductID int64    `json:"productID"`
+	SKU       string   `json:"sku"`
+	VariantID int64    `json:"-"`
+	Quantity  int64    `json:"quantity"`
+	Latitude  *float64 `json:"latitude"`
+	Longitude *float64 `json:"longitude"`
+}
@@ -0,0 +1,42 @@
+package inventory
+
+import "ordent/internal/pkg/apperror"
+
+var ErrWarehouseNotFound = apperror.NotFound("warehouse_not_found", "Warehouse not existed")
+
+const (
+	AllocationPriority  = "priority"
+	AllocationMostStock = "most_stock"
+	AllocationNearest   = "nearest"
+)
+
+// Warehouse ship orders, a lower priority is preferred
+type Warehouse struct {
+	ID        int64    `json:"id" db:"id"`
+	Code      string   `json:"code" db:"code"`
+	Name      string   `json:"name" db:"name"`
+	Latitude  *float64 `json:"latitude" db:"latitude"`
+	Longitude *float64 `json:"longitude" db:"longitude"`
+	Priority  int64    `json:"priority" db:"priority"`
+	Active    bool     `json:"active" db:"active"`
+}
+
+type WarehouseRequest struct {
+	ID        int64    `json:"id"`
+	Code      string   `json:"code"`
+	Name      string   `json:"name"`
+	Latitude  *float64 `json:"latitude"`
+	Longitude *float64 `json:"longitude"`
+	Priority  int64    `json:"priority"`
+	Active    *bool    `json:"active"`
+}
+
+// Allocation choose the warehouse fulfilling an order line. Nearest needs the
+// delivery location and falls back to priority without it. A held
+// reservation fulfils the line from its own warehouse instead.
+type Allocation struct {
+	Strategy      string
+	Latitude      *float64
+	Longitude     *float64
+	ReservationID int64
+}
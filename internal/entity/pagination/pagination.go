@@ -0,0 +1,32 @@
+package pagination
+
+const (
+	DefaultLimit = 10
+	MaxLimit     = 100
+)
+
+// Cursor is the decoded position inside a keyset ordered listing.
+// Key holds the sort key of the row (e.g. sold, created time in micro seconds)
+// and ID breaks ties between rows sharing the same key.
+type Cursor struct {
+	Key      int64 `json:"k"`
+	ID       int64 `json:"i"`
+	Backward bool  `json:"b,omitempty"`
+}
+
+// IsZero reports whether the cursor points at the first page
+func (c Cursor) IsZero() bool {
+	return c.ID == 0
+}
+
+type Request struct {
+	Cursor string
+	Limit  int
+	// Sort select the listing order where a listing offers several
+	Sort string
+}
+
+type Paging struct {
+	NextCursor string `json:"nextCursor"`
+	PrevCursor string `json:"prevCursor"`
+}
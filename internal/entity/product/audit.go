@@ -0,0 +1,44 @@
+package product
+
+import (
+	"encoding/json"
+	"time"
+)
+
+const (
+	AuditActionInsert        = "insert"
+	AuditActionUpdate        = "update"
+	AuditActionDelete        = "delete"
+	AuditActionRestore       = "restore"
+	AuditActionPurge         = "purge"
+	AuditActionImport        = "import"
+	AuditActionInsertVariant = "insert_variant"
+	AuditActionUpdateVariant = "update_variant"
+	AuditActionDeleteVariant = "delete_variant"
+	AuditActionUploadImage   = "upload_image"
+	AuditActionDeleteImage   = "delete_image"
+	AuditActionReorderImages = "reorder_images"
+	AuditActionSetThreshold  = "set_threshold"
+	AuditActionAdjustStock   = "adjust_stock"
+)
+
+// Audit is one recorded mutation of a product. Before and After are full
+// snapshots, Diff only hold the changed fields as {"field": {"before", "after"}}
+type Audit struct {
+	ID          int64           `json:"id" db:"id"`
+	ProductID   int64           `json:"productID" db:"product_id"`
+	UserID      *int64          `json:"userID" db:"user_id"`
+	Username    string          `json:"username" db:"username"`
+	Action      string          `json:"action" db:"action"`
+	Before      json.RawMessage `json:"before" db:"before"`
+	After       json.RawMessage `json:"after" db:"after"`
+	Diff        json.RawMessage `json:"diff" db:"diff"`
+	CreatedTime time.Time       `json:"createdTime" db:"created_time"`
+}
+
+type PriceAt struct {
+	ProductID int64     `json:"productID"`
+	VariantID int64     `json:"variantID,omitempty"`
+	At        time.Time `json:"at"`
+	Price     int64     `json:"price"`
+}
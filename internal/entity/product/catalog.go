@@ -0,0 +1,41 @@
+package product
+
+const (
+	CatalogFormatCSV   = "csv"
+	CatalogFormatJSONL = "jsonl"
+
+	// CatalogBatchSize is the number of import rows applied per database transaction
+	CatalogBatchSize = 100
+)
+
+// CatalogColumns are the csv header of import and export files
+var CatalogColumns = []string{"name", "categories", "price", "stock", "sku", "options", "variant_price"}
+
+// CatalogRow is one line of a catalog file. Rows without SKU describe the
+// product itself, rows with SKU describe one variant of the product named.
+type CatalogRow struct {
+	Line         int            `json:"-" db:"-"`
+	Name         string         `json:"name" db:"name"`
+	Categories   []string       `json:"categories" db:"-"`
+	Price        int64          `json:"price" db:"price"`
+	Stock        int64          `json:"stock" db:"stock"`
+	SKU          string         `json:"sku,omitempty" db:"sku"`
+	Options      VariantOptions `json:"options,omitempty" db:"options"`
+	VariantPrice *int64         `json:"variantPrice,omitempty" db:"variant_price"`
+
+	CategoryList string `json:"-" db:"categories"`
+}
+
+type CatalogError struct {
+	Line    int    `json:"line"`
+	Field   string `json:"field,omitempty"`
+	Message string `json:"message"`
+}
+
+type ImportResult struct {
+	DryRun  bool           `json:"dryRun"`
+	Total   int            `json:"total"`
+	Created int            `json:"created"`
+	Updated int            `json:"updated"`
+	Errors  []CatalogError `json:"errors"`
+}
@@ -0,0 +1,28 @@
+package product
+
+import "ordent/internal/pkg/apperror"
+
+var ErrImageNotFound = apperror.NotFound("image_not_found", "Image not existed")
+
+type Image struct {
+	ID           int64  `json:"id" db:"id"`
+	ProductID    int64  `json:"productID" db:"product_id"`
+	StorageKey   string `json:"-" db:"storage_key"`
+	ThumbnailKey string `json:"-" db:"thumbnail_key"`
+	URL          string `json:"url" db:"url"`
+	ThumbnailURL string `json:"thumbnailURL" db:"thumbnail_url"`
+	ContentType  string `json:"contentType" db:"content_type"`
+	Size         int64  `json:"size" db:"size"`
+	Position     int    `json:"position" db:"position"`
+}
+
+type ImageUpload struct {
+	ProductID int64
+	Position  *int
+	Data      []byte
+}
+
+type ImageOrderRequest struct {
+	ProductID int64   `json:"productID"`
+	ImageIDs  []int64 `json:"imageIDs"`
+}
@@ -0,0 +1,20 @@
+package product
+
+import "ordent/internal/pkg/apperror"
+
+// trending windows, the units sold over the last day or week
+const (
+	WindowDay  = "24h"
+	WindowWeek = "7d"
+)
+
+var ErrUnknownWindow = apperror.Invalid("window", "unknown window, use 24h or 7d")
+
+// Ranking is the place of a product on a leaderboard, Units are the units
+// sold over the window of the leaderboard
+type Ranking struct {
+	Rank      int      `json:"rank"`
+	ProductID int64    `json:"productID"`
+	Units     int64    `json:"units"`
+	Product   *Product `json:"product,omitempty"`
+}
@@ -1,25 +1,77 @@
 package product
 
+import (
+	enCategory "ordent/internal/entity/category"
+	enPromotion "ordent/internal/entity/promotion"
+	"ordent/internal/pkg/apperror"
+	"time"
+)
+
 type Product struct {
-	ID    int64       `json:"id" db:"id"`
-	Name  string      `json:"name" db:"name"`
-	Type  ProductType `json:"type" db:"type"`
-	Price int64       `json:"price" db:"price"`
-	Stock int64       `json:"stock" db:"stock"`
-	Sold  int64       `json:"sold" db:"sold"`
+	ID            int64                   `json:"id" db:"id"`
+	Name          string                  `json:"name" db:"name"`
+	Price         int64                   `json:"price" db:"price"`
+	SalePrice     int64                   `json:"salePrice" db:"-"`
+	Stock         int64                   `json:"stock" db:"stock"`
+	Sold          int64                   `json:"sold" db:"sold"`
+	Reserved      int64                   `json:"reserved,omitempty" db:"-"`
+	Available     int64                   `json:"available" db:"available"`
+	Version       int64                   `json:"version" db:"version"`
+	RatingAverage float64                 `json:"ratingAverage" db:"rating_average"`
+	RatingCount   int64                   `json:"ratingCount" db:"rating_count"`
+	DeletedAt     *time.Time              `json:"deletedAt,omitempty" db:"deleted_at"`
+	UpdatedTime   *time.Time              `json:"updatedTime,omitempty" db:"updated_time"`
+	CategoryIDs   []int64                 `json:"categoryIDs,omitempty" db:"-"`
+	Categories    []enCategory.Category   `json:"categories" db:"-"`
+	Images        []Image                 `json:"images" db:"-"`
+	Promotions    []enPromotion.Promotion `json:"promotions" db:"-"`
+
+	// detail only
+	Variants       []Variant           `json:"variants,omitempty" db:"-"`
+	VariantOptions map[string][]string `json:"variantOptions,omitempty" db:"-"`
+	Availability   []Availability      `json:"availability,omitempty" db:"-"`
+}
+
+// Availability is the stock of a product, or one of its SKUs, in a warehouse
+type Availability struct {
+	WarehouseID int64  `json:"warehouseID" db:"warehouse_id"`
+	Warehouse   string `json:"warehouse" db:"warehouse"`
+	VariantID   *int64 `json:"variantID,omitempty" db:"variant_id"`
+	Stock       int64  `json:"stock" db:"stock"`
 }
 
 type ProductRequest struct {
-	Name  string      `json:"name"`
-	Type  ProductType `json:"type"`
-	Price int64       `json:"price"`
-	Stock int64       `json:"stock"`
+	Name        string  `json:"name"`
+	CategoryIDs []int64 `json:"categoryIDs"`
+	Price       int64   `json:"price"`
+	Stock       int64   `json:"stock"`
 }
 
-type ProductType string
+// ProductPatch hold the fields of a partial update, nil fields are left as is.
+// Stock and Sold only exist to reject them, they change through sales and
+// stock adjustments.
+type ProductPatch struct {
+	Name        *string  `json:"name"`
+	Price       *int64   `json:"price"`
+	CategoryIDs *[]int64 `json:"categoryIDs"`
+	Stock       *int64   `json:"stock"`
+	Sold        *int64   `json:"sold"`
+}
 
+// listing sorts, by units sold or by average rating
 const (
-	HatsProduct  ProductType = "hats"
-	TopsProduct  ProductType = "tops"
-	ShortsProcut ProductType = "shorts"
+	SortSold   = "sold"
+	SortRating = "rating"
+)
+
+var ErrUnknownSort = apperror.Invalid("sort", "unknown sort, use sold or rating")
+
+var ErrTooManyProducts = apperror.Invalid("ids", "at most 100 products can be requested at once")
+
+var ErrVersionConflict = apperror.New(apperror.KindPreconditionFailed, "version_conflict", "product was changed by someone else, reload and try again")
+
+var (
+	ErrNotFound        = apperror.NotFound("product_not_found", "Product not existed")
+	ErrDeletedNotFound = apperror.NotFound("deleted_product_not_found", "Deleted product not existed")
+	ErrSKUNotFound     = apperror.NotFound("sku_not_found", "SKU not exist")
 )
@@ -0,0 +1,73 @@
+package product
+
+import "sort"
+
+// Relation is a product frequently bought together with ProductID, Score is
+// the number of customers who bought both
+type Relation struct {
+	ProductID int64 `db:"product_id"`
+	RelatedID int64 `db:"related_id"`
+	Score     int64 `db:"score"`
+	Rank      int   `db:"rank"`
+}
+
+// CoPurchases count how many customers bought each pair of products
+type CoPurchases struct {
+	counts map[int64]map[int64]int64
+}
+
+func NewCoPurchases() *CoPurchases {
+	return &CoPurchases{counts: make(map[int64]map[int64]int64)}
+}
+
+// Add count every pair of the distinct products bought by one customer
+func (c *CoPurchases) Add(basket []int64) {
+	for _, productID := range basket {
+		for _, relatedID := range basket {
+			if productID == relatedID {
+				continue
+			}
+
+			if c.counts[productID] == nil {
+				c.counts[productID] = make(map[int64]int64)
+			}
+			c.counts[productID][relatedID]++
+		}
+	}
+}
+
+// Top return up to n relations per product, most shared customers first and
+// the lowest id on a tie, ordered by product then rank
+func (c *CoPurchases) Top(n int) []Relation {
+	productIDs := make([]int64, 0, len(c.counts))
+	for productID := range c.counts {
+		productIDs = append(productIDs, productID)
+	}
+	sort.Slice(productIDs, func(i, j int) bool { return productIDs[i] < productIDs[j] })
+
+	relations := make([]Relation, 0)
+	for _, productID := range productIDs {
+		related := make([]Relation, 0, len(c.counts[productID]))
+		for relatedID, score := range c.counts[productID] {
+			related = append(related, Relation{ProductID: productID, RelatedID: relatedID, Score: score})
+		}
+
+		sort.Slice(related, func(i, j int) bool {
+			if related[i].Score != related[j].Score {
+				return related[i].Score > related[j].Score
+			}
+			return related[i].RelatedID < related[j].RelatedID
+		})
+
+		if len(related) > n {
+			related = related[:n]
+		}
+
+		for i := range related {
+			related[i].Rank = i + 1
+		}
+		relations = append(relations, related...)
+	}
+
+	return relations
+}
@@ -0,0 +1,48 @@
+package product
+
+import (
+	"time"
+
+	"ordent/internal/pkg/apperror"
+)
+
+const (
+	ReviewPending  = "pending"
+	ReviewApproved = "approved"
+	ReviewRejected = "rejected"
+)
+
+var ErrNotVerifiedBuyer = apperror.New(apperror.KindForbidden, "not_verified_buyer", "only customers who bought the product can review it")
+
+var ErrReviewNotFound = apperror.NotFound("review_not_found", "Review not existed")
+
+// Review of a product by one of its buyers, it is shown and counted in the
+// rating once approved
+type Review struct {
+	ID             int64     `json:"id" db:"id"`
+	ProductID      int64     `json:"productID" db:"product_id"`
+	UserID         int64     `json:"userID" db:"user_id"`
+	Username       string    `json:"username" db:"username"`
+	Rating         int64     `json:"rating" db:"rating"`
+	Body           string    `json:"body" db:"body"`
+	Status         string    `json:"status" db:"status"`
+	ModerationNote string    `json:"moderationNote,omitempty" db:"moderation_note"`
+	CreatedTime    time.Time `json:"createdTime" db:"created_time"`
+	UpdatedTime    time.Time `json:"updatedTime" db:"updated_time"`
+}
+
+// ReviewRequest create or replace the review of the user, a replaced review
+// goes back to moderation
+type ReviewRequest struct {
+	ProductID int64  `json:"-"`
+	UserID    int64  `json:"-"`
+	Rating    int64  `json:"rating"`
+	Body      string `json:"body"`
+}
+
+type ModerationRequest struct {
+	ID          int64  `json:"id"`
+	Status      string `json:"status"`
+	Note        string `json:"note"`
+	ModeratorID int64  `json:"-"`
+}
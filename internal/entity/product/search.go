@@ -0,0 +1,21 @@
+package product
+
+import "time"
+
+// SearchQuery is a normalized search with its statistics, LastResults is the
+// number of products found the last time it was searched
+type SearchQuery struct {
+	ID               int64     `json:"id" db:"id"`
+	Query            string    `json:"query" db:"query"`
+	Searches         int64     `json:"searches" db:"searches"`
+	ZeroResults      int64     `json:"zeroResults" db:"zero_results"`
+	LastResults      int64     `json:"lastResults" db:"last_results"`
+	LastSearchedTime time.Time `json:"lastSearchedTime" db:"last_searched_time"`
+}
+
+// Suggestion complete what is typed in the search box with product names and
+// popular past searches
+type Suggestion struct {
+	Products []string `json:"products"`
+	Queries  []string `json:"queries"`
+}
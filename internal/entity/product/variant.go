@@ -0,0 +1,100 @@
+package product
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"time"
+
+	enPromotion "ordent/internal/entity/promotion"
+	"ordent/internal/pkg/apperror"
+)
+
+var (
+	ErrInsufficientStock = apperror.Conflict("insufficient_stock", "insufficient stock")
+	ErrVariantNotFound   = apperror.NotFound("variant_not_found", "Variant not existed")
+	ErrSKUTaken          = apperror.Conflict("sku_taken", "SKU belongs to another product")
+	ErrStockReserved     = apperror.Conflict("stock_reserved", "product stock is reserved, add the first variant once the reservations are settled")
+)
+
+// Variant is a sellable SKU of a product, e.g. a size and colour combination
+type Variant struct {
+	ID            int64          `json:"id" db:"id"`
+	ProductID     int64          `json:"productID" db:"product_id"`
+	SKU           string         `json:"sku" db:"sku"`
+	Options       VariantOptions `json:"options" db:"options"`
+	PriceOverride *int64         `json:"priceOverride" db:"price"`
+	Price         int64          `json:"price" db:"-"`
+	SalePrice     int64          `json:"salePrice" db:"-"`
+	Stock         int64          `json:"stock" db:"stock"`
+	Sold          int64          `json:"sold" db:"sold"`
+	Reserved      int64          `json:"reserved" db:"reserved"`
+	Available     bool           `json:"available" db:"-"`
+}
+
+// VariantRequest create or update a variant. Stock is the opening stock on
+// insert, afterwards it only changes through stock adjustments and sales.
+type VariantRequest struct {
+	ID        int64          `json:"id"`
+	ProductID int64          `json:"productID"`
+	SKU       string         `json:"sku"`
+	Options   VariantOptions `json:"options"`
+	Price     *int64         `json:"price"`
+	Stock     int64          `json:"stock"`
+}
+
+// VariantOptions hold the option attributes of a variant, e.g. size: M
+type VariantOptions map[string]string
+
+// Value store options as jsonb
+func (o VariantOptions) Value() (driver.Value, error) {
+	if o == nil {
+		return []byte("{}"), nil
+	}
+	return json.Marshal(o)
+}
+
+// Scan read options from jsonb
+func (o *VariantOptions) Scan(src interface{}) error {
+	var data []byte
+	switch v := src.(type) {
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	case nil:
+		*o = VariantOptions{}
+		return nil
+	default:
+		return errors.New("invalid variant options")
+	}
+
+	return json.Unmarshal(data, o)
+}
+
+// UnitPrice of one item of the product, or of its SKU when variantID is set
+func (p Product) UnitPrice(variantID int64) int64 {
+	for _, variant := range p.Variants {
+		if variant.ID == variantID && variant.PriceOverride != nil {
+			return *variant.PriceOverride
+		}
+	}
+
+	return p.Price
+}
+
+// Quote price quantity items of the product, or of its SKU, with the
+// promotions running at the given time. Listings, product detail and
+// checkout all price through it so shown and charged prices agree.
+func (p Product) Quote(variantID, quantity int64, at time.Time) enPromotion.Line {
+	return enPromotion.Price(p.Promotions, p.UnitPrice(variantID), quantity, at)
+}
+
+// WithSalePrice fill the current sale price of the product and its variants
+func (p *Product) WithSalePrice(at time.Time) {
+	p.SalePrice, _ = enPromotion.UnitPrice(p.Promotions, p.Price, at)
+
+	for i := range p.Variants {
+		p.Variants[i].SalePrice, _ = enPromotion.UnitPrice(p.Promotions, p.UnitPrice(p.Variants[i].ID), at)
+	}
+}
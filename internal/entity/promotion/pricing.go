@@ -0,0 +1,84 @@
+package promotion
+
+import "time"
+
+// Line is the price of an order line after promotions
+type Line struct {
+	ListPrice    int64   `json:"listPrice"`
+	UnitPrice    int64   `json:"unitPrice"`
+	Quantity     int64   `json:"quantity"`
+	FreeItems    int64   `json:"freeItems"`
+	Subtotal     int64   `json:"subtotal"`
+	PromotionIDs []int64 `json:"promotionIDs"`
+}
+
+// UnitPrice is the lowest price any running sale gives to listPrice, a sale
+// never raises the price
+func UnitPrice(promotions []Promotion, listPrice int64, at time.Time) (int64, int64) {
+	price, promotionID := listPrice, int64(0)
+	for _, promotion := range promotions {
+		if promotion.Kind != KindSale || !promotion.Running(at) {
+			continue
+		}
+
+		candidate := listPrice
+		if promotion.SalePrice != nil {
+			candidate = *promotion.SalePrice
+		}
+		if promotion.PercentOff != nil {
+			candidate = listPrice - listPrice*(*promotion.PercentOff)/100
+		}
+
+		if candidate < price {
+			price, promotionID = candidate, promotion.ID
+		}
+	}
+
+	return price, promotionID
+}
+
+// freeItems is the number of free items the best running bundle gives to
+// quantity, e.g. buy 2 get 1 give 1 free item for every 3 items
+func freeItems(promotions []Promotion, quantity int64, at time.Time) (int64, int64) {
+	free, promotionID := int64(0), int64(0)
+	for _, promotion := range promotions {
+		if promotion.Kind != KindBundle || !promotion.Running(at) {
+			continue
+		}
+
+		if promotion.BuyQuantity == nil || promotion.FreeQuantity == nil {
+			continue
+		}
+
+		group := *promotion.BuyQuantity + *promotion.FreeQuantity
+		candidate := quantity / group * *promotion.FreeQuantity
+		if candidate > free {
+			free, promotionID = candidate, promotion.ID
+		}
+	}
+
+	return free, promotionID
+}
+
+// Price an order line. The best sale and the best bundle stack, free items
+// are taken from the sale price.
+func Price(promotions []Promotion, listPrice, quantity int64, at time.Time) Line {
+	line := Line{
+		ListPrice:    listPrice,
+		Quantity:     quantity,
+		PromotionIDs: make([]int64, 0),
+	}
+
+	var saleID, bundleID int64
+	line.UnitPrice, saleID = UnitPrice(promotions, listPrice, at)
+	line.FreeItems, bundleID = freeItems(promotions, quantity, at)
+	line.Subtotal = line.UnitPrice * (quantity - line.FreeItems)
+
+	for _, id := range []int64{saleID, bundleID} {
+		if id != 0 {
+			line.PromotionIDs = append(line.PromotionIDs, id)
+		}
+	}
+
+	return line
+}
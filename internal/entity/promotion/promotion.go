@@ -0,0 +1,67 @@
+package promotion
+
+import (
+	"time"
+
+	"ordent/internal/pkg/apperror"
+)
+
+var ErrNotFound = apperror.NotFound("promotion_not_found", "Promotion not existed")
+
+const (
+	KindSale   = "sale"
+	KindBundle = "bundle"
+)
+
+const (
+	StateScheduled = "scheduled"
+	StateRunning   = "running"
+	StateEnded     = "ended"
+)
+
+// Promotion change the price of its products between StartsAt and EndsAt.
+// A sale lower the unit price by PercentOff or to a fixed SalePrice, a bundle
+// give FreeQuantity items for every BuyQuantity bought. Categories include
+// their sub categories.
+type Promotion struct {
+	ID           int64     `json:"id" db:"id"`
+	Name         string    `json:"name" db:"name"`
+	Kind         string    `json:"kind" db:"kind"`
+	PercentOff   *int64    `json:"percentOff,omitempty" db:"percent_off"`
+	SalePrice    *int64    `json:"salePrice,omitempty" db:"sale_price"`
+	BuyQuantity  *int64    `json:"buyQuantity,omitempty" db:"buy_quantity"`
+	FreeQuantity *int64    `json:"freeQuantity,omitempty" db:"free_quantity"`
+	StartsAt     time.Time `json:"startsAt" db:"starts_at"`
+	EndsAt       time.Time `json:"endsAt" db:"ends_at"`
+	Active       bool      `json:"active" db:"active"`
+	State        string    `json:"state" db:"state"`
+	ProductIDs   []int64   `json:"productIDs,omitempty" db:"-"`
+	CategoryIDs  []int64   `json:"categoryIDs,omitempty" db:"-"`
+	CreatedTime  time.Time `json:"createdTime" db:"created_time"`
+}
+
+type PromotionRequest struct {
+	ID           int64     `json:"id"`
+	Name         string    `json:"name"`
+	Kind         string    `json:"kind"`
+	PercentOff   *int64    `json:"percentOff"`
+	SalePrice    *int64    `json:"salePrice"`
+	BuyQuantity  *int64    `json:"buyQuantity"`
+	FreeQuantity *int64    `json:"freeQuantity"`
+	StartsAt     time.Time `json:"startsAt"`
+	EndsAt       time.Time `json:"endsAt"`
+	Active       *bool     `json:"active"`
+	ProductIDs   []int64   `json:"productIDs"`
+	CategoryIDs  []int64   `json:"categoryIDs"`
+}
+
+// ProductPromotion link a product to a promotion that applies to it
+type ProductPromotion struct {
+	ProductID int64 `db:"product_id"`
+	Promotion
+}
+
+// Running tell whether the promotion applies at the given time
+func (p Promotion) Running(at time.Time) bool {
+	return p.Active && !at.Before(p.StartsAt) && at.Before(p.EndsAt)
+}
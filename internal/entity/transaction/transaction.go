@@ -1,18 +1,44 @@
 package transaction
 
+import "time"
 
 type Transaction struct {
   Id int64 `json:"id" db:"id"`
   UserID int64 `json:"userID" db:"user_id"`
   ProductID int64 `json:"productID" db:"product_id"`
+  VariantID *int64 `json:"variantID" db:"variant_id"`
+  SKU string `json:"sku" db:"sku"`
+  WarehouseID *int64 `json:"warehouseID" db:"warehouse_id"`
   ItemAmount int64 `json:"itemAmount" db:"item_amount"`
   ProductName string `json:"productName" db:"product_name"`
   ProductType string `json:"productType" db:"product_type"`
   ProductPrice string `json:"productPrice" db:"product_price"`
+  UnitPrice int64 `json:"unitPrice" db:"unit_price"`
+  FreeItems int64 `json:"freeItems" db:"free_items"`
+  CouponID *int64 `json:"couponID" db:"coupon_id"`
+  Subtotal int64 `json:"subtotal" db:"subtotal"`
+  Discount int64 `json:"discount" db:"discount"`
+  Total int64 `json:"total" db:"total"`
+  CreatedTime time.Time `json:"createdTime" db:"created_time"`
 }
 
 type TransactionRequest struct {
   UserID int64 `json:"userID"`
   ProductID int64 `json:"productID"`
+  SKU string `json:"sku"`
+  VariantID int64 `json:"-"`
+  WarehouseID int64 `json:"-"`
+  // a held reservation fixes product, SKU and amount of the line
+  ReservationID int64 `json:"reservationID"`
   ItemAmount int64 `json:"itemAmount"`
+  CouponCode string `json:"couponCode"`
+  CouponID int64 `json:"-"`
+  UnitPrice int64 `json:"-"`
+  FreeItems int64 `json:"-"`
+  Subtotal int64 `json:"-"`
+  Discount int64 `json:"-"`
+  Total int64 `json:"-"`
+  // delivery location, used by the nearest warehouse allocation
+  Latitude *float64 `json:"latitude"`
+  Longitude *float64 `json:"longitude"`
 }
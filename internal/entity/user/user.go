@@ -1,6 +1,7 @@
 package user
 
 import (
+	"context"
 	"errors"
 	"time"
 
@@ -49,6 +50,7 @@ func (t *TokenClaim) Valid() error {
 type RegisterForm struct {
 	Username string `json:"username"`
 	Password string `json:"password"`
+	Email    string `json:"email"`
 	Salt     string `json:"-"`
 }
 
@@ -92,3 +94,16 @@ type UserWallet struct {
 	Username string `json:"username" db:"username"`
 	Wallet   int64  `json:"wallet" db:"wallet"`
 }
+
+type sessionContextKey struct{}
+
+// ContextWithSession attach the session of the logged in user to a request context
+func ContextWithSession(ctx context.Context, sess Session) context.Context {
+	return context.WithValue(ctx, sessionContextKey{}, sess)
+}
+
+// SessionFromContext return the session attached by ContextWithSession
+func SessionFromContext(ctx context.Context) (Session, bool) {
+	sess, ok := ctx.Value(sessionContextKey{}).(Session)
+	return sess, ok
+}
@@ -0,0 +1,51 @@
+package wishlist
+
+import "time"
+
+const EventBackInStock = "back_in_stock"
+
+const (
+	NotificationPending = "pending"
+	NotificationSending = "sending"
+	NotificationSent    = "sent"
+	NotificationFailed  = "failed"
+)
+
+// MaxAttempts is how often a notification is tried before it is failed
+const MaxAttempts = 5
+
+// RetryBackoff is the wait after the first failed attempt, it doubles with
+// every further attempt
+const RetryBackoff = time.Minute
+
+// ClaimTimeout is how long a notification stays claimed for sending. Claims
+// older than that were left by an instance that stopped while sending.
+const ClaimTimeout = time.Hour
+
+// Item is a product saved by a user, with its current price and stock
+type Item struct {
+	ProductID   int64     `json:"productID" db:"product_id"`
+	Name        string    `json:"name" db:"name"`
+	Price       int64     `json:"price" db:"price"`
+	Stock       int64     `json:"stock" db:"stock"`
+	InStock     bool      `json:"inStock" db:"in_stock"`
+	CreatedTime time.Time `json:"createdTime" db:"created_time"`
+}
+
+type ItemRequest struct {
+	UserID    int64 `json:"-"`
+	ProductID int64 `json:"productID"`
+}
+
+// Notification tell one user that a wishlisted product is back in stock
+type Notification struct {
+	ID          int64   `json:"id" db:"id"`
+	ProductID   int64   `json:"productID" db:"product_id"`
+	ProductName string  `json:"productName" db:"product_name"`
+	Stock       int64   `json:"stock" db:"stock"`
+	UserID      int64   `json:"userID" db:"user_id"`
+	Username    string  `json:"username" db:"username"`
+	Email       *string `json:"-" db:"email"`
+	Restock     int64   `json:"restock" db:"restock"`
+	Attempts    int64   `json:"-" db:"attempts"`
+}
@@ -0,0 +1,105 @@
+package apperror
+
+import (
+	"errors"
+	"net/http"
+)
+
+// Kind group domain errors by how the client should react, each maps to one
+// HTTP status
+type Kind string
+
+const (
+	KindValidation         Kind = "validation"
+	KindUnauthorized       Kind = "unauthorized"
+	KindForbidden          Kind = "forbidden"
+	KindNotFound           Kind = "not_found"
+	KindConflict           Kind = "conflict"
+	KindPreconditionFailed Kind = "precondition_failed"
+	KindInsufficientFunds  Kind = "insufficient_funds"
+)
+
+var statuses = map[Kind]int{
+	KindValidation:         http.StatusBadRequest,
+	KindUnauthorized:       http.StatusUnauthorized,
+	KindForbidden:          http.StatusForbidden,
+	KindNotFound:           http.StatusNotFound,
+	KindConflict:           http.StatusConflict,
+	KindPreconditionFailed: http.StatusPreconditionFailed,
+	KindInsufficientFunds:  http.StatusPaymentRequired,
+}
+
+// FieldError is the detail of a validation error on one request field
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// Error is an expected domain error. Its message is written for clients and
+// Code is stable for them to branch on, e.g. product_not_found. Internal
+// failures are plain errors and are never shown to clients.
+type Error struct {
+	Kind    Kind
+	Code    string
+	Message string
+	Fields  []FieldError
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Status is the HTTP status of the error
+func (e *Error) Status() int {
+	if status, ok := statuses[e.Kind]; ok {
+		return status
+	}
+
+	return http.StatusInternalServerError
+}
+
+var (
+	ErrBadRequest   = New(KindValidation, "bad_request", "Bad Request")
+	ErrUnauthorized = New(KindUnauthorized, "unauthorized", "Unauthorized")
+	ErrNotAdmin     = New(KindForbidden, "not_admin", "Not Admin")
+)
+
+func New(kind Kind, code, message string) *Error {
+	return &Error{
+		Kind:    kind,
+		Code:    code,
+		Message: message,
+	}
+}
+
+func NotFound(code, message string) *Error {
+	return New(KindNotFound, code, message)
+}
+
+func Conflict(code, message string) *Error {
+	return New(KindConflict, code, message)
+}
+
+func InsufficientFunds(code, message string) *Error {
+	return New(KindInsufficientFunds, code, message)
+}
+
+// Validation report invalid input, with the detail of each invalid field
+// when known
+func Validation(message string, fields ...FieldError) *Error {
+	err := New(KindValidation, "validation_failed", message)
+	err.Fields = fields
+	return err
+}
+
+// Invalid report one invalid field
+func Invalid(field, message string) *Error {
+	return Validation(message, FieldError{Field: field, Message: message})
+}
+
+// As find the domain error in the chain of err
+func As(err error) (*Error, bool) {
+	var appErr *Error
+	ok := errors.As(err, &appErr)
+	return appErr, ok
+}
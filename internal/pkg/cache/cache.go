@@ -0,0 +1,271 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"math"
+	"math/rand"
+	"time"
+
+	"ordent/internal/pkg/redigo"
+)
+
+// Store is the part of redigo.Redis the cache runs on
+type Store interface {
+	Get(key string) *redigo.Result
+	MGet(keys ...string) *redigo.Result
+	Setex(key string, expireTime int, value interface{}) error
+	SetexMulti(values map[string]interface{}, expireTime int) error
+	Del(keys ...string) error
+}
+
+type Options struct {
+	// TTL keep found values, NegativeTTL remember missing ones
+	TTL         time.Duration
+	NegativeTTL time.Duration
+	// Beta scale the early refresh, 1 is the usual value and 0 disables it
+	Beta float64
+	// LoadTimeout bound a shared load, 10 seconds when empty
+	LoadTimeout time.Duration
+}
+
+const defaultLoadTimeout = 10 * time.Second
+
+// Cache is a cache-aside over Redis. Concurrent misses of a key are loaded
+// once per process, missing values are cached for a short while and hot
+// entries are refreshed a little before they expire, so an expiring key does
+// not send every request to the database at once.
+type Cache struct {
+	store  Store
+	opts   Options
+	flight group
+}
+
+func New(store Store, opts Options) *Cache {
+	return &Cache{
+		store: store,
+		opts:  opts,
+	}
+}
+
+// entry is what is stored under a key, Delta is how long the value took to
+// load and Expiry when the entry expires, both in milliseconds
+type entry struct {
+	Value   json.RawMessage `json:"v,omitempty"`
+	Missing bool            `json:"m,omitempty"`
+	Delta   int64           `json:"d"`
+	Expiry  int64           `json:"e"`
+}
+
+// fresh tell whether the entry is served as is. Entries written by something
+// else than the cache are not, and the closer an entry is to its expiry the
+// likelier it is refreshed early, the slower it loads the sooner.
+func (c *Cache) fresh(e entry, now time.Time) bool {
+	if e.Value == nil && !e.Missing {
+		return false
+	}
+
+	if c.opts.Beta <= 0 {
+		return true
+	}
+
+	early := float64(e.Delta) * c.opts.Beta * -math.Log(1-rand.Float64())
+	return float64(now.UnixMilli())+early < float64(e.Expiry)
+}
+
+func (c *Cache) encode(value interface{}, found bool, delta time.Duration) ([]byte, int, error) {
+	ttl := c.opts.TTL
+	e := entry{Delta: delta.Milliseconds()}
+
+	if found {
+		raw, err := json.Marshal(value)
+		if err != nil {
+			return nil, 0, err
+		}
+		e.Value = raw
+	} else {
+		ttl = c.opts.NegativeTTL
+		e.Missing = true
+	}
+
+	seconds := int(math.Ceil(ttl.Seconds()))
+	e.Expiry = time.Now().Add(time.Duration(seconds) * time.Second).UnixMilli()
+
+	data, err := json.Marshal(e)
+	return data, seconds, err
+}
+
+// Fetch return the value cached under key or load it. load report a missing
+// value with found false, it is cached for NegativeTTL, or not at all when
+// NegativeTTL is 0. The cache is best effort, Redis errors only cost a load.
+//
+// The load is shared by every caller of the key, so it does not run on the
+// ctx of one of them but on its own bounded by LoadTimeout. A caller whose ctx
+// is done gets its error while the load goes on for the others.
+func Fetch[T any](ctx context.Context, c *Cache, key string, load func(ctx context.Context) (T, bool, error)) (T, bool, error) {
+	var value T
+
+	if data, ok := c.store.Get(key).Value.([]byte); ok {
+		e := entry{}
+		if err := json.Unmarshal(data, &e); err == nil && c.fresh(e, time.Now()) {
+			if e.Missing {
+				return value, false, nil
+			}
+
+			if err = json.Unmarshal(e.Value, &value); err == nil {
+				return value, true, nil
+			}
+		}
+	}
+
+	type result struct {
+		value T
+		found bool
+	}
+
+	loaded, err := c.flight.do(ctx, key, func() (interface{}, error) {
+		timeout := c.opts.LoadTimeout
+		if timeout <= 0 {
+			timeout = defaultLoadTimeout
+		}
+
+		loadCtx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+
+		start := time.Now()
+
+		value, found, err := load(loadCtx)
+		if err != nil {
+			return nil, err
+		}
+
+		// a load cut short may report found false, that is no missing value
+		if !found && loadCtx.Err() != nil {
+			return nil, loadCtx.Err()
+		}
+
+		c.set(key, value, found, time.Since(start))
+
+		return result{value: value, found: found}, nil
+	})
+	if err != nil {
+		return value, false, err
+	}
+
+	r := loaded.(result)
+	return r.value, r.found, nil
+}
+
+func (c *Cache) set(key string, value interface{}, found bool, delta time.Duration) {
+	if !found && c.opts.NegativeTTL <= 0 {
+		return
+	}
+
+	data, seconds, err := c.encode(value, found, delta)
+	if err != nil {
+		log.Printf("[cache] failed to encode %s. err: %v", key, err)
+		return
+	}
+
+	if err = c.store.Setex(key, seconds, data); err != nil {
+		log.Printf("[cache] failed to set %s. err: %v", key, err)
+	}
+}
+
+// FetchMany return the values of ids read in one MGET, the misses loaded in
+// one call to load and written back in one pipeline. ids load does not
+// return are missing, they are left out of the result and cached as such.
+func FetchMany[K comparable, T any](ctx context.Context, c *Cache, ids []K, keyOf func(K) string, load func(ctx context.Context, ids []K) (map[K]T, error)) (map[K]T, error) {
+	values := make(map[K]T, len(ids))
+	if len(ids) == 0 {
+		return values, nil
+	}
+
+	keys := make([]string, len(ids))
+	for i, id := range ids {
+		keys[i] = keyOf(id)
+	}
+
+	cached, _ := c.store.MGet(keys...).Value.([]interface{})
+
+	now := time.Now()
+	misses := make([]K, 0)
+	for i, id := range ids {
+		data, ok := []byte(nil), false
+		if i < len(cached) {
+			data, ok = cached[i].([]byte)
+		}
+
+		e := entry{}
+		if ok && json.Unmarshal(data, &e) == nil && c.fresh(e, now) {
+			if e.Missing {
+				continue
+			}
+
+			var value T
+			if json.Unmarshal(e.Value, &value) == nil {
+				values[id] = value
+				continue
+			}
+		}
+
+		misses = append(misses, id)
+	}
+
+	if len(misses) == 0 {
+		return values, nil
+	}
+
+	start := time.Now()
+	loaded, err := load(ctx, misses)
+	if err != nil {
+		return values, err
+	}
+	delta := time.Since(start)
+
+	// the ids a cancelled load left out are not known to be missing
+	cacheMissing := c.opts.NegativeTTL > 0 && ctx.Err() == nil
+
+	found, missing := make(map[string]interface{}), make(map[string]interface{})
+	for _, id := range misses {
+		value, ok := loaded[id]
+		if ok {
+			values[id] = value
+		} else if !cacheMissing {
+			continue
+		}
+
+		data, _, err := c.encode(value, ok, delta)
+		if err != nil {
+			log.Printf("[cache] failed to encode %s. err: %v", keyOf(id), err)
+			continue
+		}
+
+		if ok {
+			found[keyOf(id)] = data
+		} else {
+			missing[keyOf(id)] = data
+		}
+	}
+
+	c.setMany(found, c.opts.TTL)
+	c.setMany(missing, c.opts.NegativeTTL)
+
+	return values, nil
+}
+
+func (c *Cache) setMany(values map[string]interface{}, ttl time.Duration) {
+	if len(values) == 0 {
+		return
+	}
+
+	if err := c.store.SetexMulti(values, int(math.Ceil(ttl.Seconds()))); err != nil {
+		log.Printf("[cache] failed to set %d keys. err: %v", len(values), err)
+	}
+}
+
+// Delete drop the entries of keys, the next fetch loads them again
+func (c *Cache) Delete(keys ...string) error {
+	return c.store.Del(keys...)
+}
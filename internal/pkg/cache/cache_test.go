@@ -0,0 +1,459 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"ordent/internal/pkg/redigo"
+)
+
+// fakeStore is an in-memory Store remembering the ttl of every key
+type fakeStore struct {
+	mu   sync.Mutex
+	data map[string][]byte
+	ttl  map[string]int
+	gets int
+}
+
+func newFakeStore() *fakeStore {
+	return &fakeStore{
+		data: make(map[string][]byte),
+		ttl:  make(map[string]int),
+	}
+}
+
+func (f *fakeStore) Get(key string) *redigo.Result {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.gets++
+	if data, ok := f.data[key]; ok {
+		return &redigo.Result{Value: data}
+	}
+	return &redigo.Result{}
+}
+
+func (f *fakeStore) MGet(keys ...string) *redigo.Result {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	values := make([]interface{}, len(keys))
+	for i, key := range keys {
+		if data, ok := f.data[key]; ok {
+			values[i] = data
+		}
+	}
+	return &redigo.Result{Value: values}
+}
+
+func (f *fakeStore) Setex(key string, expireTime int, value interface{}) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.data[key] = value.([]byte)
+	f.ttl[key] = expireTime
+	return nil
+}
+
+func (f *fakeStore) SetexMulti(values map[string]interface{}, expireTime int) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for key, value := range values {
+		f.data[key] = value.([]byte)
+		f.ttl[key] = expireTime
+	}
+	return nil
+}
+
+func (f *fakeStore) Del(keys ...string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, key := range keys {
+		delete(f.data, key)
+		delete(f.ttl, key)
+	}
+	return nil
+}
+
+func (f *fakeStore) entry(key string) (entry, int, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	e := entry{}
+	data, ok := f.data[key]
+	if !ok {
+		return e, 0, false
+	}
+
+	if err := json.Unmarshal(data, &e); err != nil {
+		return e, 0, false
+	}
+	return e, f.ttl[key], true
+}
+
+func (f *fakeStore) getCount() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.gets
+}
+
+func TestFetch(t *testing.T) {
+	errLoad := errors.New("database down")
+
+	tests := []struct {
+		name      string
+		opts      Options
+		load      func(ctx context.Context) (string, bool, error)
+		wantValue string
+		wantFound bool
+		wantErr   bool
+		wantLoads int32
+		wantTTL   int
+		wantEntry bool
+	}{
+		{
+			name: "found value is cached for TTL",
+			opts: Options{TTL: time.Minute, NegativeTTL: 5 * time.Second},
+			load: func(ctx context.Context) (string, bool, error) {
+				return "shirt", true, nil
+			},
+			wantValue: "shirt",
+			wantFound: true,
+			wantLoads: 1,
+			wantTTL:   60,
+			wantEntry: true,
+		},
+		{
+			name: "missing value is cached for NegativeTTL",
+			opts: Options{TTL: time.Minute, NegativeTTL: 5 * time.Second},
+			load: func(ctx context.Context) (string, bool, error) {
+				return "", false, nil
+			},
+			wantLoads: 1,
+			wantTTL:   5,
+			wantEntry: true,
+		},
+		{
+			name: "missing value is not cached without NegativeTTL",
+			opts: Options{TTL: time.Minute},
+			load: func(ctx context.Context) (string, bool, error) {
+				return "", false, nil
+			},
+			wantLoads: 2,
+		},
+		{
+			name: "load error is not cached",
+			opts: Options{TTL: time.Minute, NegativeTTL: 5 * time.Second},
+			load: func(ctx context.Context) (string, bool, error) {
+				return "", false, errLoad
+			},
+			wantErr:   true,
+			wantLoads: 2,
+		},
+		{
+			name: "load cut short by its timeout is not negative cached",
+			opts: Options{TTL: time.Minute, NegativeTTL: 5 * time.Second, LoadTimeout: 10 * time.Millisecond},
+			load: func(ctx context.Context) (string, bool, error) {
+				<-ctx.Done()
+				return "", false, nil
+			},
+			wantErr:   true,
+			wantLoads: 2,
+		},
+		{
+			name: "panic in load is returned as error",
+			opts: Options{TTL: time.Minute, NegativeTTL: 5 * time.Second},
+			load: func(ctx context.Context) (string, bool, error) {
+				panic("nil map")
+			},
+			wantErr:   true,
+			wantLoads: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newFakeStore()
+			c := New(store, tt.opts)
+
+			var loads int32
+			load := func(ctx context.Context) (string, bool, error) {
+				atomic.AddInt32(&loads, 1)
+				return tt.load(ctx)
+			}
+
+			for i := 0; i < 2; i++ {
+				value, found, err := Fetch(context.Background(), c, "product:1", load)
+				if (err != nil) != tt.wantErr {
+					t.Fatalf("Fetch() err = %v, wantErr %v", err, tt.wantErr)
+				}
+				if value != tt.wantValue || found != tt.wantFound {
+					t.Fatalf("Fetch() = %q, %v, want %q, %v", value, found, tt.wantValue, tt.wantFound)
+				}
+			}
+
+			if loads != tt.wantLoads {
+				t.Errorf("loads = %d, want %d", loads, tt.wantLoads)
+			}
+
+			e, ttl, ok := store.entry("product:1")
+			if ok != tt.wantEntry {
+				t.Fatalf("cached = %v, want %v", ok, tt.wantEntry)
+			}
+			if ok && (ttl != tt.wantTTL || e.Missing == tt.wantFound) {
+				t.Errorf("cached ttl = %d missing = %v, want ttl %d missing %v", ttl, e.Missing, tt.wantTTL, !tt.wantFound)
+			}
+		})
+	}
+}
+
+func TestFetchSingleFlight(t *testing.T) {
+	const callers = 10
+
+	store := newFakeStore()
+	c := New(store, Options{TTL: time.Minute})
+
+	var loads int32
+	release := make(chan struct{})
+	load := func(ctx context.Context) (string, bool, error) {
+		atomic.AddInt32(&loads, 1)
+		<-release
+		return "shirt", true, nil
+	}
+
+	var wg sync.WaitGroup
+	values := make([]string, callers)
+	errs := make([]error, callers)
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			values[i], _, errs[i] = Fetch(context.Background(), c, "product:1", load)
+		}(i)
+	}
+
+	// every caller missed the cache, give them the time to join the load
+	for store.getCount() < callers {
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if loads != 1 {
+		t.Errorf("loads = %d, want 1", loads)
+	}
+
+	for i := 0; i < callers; i++ {
+		if errs[i] != nil || values[i] != "shirt" {
+			t.Errorf("caller %d got %q, %v", i, values[i], errs[i])
+		}
+	}
+}
+
+func TestFetchCallerCancelled(t *testing.T) {
+	store := newFakeStore()
+	c := New(store, Options{TTL: time.Minute})
+
+	release := make(chan struct{})
+	load := func(ctx context.Context) (string, bool, error) {
+		<-release
+		return "shirt", true, ctx.Err()
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, _, err := Fetch(ctx, c, "product:1", load); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Fetch() err = %v, want %v", err, context.Canceled)
+	}
+
+	// the load goes on without the caller and is still cached
+	close(release)
+	deadline := time.Now().Add(time.Second)
+	for {
+		if _, _, ok := store.entry("product:1"); ok {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("the detached load was not cached")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestFetchEarlyRefresh(t *testing.T) {
+	now := time.Now()
+
+	tests := []struct {
+		name      string
+		beta      float64
+		entry     entry
+		wantLoads int32
+	}{
+		{
+			name:      "entry far from its expiry is served",
+			beta:      1,
+			entry:     entry{Value: json.RawMessage(`"cached"`), Delta: 1, Expiry: now.Add(time.Hour).UnixMilli()},
+			wantLoads: 0,
+		},
+		{
+			name:      "entry at its expiry is refreshed early",
+			beta:      1,
+			entry:     entry{Value: json.RawMessage(`"cached"`), Delta: 1000, Expiry: now.UnixMilli()},
+			wantLoads: 1,
+		},
+		{
+			name:      "entry at its expiry is served without beta",
+			beta:      0,
+			entry:     entry{Value: json.RawMessage(`"cached"`), Delta: 1000, Expiry: now.UnixMilli()},
+			wantLoads: 0,
+		},
+		{
+			name:      "value not written by the cache is loaded",
+			beta:      1,
+			entry:     entry{Expiry: now.Add(time.Hour).UnixMilli()},
+			wantLoads: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newFakeStore()
+			c := New(store, Options{TTL: time.Minute, Beta: tt.beta})
+
+			data, _ := json.Marshal(tt.entry)
+			store.Setex("product:1", 60, data)
+
+			var loads int32
+			value, _, err := Fetch(context.Background(), c, "product:1", func(ctx context.Context) (string, bool, error) {
+				atomic.AddInt32(&loads, 1)
+				return "loaded", true, nil
+			})
+			if err != nil {
+				t.Fatalf("Fetch() err = %v", err)
+			}
+
+			if loads != tt.wantLoads {
+				t.Errorf("loads = %d, want %d", loads, tt.wantLoads)
+			}
+
+			want := "cached"
+			if tt.wantLoads > 0 {
+				want = "loaded"
+			}
+			if value != want {
+				t.Errorf("Fetch() = %q, want %q", value, want)
+			}
+		})
+	}
+}
+
+func TestFetchMany(t *testing.T) {
+	keyOf := func(id int64) string {
+		return fmt.Sprintf("product:%d", id)
+	}
+
+	tests := []struct {
+		name        string
+		cached      map[int64]string
+		ids         []int64
+		loaded      map[int64]string
+		cancelled   bool
+		want        map[int64]string
+		wantMisses  []int64
+		wantMissing []int64
+	}{
+		{
+			name:        "hits are read, misses loaded and unknown ids negative cached",
+			cached:      map[int64]string{1: "shirt", 2: "shoe"},
+			ids:         []int64{1, 2, 3, 4},
+			loaded:      map[int64]string{3: "hat"},
+			want:        map[int64]string{1: "shirt", 2: "shoe", 3: "hat"},
+			wantMisses:  []int64{3, 4},
+			wantMissing: []int64{4},
+		},
+		{
+			name:       "every id cached does not load",
+			cached:     map[int64]string{1: "shirt", 2: "shoe"},
+			ids:        []int64{1, 2},
+			want:       map[int64]string{1: "shirt", 2: "shoe"},
+			wantMisses: nil,
+		},
+		{
+			name:       "ids left out by a cancelled load are not negative cached",
+			cached:     map[int64]string{1: "shirt"},
+			ids:        []int64{1, 2},
+			loaded:     map[int64]string{},
+			cancelled:  true,
+			want:       map[int64]string{1: "shirt"},
+			wantMisses: []int64{2},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newFakeStore()
+			c := New(store, Options{TTL: time.Minute, NegativeTTL: 5 * time.Second})
+
+			for id, value := range tt.cached {
+				data, seconds, err := c.encode(value, true, time.Millisecond)
+				if err != nil {
+					t.Fatal(err)
+				}
+				store.Setex(keyOf(id), seconds, data)
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			var misses []int64
+			got, err := FetchMany(ctx, c, tt.ids, keyOf, func(ctx context.Context, ids []int64) (map[int64]string, error) {
+				misses = append(misses, ids...)
+				if tt.cancelled {
+					cancel()
+				}
+				return tt.loaded, nil
+			})
+			if err != nil {
+				t.Fatalf("FetchMany() err = %v", err)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("FetchMany() = %v, want %v", got, tt.want)
+			}
+
+			sort.Slice(misses, func(i, j int) bool { return misses[i] < misses[j] })
+			if !reflect.DeepEqual(misses, tt.wantMisses) {
+				t.Errorf("loaded ids = %v, want %v", misses, tt.wantMisses)
+			}
+
+			for _, id := range tt.ids {
+				if _, ok := tt.want[id]; ok {
+					continue
+				}
+
+				e, ttl, ok := store.entry(keyOf(id))
+				wantMissing := false
+				for _, missing := range tt.wantMissing {
+					wantMissing = wantMissing || missing == id
+				}
+
+				if ok != wantMissing || (ok && (!e.Missing || ttl != 5)) {
+					t.Errorf("id %d cached = %v missing = %v ttl = %d, want missing %v for 5s", id, ok, e.Missing, ttl, wantMissing)
+				}
+			}
+		})
+	}
+}
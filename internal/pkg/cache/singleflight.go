@@ -0,0 +1,62 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// call is a load in flight, the callers asking for the same key wait on it
+type call struct {
+	done chan struct{}
+	val  interface{}
+	err  error
+}
+
+// group run one load per key at a time inside the process and hand its
+// result to every caller that asked meanwhile
+type group struct {
+	mu    sync.Mutex
+	calls map[string]*call
+}
+
+// do run fn once for all concurrent callers of key. fn runs on its own
+// goroutine, a caller whose ctx is done stops waiting while fn goes on for
+// the others. A panic in fn is returned as error to every caller.
+func (g *group) do(ctx context.Context, key string, fn func() (interface{}, error)) (interface{}, error) {
+	g.mu.Lock()
+	if g.calls == nil {
+		g.calls = make(map[string]*call)
+	}
+
+	c, ok := g.calls[key]
+	if !ok {
+		c = &call{done: make(chan struct{})}
+		g.calls[key] = c
+
+		go g.run(key, c, fn)
+	}
+	g.mu.Unlock()
+
+	select {
+	case <-c.done:
+		return c.val, c.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (g *group) run(key string, c *call, fn func() (interface{}, error)) {
+	defer func() {
+		if r := recover(); r != nil {
+			c.val, c.err = nil, fmt.Errorf("load of %s panicked: %v", key, r)
+		}
+
+		g.mu.Lock()
+		delete(g.calls, key)
+		g.mu.Unlock()
+		close(c.done)
+	}()
+
+	c.val, c.err = fn()
+}
@@ -0,0 +1,166 @@
+package cursor
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"ordent/internal/config"
+	enPagination "ordent/internal/entity/pagination"
+	"ordent/internal/pkg/apperror"
+)
+
+var (
+	ErrInvalidCursor  = apperror.New(apperror.KindValidation, "invalid_cursor", "invalid cursor")
+	ErrCursorMismatch = apperror.New(apperror.KindValidation, "cursor_mismatch", "cursor belongs to another listing or sort")
+)
+
+type Signer struct {
+	secret []byte
+	scope  string
+}
+
+// token is the signed payload, the cursor and the scope of the listing which
+// created it
+type token struct {
+	enPagination.Cursor
+	Scope string `json:"s,omitempty"`
+}
+
+// NewSigner create cursor signer using the configured secret
+func NewSigner(cfg config.Pagination) *Signer {
+	return &Signer{
+		secret: []byte(cfg.Secret),
+	}
+}
+
+// Scope return a signer for one listing, e.g. Scope("products", "rating").
+// Its cursors carry the scope and are rejected by signers of another scope.
+func (s *Signer) Scope(parts ...string) *Signer {
+	return &Signer{
+		secret: s.secret,
+		scope:  strings.Join(parts, ":"),
+	}
+}
+
+// Encode cursor into an opaque token signed with HMAC-SHA256
+func (s *Signer) Encode(c enPagination.Cursor) string {
+	payload, _ := json.Marshal(token{Cursor: c, Scope: s.scope})
+	body := base64.RawURLEncoding.EncodeToString(payload)
+
+	return body + "." + base64.RawURLEncoding.EncodeToString(s.sign(body))
+}
+
+// Decode verify the token signature and scope and return the cursor.
+// Empty token return the zero cursor which points at the first page
+func (s *Signer) Decode(encoded string) (enPagination.Cursor, error) {
+	c := enPagination.Cursor{}
+	if encoded == "" {
+		return c, nil
+	}
+
+	body, signature, found := strings.Cut(encoded, ".")
+	if !found {
+		return c, ErrInvalidCursor
+	}
+
+	sig, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil || !hmac.Equal(sig, s.sign(body)) {
+		return c, ErrInvalidCursor
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(body)
+	if err != nil {
+		return c, ErrInvalidCursor
+	}
+
+	t := token{}
+	if err := json.Unmarshal(payload, &t); err != nil || t.IsZero() {
+		return c, ErrInvalidCursor
+	}
+
+	if t.Scope != s.scope {
+		return c, ErrCursorMismatch
+	}
+
+	return t.Cursor, nil
+}
+
+func (s *Signer) sign(body string) []byte {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(body))
+	return mac.Sum(nil)
+}
+
+// Keyset build the where condition and ordering for a listing sorted by
+// keyColumn then idColumn descending. Cursor values are bound to the
+// placeholders starting at argIndex, key first then id.
+func Keyset(c enPagination.Cursor, keyColumn, idColumn string, argIndex int) (string, string) {
+	if c.IsZero() {
+		return "true", fmt.Sprintf("%s desc, %s desc", keyColumn, idColumn)
+	}
+
+	if c.Backward {
+		return fmt.Sprintf("(%s, %s) > ($%d, $%d)", keyColumn, idColumn, argIndex, argIndex+1),
+			fmt.Sprintf("%s asc, %s asc", keyColumn, idColumn)
+	}
+
+	return fmt.Sprintf("(%s, %s) < ($%d, $%d)", keyColumn, idColumn, argIndex, argIndex+1),
+		fmt.Sprintf("%s desc, %s desc", keyColumn, idColumn)
+}
+
+// Page trim the rows fetched with limit+1 by a Keyset query, restore the
+// display order and build the next and previous cursors.
+func Page[T any](s *Signer, c enPagination.Cursor, limit int, rows []T, keyOf func(T) enPagination.Cursor) ([]T, enPagination.Paging) {
+	paging := enPagination.Paging{}
+
+	hasMore := len(rows) > limit
+	if hasMore {
+		rows = rows[:limit]
+	}
+
+	if c.Backward {
+		for i, j := 0, len(rows)-1; i < j; i, j = i+1, j-1 {
+			rows[i], rows[j] = rows[j], rows[i]
+		}
+	}
+
+	if len(rows) == 0 {
+		return rows, paging
+	}
+
+	first := keyOf(rows[0])
+	first.Backward = true
+	last := keyOf(rows[len(rows)-1])
+
+	if c.Backward {
+		// walking back, there is always a page after this one
+		paging.NextCursor = s.Encode(last)
+		if hasMore {
+			paging.PrevCursor = s.Encode(first)
+		}
+	} else {
+		if hasMore {
+			paging.NextCursor = s.Encode(last)
+		}
+		if !c.IsZero() {
+			paging.PrevCursor = s.Encode(first)
+		}
+	}
+
+	return rows, paging
+}
+
+// Limit clamp requested page size into the allowed range
+func Limit(limit int) int {
+	if limit <= 0 {
+		return enPagination.DefaultLimit
+	}
+	if limit > enPagination.MaxLimit {
+		return enPagination.MaxLimit
+	}
+	return limit
+}
@@ -0,0 +1,102 @@
+package etag
+
+import (
+	"crypto/sha1"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"ordent/internal/pkg/apperror"
+)
+
+const (
+	HeaderETag            = "ETag"
+	HeaderIfMatch         = "If-Match"
+	HeaderIfNoneMatch     = "If-None-Match"
+	HeaderLastModified    = "Last-Modified"
+	HeaderIfModifiedSince = "If-Modified-Since"
+)
+
+var ErrInvalidETag = apperror.New(apperror.KindValidation, "invalid_etag", "invalid etag")
+
+// Version format a resource version as strong ETag, e.g. "v3"
+func Version(version int64) string {
+	return fmt.Sprintf(`"v%d"`, version)
+}
+
+// Content format a strong ETag from the representation sent, e.g. "9f3c0a1b2d4e6f70"
+func Content(body []byte) string {
+	sum := sha1.Sum(body)
+	return fmt.Sprintf(`"%x"`, sum[:8])
+}
+
+// VersionContent format a strong ETag from both the resource version and the
+// representation sent, e.g. "v3-9f3c0a1b2d4e6f70". The representation also
+// changes with data which does not bump the version, like stock, while
+// If-Match only cares about the version.
+func VersionContent(version int64, body []byte) string {
+	sum := sha1.Sum(body)
+	return fmt.Sprintf(`"v%d-%x"`, version, sum[:8])
+}
+
+// ParseVersion read the version from an If-Match header built by Version or
+// VersionContent. Empty header or * return 0, meaning any version matches.
+func ParseVersion(header string) (int64, error) {
+	header = strings.TrimSpace(header)
+	if header == "" || header == "*" {
+		return 0, nil
+	}
+
+	if strings.HasPrefix(header, "W/") {
+		// weak tags never match for If-Match
+		return 0, ErrInvalidETag
+	}
+
+	tag := strings.Trim(header, `"`)
+	if !strings.HasPrefix(tag, "v") {
+		return 0, ErrInvalidETag
+	}
+
+	tag, _, _ = strings.Cut(strings.TrimPrefix(tag, "v"), "-")
+
+	version, err := strconv.ParseInt(tag, 10, 64)
+	if err != nil || version <= 0 {
+		return 0, ErrInvalidETag
+	}
+
+	return version, nil
+}
+
+// NotModified tell whether the client already has the representation tagged
+// tag and modified at modified, so a 304 can be sent instead. If-None-Match
+// wins over If-Modified-Since, a zero modified ignores the latter.
+func NotModified(r *http.Request, tag string, modified time.Time) bool {
+	if header := r.Header.Get(HeaderIfNoneMatch); header != "" {
+		return matchAny(header, tag)
+	}
+
+	since, err := http.ParseTime(r.Header.Get(HeaderIfModifiedSince))
+	if err != nil || modified.IsZero() {
+		return false
+	}
+
+	// http dates only carry seconds
+	return !modified.Truncate(time.Second).After(since)
+}
+
+// matchAny compare tag with a list of tags using weak comparison, as
+// If-None-Match does
+func matchAny(header, tag string) bool {
+	tag = strings.TrimPrefix(tag, "W/")
+
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == tag {
+			return true
+		}
+	}
+
+	return false
+}
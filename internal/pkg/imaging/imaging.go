@@ -0,0 +1,101 @@
+package imaging
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+
+	"ordent/internal/pkg/apperror"
+)
+
+var ErrUnsupportedType = apperror.New(apperror.KindValidation, "unsupported_image_type", "unsupported image type")
+
+// Extensions of the content types accepted for upload
+var Extensions = map[string]string{
+	"image/jpeg": "jpg",
+	"image/png":  "png",
+	"image/gif":  "gif",
+}
+
+// DetectContentType sniff the content type from the data itself,
+// ignoring whatever the client claims
+func DetectContentType(data []byte) (string, error) {
+	contentType := http.DetectContentType(data)
+	if _, ok := Extensions[contentType]; !ok {
+		return contentType, ErrUnsupportedType
+	}
+
+	return contentType, nil
+}
+
+// Thumbnail decode the image and scale it down to fit width, keeping the
+// aspect ratio. Result is encoded as JPEG, or PNG for sources that may carry
+// transparency.
+func Thumbnail(data []byte, width int) ([]byte, string, error) {
+	src, format, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", err
+	}
+
+	bounds := src.Bounds()
+	if bounds.Dx() > width {
+		height := bounds.Dy() * width / bounds.Dx()
+		if height == 0 {
+			height = 1
+		}
+		src = scale(src, width, height)
+	}
+
+	buf := &bytes.Buffer{}
+	if format == "jpeg" {
+		err = jpeg.Encode(buf, src, &jpeg.Options{Quality: 85})
+		return buf.Bytes(), "image/jpeg", err
+	}
+
+	err = png.Encode(buf, src)
+	return buf.Bytes(), "image/png", err
+}
+
+// scale resize using box filter, every target pixel average the source pixels it covers
+func scale(src image.Image, width, height int) image.Image {
+	bounds := src.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+
+	for y := 0; y < height; y++ {
+		y0 := bounds.Min.Y + y*bounds.Dy()/height
+		y1 := bounds.Min.Y + (y+1)*bounds.Dy()/height
+		if y1 == y0 {
+			y1 = y0 + 1
+		}
+
+		for x := 0; x < width; x++ {
+			x0 := bounds.Min.X + x*bounds.Dx()/width
+			x1 := bounds.Min.X + (x+1)*bounds.Dx()/width
+			if x1 == x0 {
+				x1 = x0 + 1
+			}
+
+			var r, g, b, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					cr, cg, cb, ca := src.At(sx, sy).RGBA()
+					r, g, b, a = r+uint64(cr), g+uint64(cg), b+uint64(cb), a+uint64(ca)
+					n++
+				}
+			}
+
+			dst.SetRGBA64(x, y, color.RGBA64{
+				R: uint16(r / n),
+				G: uint16(g / n),
+				B: uint16(b / n),
+				A: uint16(a / n),
+			})
+		}
+	}
+
+	return dst
+}
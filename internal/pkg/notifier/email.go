@@ -0,0 +1,76 @@
+package notifier
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+
+	"ordent/internal/config"
+)
+
+// Email send plain text mails through SMTP
+type Email struct {
+	addr string
+	auth smtp.Auth
+	from string
+	to   []string
+}
+
+func NewEmail(cfg config.EmailNotifier) (*Email, error) {
+	if cfg.Host == "" || cfg.From == "" {
+		return nil, errors.New("email notifier requires host and from")
+	}
+
+	port := cfg.Port
+	if port == 0 {
+		port = 25
+	}
+
+	var auth smtp.Auth
+	if cfg.Username != "" {
+		auth = smtp.PlainAuth("", cfg.Username, cfg.Password, cfg.Host)
+	}
+
+	return &Email{
+		addr: net.JoinHostPort(cfg.Host, strconv.Itoa(port)),
+		auth: auth,
+		from: cfg.From,
+		to:   cfg.To,
+	}, nil
+}
+
+func (e *Email) Notify(ctx context.Context, msg Message) error {
+	to := msg.To
+	if len(to) == 0 {
+		to = e.to
+	}
+
+	if len(to) == 0 {
+		return errors.New("email notifier has no recipient")
+	}
+
+	var body strings.Builder
+	fmt.Fprintf(&body, "From: %s\r\n", e.from)
+	fmt.Fprintf(&body, "To: %s\r\n", strings.Join(to, ", "))
+	fmt.Fprintf(&body, "Subject: %s\r\n", msg.Subject)
+	body.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	body.WriteString(msg.Body)
+	body.WriteString("\r\n")
+
+	// net/smtp has no context support, run it aside so the caller can give up
+	done := make(chan error, 1)
+	go func() {
+		done <- smtp.SendMail(e.addr, e.auth, e.from, to, []byte(body.String()))
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
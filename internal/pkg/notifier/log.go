@@ -0,0 +1,18 @@
+package notifier
+
+import (
+	"context"
+	"log"
+)
+
+// Log only write messages to the application log, useful in development
+type Log struct{}
+
+func NewLog() *Log {
+	return &Log{}
+}
+
+func (l *Log) Notify(ctx context.Context, msg Message) error {
+	log.Printf("[notify] %s to %v: %s. %s", msg.Event, msg.To, msg.Subject, msg.Body)
+	return nil
+}
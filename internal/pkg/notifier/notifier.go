@@ -0,0 +1,37 @@
+package notifier
+
+import (
+	"context"
+	"errors"
+
+	"ordent/internal/config"
+)
+
+// Message is one notification. To is optional, each notifier falls back to
+// its configured recipients.
+type Message struct {
+	Event   string      `json:"event"`
+	To      []string    `json:"to,omitempty"`
+	Subject string      `json:"subject"`
+	Body    string      `json:"body"`
+	Data    interface{} `json:"data,omitempty"`
+}
+
+// Notifier deliver messages to admins or customers, e.g. low stock alerts
+type Notifier interface {
+	Notify(ctx context.Context, msg Message) error
+}
+
+// New create the notifier selected by config driver
+func New(cfg config.Notifier) (Notifier, error) {
+	switch cfg.Driver {
+	case "", "log":
+		return NewLog(), nil
+	case "webhook":
+		return NewWebhook(cfg.Webhook)
+	case "email":
+		return NewEmail(cfg.Email)
+	}
+
+	return nil, errors.New("unknown notifier driver " + cfg.Driver)
+}
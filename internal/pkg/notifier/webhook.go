@@ -0,0 +1,74 @@
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"ordent/internal/config"
+)
+
+const HeaderSignature = "X-Ordent-Signature"
+
+// Webhook post messages as json. With a secret the body is signed with
+// HMAC-SHA256 in the X-Ordent-Signature header.
+type Webhook struct {
+	url    string
+	secret []byte
+	client *http.Client
+}
+
+func NewWebhook(cfg config.WebhookNotifier) (*Webhook, error) {
+	if cfg.URL == "" {
+		return nil, errors.New("webhook notifier requires url")
+	}
+
+	timeout := cfg.Timeout
+	if timeout == 0 {
+		timeout = 10 * time.Second
+	}
+
+	return &Webhook{
+		url:    cfg.URL,
+		secret: []byte(cfg.Secret),
+		client: &http.Client{Timeout: timeout},
+	}, nil
+}
+
+func (w *Webhook) Notify(ctx context.Context, msg Message) error {
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	if len(w.secret) > 0 {
+		mac := hmac.New(sha256.New, w.secret)
+		mac.Write(body)
+		req.Header.Set(HeaderSignature, "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook responded %s", resp.Status)
+	}
+
+	return nil
+}
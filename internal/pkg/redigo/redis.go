@@ -18,6 +18,12 @@ type Result struct {
 	Error error
 }
 
+// Command is one command of a Multi block
+type Command struct {
+	Name string
+	Args []interface{}
+}
+
 // New Redis module
 func New(redisCfg config.Redis) *Redis {
 	// Set default 10 seconds timeout
@@ -62,6 +68,55 @@ func (rgo *Redis) Setex(key string, expireTime int, value interface{}) error {
 	return rgo.cmd("SETEX", args...).Error
 }
 
+// SetexMulti set every key of values with the same expiration, pipelined in
+// one round trip
+func (rgo *Redis) SetexMulti(values map[string]interface{}, expireTime int) error {
+	conn := rgo.pool.Get()
+	defer conn.Close()
+
+	for key, value := range values {
+		if err := conn.Send("SETEX", key, expireTime, value); err != nil {
+			return err
+		}
+	}
+
+	if err := conn.Flush(); err != nil {
+		return err
+	}
+
+	for range values {
+		if _, err := conn.Receive(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Multi run the commands atomically in one MULTI/EXEC round trip, Value
+// holds the reply of each command
+func (rgo *Redis) Multi(commands ...Command) *Result {
+	result := &Result{}
+	conn := rgo.pool.Get()
+	defer conn.Close()
+
+	if err := conn.Send("MULTI"); err != nil {
+		result.Error = err
+		return result
+	}
+
+	for _, command := range commands {
+		if err := conn.Send(command.Name, command.Args...); err != nil {
+			result.Error = err
+			return result
+		}
+	}
+
+	result.Value, result.Error = conn.Do("EXEC")
+
+	return result
+}
+
 // Del redis command
 func (rgo *Redis) Del(keys ...string) error {
 	args := make([]interface{}, len(keys))
@@ -162,6 +217,44 @@ func (rgo *Redis) ZRangeByScore(key, min, max string, limit int) *Result {
 	return rgo.cmd("ZRANGEBYSCORE", args...)
 }
 
+// ZAdd redis command, scores map each member to its score
+func (rgo *Redis) ZAdd(key string, scores map[string]int64) error {
+	args := []interface{}{key}
+
+	for member, score := range scores {
+		args = append(args, score, member)
+	}
+
+	return rgo.cmd("ZADD", args...).Error
+}
+
+// ZIncrBy redis command
+func (rgo *Redis) ZIncrBy(key string, increment int64, member string) error {
+	args := []interface{}{key, increment, member}
+	return rgo.cmd("ZINCRBY", args...).Error
+}
+
+// ZRevRange redis command, with scores the members and scores alternate
+func (rgo *Redis) ZRevRange(key string, start int, end int, withScores bool) *Result {
+	args := []interface{}{key, start, end}
+	if withScores {
+		args = append(args, "WITHSCORES")
+	}
+	return rgo.cmd("ZREVRANGE", args...)
+}
+
+// ZUnionStore redis command, aggregate is SUM when empty
+func (rgo *Redis) ZUnionStore(destination string, keys []string, aggregate string) error {
+	args := []interface{}{destination, len(keys)}
+	for _, v := range keys {
+		args = append(args, v)
+	}
+	if aggregate != "" {
+		args = append(args, "AGGREGATE", aggregate)
+	}
+	return rgo.cmd("ZUNIONSTORE", args...).Error
+}
+
 // TTL get expiry time
 func (rgo *Redis) TTL(key string) *Result {
 	args := []interface{}{key}
@@ -239,6 +332,24 @@ func (rgo *Redis) RPush(key string, value interface{}) error {
 	return rgo.cmd("RPUSH", args...).Error
 }
 
+// LRem Removes count occurrences of value from the list stored at key, 0 removes all
+func (rgo *Redis) LRem(key string, count int, value interface{}) error {
+	args := []interface{}{key, count, value}
+	return rgo.cmd("LREM", args...).Error
+}
+
+// LTrim Trim the list stored at key to the elements from start to stop
+func (rgo *Redis) LTrim(key string, start int, stop int) error {
+	args := []interface{}{key, start, stop}
+	return rgo.cmd("LTRIM", args...).Error
+}
+
+// LRange return the elements from start to stop of the list stored at key
+func (rgo *Redis) LRange(key string, start int, stop int) *Result {
+	args := []interface{}{key, start, stop}
+	return rgo.cmd("LRANGE", args...)
+}
+
 // LPop Removes and returns the first element of the list stored at key
 func (rgo *Redis) LPop(key string) *Result {
 	args := []interface{}{key}
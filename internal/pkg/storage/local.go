@@ -0,0 +1,73 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"ordent/internal/config"
+)
+
+// Local store blobs on the filesystem, served by the http server under BaseURL
+type Local struct {
+	directory string
+	baseURL   string
+}
+
+func NewLocal(cfg config.LocalStorage) (*Local, error) {
+	if err := os.MkdirAll(cfg.Directory, 0o755); err != nil {
+		return nil, err
+	}
+
+	return &Local{
+		directory: cfg.Directory,
+		baseURL:   strings.TrimSuffix(cfg.BaseURL, "/"),
+	}, nil
+}
+
+func (l *Local) Put(ctx context.Context, key string, data []byte, contentType string) error {
+	path, err := l.path(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	// write to a temporary file first so readers never see a partial blob
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, path)
+}
+
+func (l *Local) Delete(ctx context.Context, key string) error {
+	path, err := l.path(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	return nil
+}
+
+func (l *Local) URL(key string) string {
+	return l.baseURL + "/" + key
+}
+
+func (l *Local) path(key string) (string, error) {
+	clean := filepath.Clean("/" + key)
+	if clean == "/" {
+		return "", errors.New("invalid storage key")
+	}
+
+	return filepath.Join(l.directory, clean), nil
+}
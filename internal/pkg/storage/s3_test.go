@@ -0,0 +1,183 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"ordent/internal/config"
+)
+
+// fakeS3 is an in-memory S3 compatible server with a single bucket, enough
+// for the path style PUT and DELETE requests of the S3 storage
+type fakeS3 struct {
+	mu           sync.Mutex
+	bucket       string
+	objects      map[string][]byte
+	contentTypes map[string]string
+}
+
+func newFakeS3(bucket string) *fakeS3 {
+	return &fakeS3{
+		bucket:       bucket,
+		objects:      make(map[string][]byte),
+		contentTypes: make(map[string]string),
+	}
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, "AWS4-HMAC-SHA256 Credential=minio/") || r.Header.Get("X-Amz-Date") == "" {
+		http.Error(w, "<Error><Code>AccessDenied</Code></Error>", http.StatusForbidden)
+		return
+	}
+
+	bucket, key, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	if bucket != f.bucket {
+		http.Error(w, "<Error><Code>NoSuchBucket</Code></Error>", http.StatusNotFound)
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	switch r.Method {
+	case http.MethodPut:
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if r.Header.Get("X-Amz-Content-Sha256") != sha256Hex(body) {
+			http.Error(w, "<Error><Code>XAmzContentSHA256Mismatch</Code></Error>", http.StatusBadRequest)
+			return
+		}
+
+		f.objects[key] = body
+		f.contentTypes[key] = r.Header.Get("Content-Type")
+		w.WriteHeader(http.StatusOK)
+	case http.MethodDelete:
+		delete(f.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func newTestS3(t *testing.T, fake *fakeS3, bucket string) *S3 {
+	t.Helper()
+
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	s3 := NewS3(config.S3Storage{
+		Endpoint:  server.URL + "/",
+		Bucket:    bucket,
+		AccessKey: "minio",
+		SecretKey: "minio123",
+	})
+	s3.now = func() time.Time { return time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC) }
+
+	return s3
+}
+
+func TestS3PutAndDelete(t *testing.T) {
+	fake := newFakeS3("images")
+	s3 := newTestS3(t, fake, "images")
+	ctx := context.Background()
+
+	key := "products/7/1c2d3e.jpg"
+	if err := s3.Put(ctx, key, []byte("jpeg data"), "image/jpeg"); err != nil {
+		t.Fatalf("Put() err: %v", err)
+	}
+
+	if got := string(fake.objects[key]); got != "jpeg data" {
+		t.Errorf("stored object = %q, want %q", got, "jpeg data")
+	}
+
+	if got := fake.contentTypes[key]; got != "image/jpeg" {
+		t.Errorf("stored content type = %q, want image/jpeg", got)
+	}
+
+	if err := s3.Delete(ctx, key); err != nil {
+		t.Fatalf("Delete() err: %v", err)
+	}
+
+	if _, ok := fake.objects[key]; ok {
+		t.Errorf("object %s still stored after Delete()", key)
+	}
+}
+
+func TestS3KeyNaming(t *testing.T) {
+	fake := newFakeS3("images")
+	s3 := newTestS3(t, fake, "images")
+
+	// a leading slash does not create an empty path segment
+	if err := s3.Put(context.Background(), "/products/7/a.png", []byte("png"), "image/png"); err != nil {
+		t.Fatalf("Put() err: %v", err)
+	}
+
+	if _, ok := fake.objects["products/7/a.png"]; !ok {
+		t.Errorf("object stored under %v, want products/7/a.png", fake.objects)
+	}
+
+	want := strings.TrimSuffix(s3.endpoint, "/") + "/images/products/7/a.png"
+	if got := s3.URL("products/7/a.png"); got != want {
+		t.Errorf("URL() = %q, want %q", got, want)
+	}
+
+	withBaseURL := NewS3(config.S3Storage{Endpoint: "http://minio:9000", Bucket: "images", BaseURL: "https://cdn.example.com/"})
+	if got := withBaseURL.URL("products/7/a.png"); got != "https://cdn.example.com/products/7/a.png" {
+		t.Errorf("URL() with base url = %q", got)
+	}
+}
+
+func TestS3Errors(t *testing.T) {
+	fake := newFakeS3("images")
+	s3 := newTestS3(t, fake, "missing")
+	ctx := context.Background()
+
+	err := s3.Put(ctx, "products/7/a.png", []byte("png"), "image/png")
+	if err == nil || !strings.Contains(err.Error(), "status 404") || !strings.Contains(err.Error(), "NoSuchBucket") {
+		t.Errorf("Put() err = %v, want NoSuchBucket with status 404", err)
+	}
+
+	if err = s3.Delete(ctx, "products/7/a.png"); err == nil {
+		t.Error("Delete() want error")
+	}
+
+	if len(fake.objects) != 0 {
+		t.Errorf("objects stored after failed requests: %v", fake.objects)
+	}
+}
+
+func TestS3Signature(t *testing.T) {
+	s3 := NewS3(config.S3Storage{Endpoint: "http://minio:9000", Bucket: "images", AccessKey: "minio", SecretKey: "minio123"})
+	s3.now = func() time.Time { return time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC) }
+
+	sign := func() string {
+		req, _ := http.NewRequest(http.MethodPut, s3.objectURL("products/7/a.png"), nil)
+		s3.sign(req, []byte("png"))
+		return req.Header.Get("Authorization")
+	}
+
+	auth := sign()
+	wantPrefix := "AWS4-HMAC-SHA256 Credential=minio/20230102/us-east-1/s3/aws4_request, SignedHeaders=host;x-amz-content-sha256;x-amz-date, Signature="
+	if !strings.HasPrefix(auth, wantPrefix) {
+		t.Fatalf("Authorization = %q, want prefix %q", auth, wantPrefix)
+	}
+
+	if signature := strings.TrimPrefix(auth, wantPrefix); len(signature) != 64 {
+		t.Errorf("signature %q is not a hex sha256", signature)
+	}
+
+	if again := sign(); again != auth {
+		t.Errorf("signature of the same request changed: %q and %q", auth, again)
+	}
+}
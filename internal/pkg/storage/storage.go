@@ -0,0 +1,27 @@
+package storage
+
+import (
+	"context"
+	"errors"
+
+	"ordent/internal/config"
+)
+
+// Storage keep uploaded blobs, e.g. product images
+type Storage interface {
+	Put(ctx context.Context, key string, data []byte, contentType string) error
+	Delete(ctx context.Context, key string) error
+	URL(key string) string
+}
+
+// New create the storage backend selected by config driver
+func New(cfg config.Storage) (Storage, error) {
+	switch cfg.Driver {
+	case "", "local":
+		return NewLocal(cfg.Local)
+	case "s3":
+		return NewS3(cfg.S3), nil
+	}
+
+	return nil, errors.New("unknown storage driver " + cfg.Driver)
+}
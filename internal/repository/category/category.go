@@ -0,0 +1,216 @@
+package category
+
+import (
+	"context"
+	"database/sql"
+	"log"
+
+	enCategory "ordent/internal/entity/category"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+type Repository struct {
+	database *sqlx.DB
+}
+
+func NewRepository(
+	db *sqlx.DB,
+) *Repository {
+	return &Repository{
+		database: db,
+	}
+}
+
+func (r *Repository) InsertCategory(ctx context.Context, form enCategory.CategoryRequest) (int64, error) {
+  var id int64
+
+  err := r.database.QueryRowContext(ctx, `
+    insert into categories
+      (parent_id, name, slug)
+    values ($1, $2, $3)
+    returning id
+  `, form.ParentID, form.Name, form.Slug).Scan(&id)
+  if err != nil {
+    log.Printf("[InsertCategory] failed to insert category. err: %+v", err)
+    return 0, err
+  }
+
+  return id, nil
+}
+
+func (r *Repository) UpdateCategory(ctx context.Context, form enCategory.CategoryRequest) error {
+  _, err := r.database.ExecContext(ctx, `
+    update categories
+      set parent_id=$1, name=$2, slug=$3, updated_time=now()
+    where id = $4
+  `, form.ParentID, form.Name, form.Slug, form.ID)
+  if err != nil {
+    log.Printf("[UpdateCategory] failed to update category. err: %+v", err)
+    return err
+  }
+
+  return nil
+}
+
+func (r *Repository) DeleteCategory(ctx context.Context, categoryID int64) error {
+  _, err := r.database.ExecContext(ctx, `
+    delete from categories
+    where id = $1
+  `, categoryID)
+  if err != nil {
+    log.Printf("[DeleteCategory] failed to delete category. err: %+v", err)
+    return err
+  }
+
+  return nil
+}
+
+func (r *Repository) GetCategory(ctx context.Context, categoryID int64) (*enCategory.Category, error) {
+  category := &enCategory.Category{}
+
+  err := r.database.GetContext(ctx, category, `
+    select id, parent_id, name, slug
+    from categories
+    where id = $1
+  `, categoryID)
+  if err != nil {
+    if err == sql.ErrNoRows {
+      return category, nil
+    }
+
+    log.Printf("[GetCategory] failed to get category. err: %+v", err)
+    return category, err
+  }
+
+  return category, nil
+}
+
+func (r *Repository) GetCategoryBySlug(ctx context.Context, slug string) (*enCategory.Category, error) {
+  category := &enCategory.Category{}
+
+  err := r.database.GetContext(ctx, category, `
+    select id, parent_id, name, slug
+    from categories
+    where slug = $1
+  `, slug)
+  if err != nil {
+    if err == sql.ErrNoRows {
+      return category, nil
+    }
+
+    log.Printf("[GetCategoryBySlug] failed to get category. err: %+v", err)
+    return category, err
+  }
+
+  return category, nil
+}
+
+func (r *Repository) GetCategories(ctx context.Context) ([]enCategory.Category, error) {
+  categories := make([]enCategory.Category, 0)
+
+  err := r.database.SelectContext(ctx, &categories, `
+    select id, parent_id, name, slug
+    from categories
+    order by parent_id nulls first, name
+  `)
+  if err != nil {
+    log.Printf("[GetCategories] failed to get categories. err: %+v", err)
+    return categories, err
+  }
+
+  return categories, nil
+}
+
+func (r *Repository) GetCategoriesByIDs(ctx context.Context, categoryIDs []int64) ([]enCategory.Category, error) {
+  categories := make([]enCategory.Category, 0)
+
+  err := r.database.SelectContext(ctx, &categories, `
+    select id, parent_id, name, slug
+    from categories
+    where id = any($1)
+  `, pq.Array(categoryIDs))
+  if err != nil {
+    log.Printf("[GetCategoriesByIDs] failed to get categories. err: %+v", err)
+    return categories, err
+  }
+
+  return categories, nil
+}
+
+func (r *Repository) GetCategoriesBySlugs(ctx context.Context, slugs []string) ([]enCategory.Category, error) {
+  categories := make([]enCategory.Category, 0)
+
+  err := r.database.SelectContext(ctx, &categories, `
+    select id, parent_id, name, slug
+    from categories
+    where slug = any($1)
+  `, pq.Array(slugs))
+  if err != nil {
+    log.Printf("[GetCategoriesBySlugs] failed to get categories. err: %+v", err)
+    return categories, err
+  }
+
+  return categories, nil
+}
+
+// GetDescendantIDs return the category id itself and all of its sub categories
+func (r *Repository) GetDescendantIDs(ctx context.Context, categoryID int64) ([]int64, error) {
+  ids := make([]int64, 0)
+
+  err := r.database.SelectContext(ctx, &ids, `
+    with recursive tree as (
+      select id from categories where id = $1
+      union
+      select c.id from categories c
+      inner join tree t on c.parent_id = t.id
+    )
+    select id from tree
+  `, categoryID)
+  if err != nil {
+    log.Printf("[GetDescendantIDs] failed to get sub categories of %d. err: %+v", categoryID, err)
+    return ids, err
+  }
+
+  return ids, nil
+}
+
+// GetProductIDs return the products linked to the category or one of its sub categories
+func (r *Repository) GetProductIDs(ctx context.Context, categoryID int64) ([]int64, error) {
+  ids := make([]int64, 0)
+
+  err := r.database.SelectContext(ctx, &ids, `
+    with recursive tree as (
+      select id from categories where id = $1
+      union
+      select c.id from categories c
+      inner join tree t on c.parent_id = t.id
+    )
+    select distinct pc.product_id from product_categories pc
+    inner join tree t on t.id = pc.category_id
+  `, categoryID)
+  if err != nil {
+    log.Printf("[GetProductIDs] failed to get products of category %d. err: %+v", categoryID, err)
+    return ids, err
+  }
+
+  return ids, nil
+}
+
+// CountUsage return the number of sub categories and products linked to the category
+func (r *Repository) CountUsage(ctx context.Context, categoryID int64) (int64, int64, error) {
+  var children, products int64
+
+  err := r.database.QueryRowContext(ctx, `
+    select
+      (select count(*) from categories where parent_id = $1),
+      (select count(*) from product_categories where category_id = $1)
+  `, categoryID).Scan(&children, &products)
+  if err != nil {
+    log.Printf("[CountUsage] failed to count category usage. err: %+v", err)
+    return 0, 0, err
+  }
+
+  return children, products, nil
+}
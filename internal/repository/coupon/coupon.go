@@ -0,0 +1,323 @@
+package coupon
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+
+	enCoupon "ordent/internal/entity/coupon"
+	enPagination "ordent/internal/entity/pagination"
+	cursorPkg "ordent/internal/pkg/cursor"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+type Repository struct {
+	database *sqlx.DB
+}
+
+func NewRepository(
+	db *sqlx.DB,
+) *Repository {
+	return &Repository{
+		database: db,
+	}
+}
+
+const couponColumns = `
+  id, code, description, type, value, max_discount, min_spend, usage_limit,
+  per_user_limit, used_count, valid_from, valid_until, active, created_time
+`
+
+func (r *Repository) InsertCoupon(ctx context.Context, form enCoupon.CouponRequest) (int64, error) {
+  tx, err := r.database.BeginTxx(ctx, nil)
+  if err != nil {
+    log.Printf("[InsertCoupon] failed to begin transaction. err: %+v", err)
+    return 0, err
+  }
+  defer tx.Rollback()
+
+  var id int64
+  err = tx.QueryRowContext(ctx, `
+    insert into coupons
+      (code, description, type, value, max_discount, min_spend, usage_limit,
+      per_user_limit, valid_from, valid_until, active)
+    values ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+    returning id
+  `, form.Code, form.Description, form.Type, form.Value, form.MaxDiscount, form.MinSpend, form.UsageLimit,
+    form.PerUserLimit, form.ValidFrom, form.ValidUntil, form.Active == nil || *form.Active).Scan(&id)
+  if err != nil {
+    log.Printf("[InsertCoupon] failed to insert coupon. err: %+v", err)
+    return 0, err
+  }
+
+  if err = setEligibility(ctx, tx, id, form); err != nil {
+    return 0, err
+  }
+
+  if err = tx.Commit(); err != nil {
+    log.Printf("[InsertCoupon] failed to commit coupon. err: %+v", err)
+    return 0, err
+  }
+
+  return id, nil
+}
+
+func (r *Repository) UpdateCoupon(ctx context.Context, form enCoupon.CouponRequest) error {
+  tx, err := r.database.BeginTxx(ctx, nil)
+  if err != nil {
+    log.Printf("[UpdateCoupon] failed to begin transaction. err: %+v", err)
+    return err
+  }
+  defer tx.Rollback()
+
+  _, err = tx.ExecContext(ctx, `
+    update coupons
+      set code=$1, description=$2, type=$3, value=$4, max_discount=$5, min_spend=$6,
+      usage_limit=$7, per_user_limit=$8, valid_from=$9, valid_until=$10,
+      active=coalesce($11, active), updated_time=now()
+    where id = $12
+  `, form.Code, form.Description, form.Type, form.Value, form.MaxDiscount, form.MinSpend,
+    form.UsageLimit, form.PerUserLimit, form.ValidFrom, form.ValidUntil, form.Active, form.ID)
+  if err != nil {
+    log.Printf("[UpdateCoupon] failed to update coupon. err: %+v", err)
+    return err
+  }
+
+  if err = setEligibility(ctx, tx, form.ID, form); err != nil {
+    return err
+  }
+
+  if err = tx.Commit(); err != nil {
+    log.Printf("[UpdateCoupon] failed to commit coupon. err: %+v", err)
+    return err
+  }
+
+  return nil
+}
+
+// setEligibility replace the products and categories a coupon applies to
+func setEligibility(ctx context.Context, tx *sqlx.Tx, couponID int64, form enCoupon.CouponRequest) error {
+  _, err := tx.ExecContext(ctx, `
+    delete from coupon_products where coupon_id = $1
+  `, couponID)
+  if err != nil {
+    log.Printf("[setEligibility] failed to clear products of coupon %d. err: %+v", couponID, err)
+    return err
+  }
+
+  _, err = tx.ExecContext(ctx, `
+    delete from coupon_categories where coupon_id = $1
+  `, couponID)
+  if err != nil {
+    log.Printf("[setEligibility] failed to clear categories of coupon %d. err: %+v", couponID, err)
+    return err
+  }
+
+  _, err = tx.ExecContext(ctx, `
+    insert into coupon_products (coupon_id, product_id)
+    select $1, unnest($2::bigint[])
+    on conflict do nothing
+  `, couponID, pq.Array(form.ProductIDs))
+  if err != nil {
+    log.Printf("[setEligibility] failed to link products of coupon %d. err: %+v", couponID, err)
+    return err
+  }
+
+  _, err = tx.ExecContext(ctx, `
+    insert into coupon_categories (coupon_id, category_id)
+    select $1, unnest($2::bigint[])
+    on conflict do nothing
+  `, couponID, pq.Array(form.CategoryIDs))
+  if err != nil {
+    log.Printf("[setEligibility] failed to link categories of coupon %d. err: %+v", couponID, err)
+    return err
+  }
+
+  return nil
+}
+
+func (r *Repository) DeleteCoupon(ctx context.Context, couponID int64) error {
+  _, err := r.database.ExecContext(ctx, `
+    delete from coupons
+    where id = $1
+  `, couponID)
+  if err != nil {
+    log.Printf("[DeleteCoupon] failed to delete coupon. err: %+v", err)
+    return err
+  }
+
+  return nil
+}
+
+func (r *Repository) GetCoupon(ctx context.Context, couponID int64) (*enCoupon.Coupon, error) {
+  return r.getCoupon(ctx, "id = $1", couponID)
+}
+
+func (r *Repository) GetCouponByCode(ctx context.Context, code string) (*enCoupon.Coupon, error) {
+  return r.getCoupon(ctx, "code = $1", code)
+}
+
+func (r *Repository) getCoupon(ctx context.Context, condition string, arg interface{}) (*enCoupon.Coupon, error) {
+  coupon := &enCoupon.Coupon{}
+
+  err := r.database.GetContext(ctx, coupon, fmt.Sprintf(`
+    select %s
+    from coupons
+    where %s
+  `, couponColumns, condition), arg)
+  if err != nil {
+    if err == sql.ErrNoRows {
+      return coupon, nil
+    }
+
+    log.Printf("[getCoupon] failed to get coupon. err: %+v", err)
+    return coupon, err
+  }
+
+  coupon.ProductIDs = make([]int64, 0)
+  err = r.database.SelectContext(ctx, &coupon.ProductIDs, `
+    select product_id from coupon_products where coupon_id = $1 order by product_id
+  `, coupon.ID)
+  if err != nil {
+    log.Printf("[getCoupon] failed to get products of coupon %d. err: %+v", coupon.ID, err)
+    return coupon, err
+  }
+
+  coupon.CategoryIDs = make([]int64, 0)
+  err = r.database.SelectContext(ctx, &coupon.CategoryIDs, `
+    select category_id from coupon_categories where coupon_id = $1 order by category_id
+  `, coupon.ID)
+  if err != nil {
+    log.Printf("[getCoupon] failed to get categories of coupon %d. err: %+v", coupon.ID, err)
+    return coupon, err
+  }
+
+  return coupon, nil
+}
+
+func (r *Repository) GetCoupons(ctx context.Context, cursor enPagination.Cursor, limit int) ([]enCoupon.Coupon, error) {
+  coupons := make([]enCoupon.Coupon, 0)
+
+  condition, order := cursorPkg.Keyset(cursor, "created_time", "id", 2)
+  args := []interface{}{limit + 1}
+  if !cursor.IsZero() {
+    args = append(args, time.UnixMicro(cursor.Key), cursor.ID)
+  }
+
+  err := r.database.SelectContext(ctx, &coupons, fmt.Sprintf(`
+    select %s
+    from coupons
+    where %s
+    order by %s
+    limit $1
+  `, couponColumns, condition, order), args...)
+  if err != nil {
+    log.Printf("[GetCoupons] failed to get coupons. err: %+v", err)
+    return coupons, err
+  }
+
+  return coupons, nil
+}
+
+// IsEligible check the coupon applies to the product. A coupon without
+// products and categories applies to everything, a category also covers
+// all of its sub categories.
+func (r *Repository) IsEligible(ctx context.Context, couponID, productID int64) (bool, error) {
+  var eligible bool
+
+  err := r.database.QueryRowContext(ctx, `
+    with recursive tree as (
+      select category_id as id from coupon_categories where coupon_id = $1
+      union
+      select c.id from categories c
+      inner join tree t on c.parent_id = t.id
+    )
+    select
+      (
+        not exists (select 1 from coupon_products where coupon_id = $1)
+        and not exists (select 1 from coupon_categories where coupon_id = $1)
+      )
+      or exists (select 1 from coupon_products where coupon_id = $1 and product_id = $2)
+      or exists (
+        select 1 from product_categories pc
+        inner join tree t on t.id = pc.category_id
+        where pc.product_id = $2
+      )
+  `, couponID, productID).Scan(&eligible)
+  if err != nil {
+    log.Printf("[IsEligible] failed to check coupon %d for product %d. err: %+v", couponID, productID, err)
+    return false, err
+  }
+
+  return eligible, nil
+}
+
+func (r *Repository) CountUserRedemptions(ctx context.Context, couponID, userID int64) (int64, error) {
+  var count int64
+
+  err := r.database.QueryRowContext(ctx, `
+    select count(*) from coupon_redemptions
+    where coupon_id = $1 and user_id = $2
+  `, couponID, userID).Scan(&count)
+  if err != nil {
+    log.Printf("[CountUserRedemptions] failed to count redemptions of coupon %d. err: %+v", couponID, err)
+    return 0, err
+  }
+
+  return count, nil
+}
+
+func (r *Repository) GetRedemptions(ctx context.Context, couponID int64, cursor enPagination.Cursor, limit int) ([]enCoupon.Redemption, error) {
+  redemptions := make([]enCoupon.Redemption, 0)
+
+  condition, order := cursorPkg.Keyset(cursor, "cr.created_time", "cr.id", 3)
+  args := []interface{}{limit + 1, couponID}
+  if !cursor.IsZero() {
+    args = append(args, time.UnixMicro(cursor.Key), cursor.ID)
+  }
+
+  err := r.database.SelectContext(ctx, &redemptions, fmt.Sprintf(`
+    select cr.id, cr.coupon_id, cr.user_id, coalesce(u.username, '') as username,
+      cr.transaction_id, cr.discount, cr.created_time
+    from coupon_redemptions cr
+    left join users u on u.id = cr.user_id
+    where cr.coupon_id = $2 and %s
+    order by %s
+    limit $1
+  `, condition, order), args...)
+  if err != nil {
+    log.Printf("[GetRedemptions] failed to get redemptions of coupon %d. err: %+v", couponID, err)
+    return redemptions, err
+  }
+
+  return redemptions, nil
+}
+
+// GetReport sum the redemptions of every coupon redeemed between from and to
+func (r *Repository) GetReport(ctx context.Context, from, to time.Time) ([]enCoupon.Report, error) {
+  reports := make([]enCoupon.Report, 0)
+
+  err := r.database.SelectContext(ctx, &reports, `
+    select c.id as coupon_id, c.code,
+      count(cr.id) as redemptions,
+      count(distinct cr.user_id) as users,
+      coalesce(sum(cr.discount), 0) as discount,
+      coalesce(sum(t.total), 0) as revenue
+    from coupons c
+    inner join coupon_redemptions cr on cr.coupon_id = c.id
+    inner join transactions t on t.id = cr.transaction_id
+    where cr.created_time >= $1 and cr.created_time < $2
+    group by c.id, c.code
+    order by discount desc, c.id
+  `, from, to)
+  if err != nil {
+    log.Printf("[GetReport] failed to get coupon report. err: %+v", err)
+    return reports, err
+  }
+
+  return reports, nil
+}
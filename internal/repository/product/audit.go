@@ -0,0 +1,331 @@
+package product
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	enPagination "ordent/internal/entity/pagination"
+	enProduct "ordent/internal/entity/product"
+	enUser "ordent/internal/entity/user"
+	cursorPkg "ordent/internal/pkg/cursor"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type priceSnapshot struct {
+	Price    int64 `json:"price"`
+	Variants []struct {
+		ID    int64  `json:"id"`
+		Price *int64 `json:"price"`
+	} `json:"variants"`
+}
+
+// snapshotProduct read the product with its categories, variants and images
+// as json, nil when the product does not exist
+func snapshotProduct(ctx context.Context, tx *sqlx.Tx, productID int64) (json.RawMessage, error) {
+  var snapshot []byte
+
+  err := tx.QueryRowContext(ctx, `
+    select to_jsonb(p) - 'created_time' - 'updated_time' - 'low_stock_alerted' - 'rating_average' - 'rating_count' || jsonb_build_object(
+      'categories', coalesce((
+        select jsonb_agg(pc.category_id order by pc.category_id)
+        from product_categories pc where pc.product_id = p.id
+      ), '[]'::jsonb),
+      'variants', coalesce((
+        select jsonb_agg(to_jsonb(v) - 'product_id' - 'created_time' - 'updated_time' order by v.id)
+        from product_variants v where v.product_id = p.id
+      ), '[]'::jsonb),
+      'images', coalesce((
+        select jsonb_agg(jsonb_build_object('id', i.id, 'url', i.url, 'position', i.position) order by i.position, i.id)
+        from product_images i where i.product_id = p.id
+      ), '[]'::jsonb)
+    )
+    from products p
+    where p.id = $1
+    for update of p
+  `, productID).Scan(&snapshot)
+  if err != nil {
+    if err == sql.ErrNoRows {
+      return nil, nil
+    }
+    return nil, err
+  }
+
+  return snapshot, nil
+}
+
+// recordAudit snapshot the product after a mutation, store the audit entry
+// against the before snapshot and extend the price history when prices changed.
+// It must run inside the same transaction as the mutation.
+func recordAudit(ctx context.Context, tx *sqlx.Tx, productID int64, action string, before json.RawMessage) error {
+  after, err := snapshotProduct(ctx, tx, productID)
+  if err != nil {
+    return err
+  }
+
+  diff, err := diffSnapshots(before, after)
+  if err != nil {
+    return err
+  }
+
+  var userID *int64
+  username := ""
+  if sess, ok := enUser.SessionFromContext(ctx); ok {
+    userID = &sess.ID
+    username = sess.Username
+  }
+
+  _, err = tx.ExecContext(ctx, `
+    insert into product_audits
+      (product_id, user_id, username, action, before, after, diff)
+    values ($1, $2, $3, $4, $5, $6, $7)
+  `, productID, userID, username, action, nullableJSON(before), nullableJSON(after), diff)
+  if err != nil {
+    return err
+  }
+
+  return recordPrices(ctx, tx, productID, before, after)
+}
+
+// recordPrices close the open price of every changed series and open a new one
+func recordPrices(ctx context.Context, tx *sqlx.Tx, productID int64, before, after json.RawMessage) error {
+  if after == nil {
+    return nil
+  }
+
+  old := priceSnapshot{}
+  if before != nil {
+    if err := json.Unmarshal(before, &old); err != nil {
+      return err
+    }
+  }
+
+  current := priceSnapshot{}
+  if err := json.Unmarshal(after, &current); err != nil {
+    return err
+  }
+
+  if before == nil || old.Price != current.Price {
+    if err := openPrice(ctx, tx, productID, nil, &current.Price); err != nil {
+      return err
+    }
+  }
+
+  oldVariants := make(map[int64]*int64, len(old.Variants))
+  for _, variant := range old.Variants {
+    oldVariants[variant.ID] = variant.Price
+  }
+
+  for _, variant := range current.Variants {
+    previous, existed := oldVariants[variant.ID]
+    delete(oldVariants, variant.ID)
+
+    if existed && equalPrice(previous, variant.Price) {
+      continue
+    }
+
+    variantID := variant.ID
+    if err := openPrice(ctx, tx, productID, &variantID, variant.Price); err != nil {
+      return err
+    }
+  }
+
+  // variants removed in this mutation
+  for variantID := range oldVariants {
+    _, err := tx.ExecContext(ctx, `
+      update product_prices set valid_to = now()
+      where product_id = $1 and variant_id = $2 and valid_to is null
+    `, productID, variantID)
+    if err != nil {
+      return err
+    }
+  }
+
+  return nil
+}
+
+func openPrice(ctx context.Context, tx *sqlx.Tx, productID int64, variantID *int64, price *int64) error {
+  _, err := tx.ExecContext(ctx, `
+    update product_prices set valid_to = now()
+    where product_id = $1 and variant_id is not distinct from $2 and valid_to is null
+  `, productID, variantID)
+  if err != nil {
+    return err
+  }
+
+  _, err = tx.ExecContext(ctx, `
+    insert into product_prices (product_id, variant_id, price, valid_from)
+    values ($1, $2, $3, now())
+  `, productID, variantID, price)
+  return err
+}
+
+func equalPrice(a, b *int64) bool {
+  if a == nil || b == nil {
+    return a == nil && b == nil
+  }
+  return *a == *b
+}
+
+// diffSnapshots list the top level fields that differ between two snapshots
+func diffSnapshots(before, after json.RawMessage) ([]byte, error) {
+  oldFields := map[string]json.RawMessage{}
+  if before != nil {
+    if err := json.Unmarshal(before, &oldFields); err != nil {
+      return nil, err
+    }
+  }
+
+  newFields := map[string]json.RawMessage{}
+  if after != nil {
+    if err := json.Unmarshal(after, &newFields); err != nil {
+      return nil, err
+    }
+  }
+
+  diff := map[string]map[string]json.RawMessage{}
+  for field, value := range newFields {
+    if old, ok := oldFields[field]; !ok || !bytes.Equal(old, value) {
+      diff[field] = map[string]json.RawMessage{"before": nullableRaw(old), "after": value}
+    }
+  }
+
+  for field, old := range oldFields {
+    if _, ok := newFields[field]; !ok {
+      diff[field] = map[string]json.RawMessage{"before": old, "after": json.RawMessage("null")}
+    }
+  }
+
+  return json.Marshal(diff)
+}
+
+func nullableRaw(value json.RawMessage) json.RawMessage {
+  if value == nil {
+    return json.RawMessage("null")
+  }
+  return value
+}
+
+func nullableJSON(value json.RawMessage) interface{} {
+  if value == nil {
+    return nil
+  }
+  return []byte(value)
+}
+
+func (r *Repository) GetProductAudits(ctx context.Context, productID int64, cursor enPagination.Cursor, limit int) ([]enProduct.Audit, error) {
+  audits := make([]enProduct.Audit, 0)
+
+  condition, order := cursorPkg.Keyset(cursor, "created_time", "id", 3)
+  args := []interface{}{limit + 1, productID}
+  if !cursor.IsZero() {
+    args = append(args, time.UnixMicro(cursor.Key), cursor.ID)
+  }
+
+  err := r.database.SelectContext(ctx, &audits, fmt.Sprintf(`
+    select id, product_id, user_id, username, action,
+      coalesce(before, 'null'::jsonb) as before, coalesce(after, 'null'::jsonb) as after,
+      diff, created_time
+    from product_audits
+    where product_id = $2 and %s
+    order by %s
+    limit $1
+  `, condition, order), args...)
+  if err != nil {
+    log.Printf("[GetProductAudits] failed to get audits of product %d. err: %+v", productID, err)
+    return audits, err
+  }
+
+  return audits, nil
+}
+
+// GetPriceAt return the price charged at the given time, a variant without
+// its own price falls back to the product base price
+func (r *Repository) GetPriceAt(ctx context.Context, productID, variantID int64, at time.Time) (int64, bool, error) {
+  var price sql.NullInt64
+
+  if variantID != 0 {
+    err := r.database.QueryRowContext(ctx, `
+      select price from product_prices
+      where product_id = $1 and variant_id = $2
+        and valid_from <= $3 and (valid_to is null or valid_to > $3)
+      order by valid_from desc
+      limit 1
+    `, productID, variantID, at).Scan(&price)
+    if err != nil && err != sql.ErrNoRows {
+      log.Printf("[GetPriceAt] failed to get variant price. err: %+v", err)
+      return 0, false, err
+    }
+
+    if price.Valid {
+      return price.Int64, true, nil
+    }
+  }
+
+  err := r.database.QueryRowContext(ctx, `
+    select price from product_prices
+    where product_id = $1 and variant_id is null
+      and valid_from <= $2 and (valid_to is null or valid_to > $2)
+    order by valid_from desc
+    limit 1
+  `, productID, at).Scan(&price)
+  if err != nil {
+    if err == sql.ErrNoRows {
+      return 0, false, nil
+    }
+
+    log.Printf("[GetPriceAt] failed to get product price. err: %+v", err)
+    return 0, false, err
+  }
+
+  return price.Int64, price.Valid, nil
+}
+
+// mutate run fn inside a transaction together with the version bump and the
+// audit entry of the product, then drop the product cache
+func (r *Repository) mutate(ctx context.Context, productID int64, action string, fn func(tx *sqlx.Tx) error) error {
+  tx, err := r.database.BeginTxx(ctx, nil)
+  if err != nil {
+    log.Printf("[mutate] failed to begin %s of product %d. err: %+v", action, productID, err)
+    return err
+  }
+  defer tx.Rollback()
+
+  before, err := snapshotProduct(ctx, tx, productID)
+  if err != nil {
+    log.Printf("[mutate] failed to snapshot product %d. err: %+v", productID, err)
+    return err
+  }
+
+  if err = fn(tx); err != nil {
+    return err
+  }
+
+  // every admin change is a new version, sales are not
+  _, err = tx.ExecContext(ctx, `
+    update products set version = version + 1 where id = $1
+  `, productID)
+  if err != nil {
+    log.Printf("[mutate] failed to bump version of product %d. err: %+v", productID, err)
+    return err
+  }
+
+  if err = recordAudit(ctx, tx, productID, action, before); err != nil {
+    log.Printf("[mutate] failed to audit %s of product %d. err: %+v", action, productID, err)
+    return err
+  }
+
+  if err = tx.Commit(); err != nil {
+    log.Printf("[mutate] failed to commit %s of product %d. err: %+v", action, productID, err)
+    return err
+  }
+
+  r.deleteProductCache(productID)
+
+  return nil
+}
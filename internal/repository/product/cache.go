@@ -0,0 +1,109 @@
+package product
+
+import (
+	"context"
+	"crypto/sha1"
+	"fmt"
+	"log"
+	"strconv"
+
+	enPagination "ordent/internal/entity/pagination"
+	enProduct "ordent/internal/entity/product"
+	"ordent/internal/pkg/cache"
+)
+
+// Listing pages are cached under the current list version, every product
+// write bumps the version so pages cached before are never read again and
+// expire on their own.
+const (
+  listVersionKey = "products-list-version"
+  listKey        = "products-list-%d-%x"
+  listExpireTime = 300
+)
+
+func (r *Repository) GetProducts(ctx context.Context, cursor enPagination.Cursor, limit int, sort string) ([]enProduct.Product, error) {
+  page := fmt.Sprintf("all|%s|%d|%d|%d", sort, cursor.Key, cursor.ID, limit)
+  return r.cachedList(ctx, page, func(ctx context.Context) ([]enProduct.Product, error) {
+    return r.getProducts(ctx, cursor, limit, sort)
+  })
+}
+
+func (r *Repository) GetProductsByCategory(ctx context.Context, cursor enPagination.Cursor, limit int, sort string, categoryIDs []int64) ([]enProduct.Product, error) {
+  page := fmt.Sprintf("category|%s|%d|%d|%d|%v", sort, cursor.Key, cursor.ID, limit, categoryIDs)
+  return r.cachedList(ctx, page, func(ctx context.Context) ([]enProduct.Product, error) {
+    return r.getProductsByCategory(ctx, cursor, limit, sort, categoryIDs)
+  })
+}
+
+func (r *Repository) SearchProduct(ctx context.Context, query string, cursor enPagination.Cursor, limit int, sort string) ([]enProduct.Product, error) {
+  page := fmt.Sprintf("search|%s|%d|%d|%d|%s", sort, cursor.Key, cursor.ID, limit, query)
+  return r.cachedList(ctx, page, func(ctx context.Context) ([]enProduct.Product, error) {
+    return r.searchProduct(ctx, query, cursor, limit, sort)
+  })
+}
+
+// cachedList serve the listing page from the cache, or load and cache it.
+// The cache is best effort, the page is loaded whenever it fails.
+func (r *Repository) cachedList(ctx context.Context, page string, load func(ctx context.Context) ([]enProduct.Product, error)) ([]enProduct.Product, error) {
+  key := fmt.Sprintf(listKey, r.listVersion(), sha1.Sum([]byte(page)))
+
+  products, _, err := cache.Fetch(ctx, r.listCache, key, func(ctx context.Context) ([]enProduct.Product, bool, error) {
+    products, err := load(ctx)
+    return products, err == nil, err
+  })
+  if err != nil {
+    return make([]enProduct.Product, 0), err
+  }
+
+  return products, nil
+}
+
+func (r *Repository) listVersion() int64 {
+  versionByte, _ := r.redis.Get(listVersionKey).Value.([]byte)
+  version, _ := strconv.ParseInt(string(versionByte), 10, 64)
+  return version
+}
+
+// invalidateLists retire every cached listing page
+func (r *Repository) invalidateLists() {
+  if err := r.redis.Incr(listVersionKey); err != nil {
+    log.Printf("Failed to incr redis for key %s. err: %v", listVersionKey, err)
+  }
+}
+
+// GetProductsByIDs return the given products in the requested order, read
+// from the cache in one MGET. The misses are loaded in one batch and written
+// back in one pipeline, deleted and unknown products are left out.
+func (r *Repository) GetProductsByIDs(ctx context.Context, productIDs []int64) ([]enProduct.Product, error) {
+  products := make([]enProduct.Product, 0, len(productIDs))
+
+  keyOf := func(productID int64) string {
+    return fmt.Sprintf(productKey, productID)
+  }
+
+  found, err := cache.FetchMany(ctx, r.productCache, productIDs, keyOf, func(ctx context.Context, misses []int64) (map[int64]enProduct.Product, error) {
+    loaded, err := r.loadProducts(ctx, misses)
+    if err != nil {
+      return nil, err
+    }
+
+    byID := make(map[int64]enProduct.Product, len(loaded))
+    for _, product := range loaded {
+      byID[product.ID] = product
+    }
+
+    return byID, nil
+  })
+  if err != nil {
+    log.Printf("[GetProductsByIDs] failed to get products. err: %+v", err)
+    return products, err
+  }
+
+  for _, productID := range productIDs {
+    if product, ok := found[productID]; ok {
+      products = append(products, product)
+    }
+  }
+
+  return products, nil
+}
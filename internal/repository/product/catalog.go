@@ -0,0 +1,278 @@
+package product
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	enInventory "ordent/internal/entity/inventory"
+	enProduct "ordent/internal/entity/product"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// ImportProducts create or update one batch of catalog rows inside a single
+// transaction. Products are matched by name and variants by SKU. Rows the
+// stock can not follow, or naming the SKU of another product, are rolled back
+// to their savepoint and reported, the rest of the batch is kept.
+func (r *Repository) ImportProducts(ctx context.Context, rows []enProduct.CatalogRow, categoryIDs map[string]int64) (int, int, []enProduct.CatalogError, error) {
+  var created, updated int
+  rowErrors := make([]enProduct.CatalogError, 0)
+
+  tx, err := r.database.BeginTxx(ctx, nil)
+  if err != nil {
+    log.Printf("[ImportProducts] failed to begin transaction. err: %+v", err)
+    return 0, 0, nil, err
+  }
+  defer tx.Rollback()
+
+  touched := make(map[int64]bool)
+  for _, row := range rows {
+    if _, err = tx.ExecContext(ctx, `savepoint import_row`); err != nil {
+      log.Printf("[ImportProducts] failed to set savepoint at line %d. err: %+v", row.Line, err)
+      return 0, 0, nil, err
+    }
+
+    productID, isNew, err := importCatalogRow(ctx, tx, row, categoryIDs)
+    if errors.Is(err, enProduct.ErrInsufficientStock) || errors.Is(err, enProduct.ErrSKUTaken) {
+      if _, err := tx.ExecContext(ctx, `rollback to savepoint import_row`); err != nil {
+        log.Printf("[ImportProducts] failed to roll back line %d. err: %+v", row.Line, err)
+        return 0, 0, nil, err
+      }
+
+      rowError := enProduct.CatalogError{Line: row.Line, Field: "sku", Message: "SKU belongs to another product"}
+      if errors.Is(err, enProduct.ErrInsufficientStock) {
+        rowError = enProduct.CatalogError{Line: row.Line, Field: "stock", Message: fmt.Sprintf("Not enough unreserved stock in the default warehouse to lower the stock to %d", row.Stock)}
+      }
+      rowErrors = append(rowErrors, rowError)
+      continue
+    }
+    if err != nil {
+      return 0, 0, nil, err
+    }
+
+    // several rows of the same product only count once
+    if isNew {
+      created++
+    } else if !touched[productID] {
+      updated++
+    }
+
+    touched[productID] = true
+  }
+
+  if err = tx.Commit(); err != nil {
+    log.Printf("[ImportProducts] failed to commit batch. err: %+v", err)
+    return 0, 0, nil, err
+  }
+
+  for productID := range touched {
+    r.deleteProductCache(productID)
+  }
+
+  return created, updated, rowErrors, nil
+}
+
+// importCatalogRow apply one catalog row and return the product it touched
+// and whether the product was created by it
+func importCatalogRow(ctx context.Context, tx *sqlx.Tx, row enProduct.CatalogRow, categoryIDs map[string]int64) (int64, bool, error) {
+  var productID, stock int64
+  err := tx.QueryRowContext(ctx, `
+    select id, stock from products
+    where lower(name) = lower($1) and deleted_at is null
+    order by id
+    limit 1
+    for update
+  `, row.Name).Scan(&productID, &stock)
+  if err != nil && err != sql.ErrNoRows {
+    log.Printf("[ImportProducts] failed to find product at line %d. err: %+v", row.Line, err)
+    return 0, false, err
+  }
+
+  before, err := snapshotProduct(ctx, tx, productID)
+  if err != nil {
+    log.Printf("[ImportProducts] failed to snapshot product at line %d. err: %+v", row.Line, err)
+    return 0, false, err
+  }
+
+  isNew := productID == 0
+  if isNew {
+    err = tx.QueryRowContext(ctx, `
+      insert into products
+        (name, price, stock)
+      values ($1, $2, $3)
+      returning id
+    `, row.Name, row.Price, productStock(row)).Scan(&productID)
+  } else if row.SKU == "" {
+    _, err = tx.ExecContext(ctx, `
+      update products
+        set price=$1, stock=$2, version=version+1, updated_time=now()
+      where id = $3
+    `, row.Price, row.Stock, productID)
+  } else {
+    _, err = tx.ExecContext(ctx, `
+      update products
+        set price=$1, version=version+1, updated_time=now()
+      where id = $2
+    `, row.Price, productID)
+  }
+  if err != nil {
+    log.Printf("[ImportProducts] failed to save product at line %d. err: %+v", row.Line, err)
+    return 0, false, err
+  }
+
+  // stock is set absolute by the file, log the difference
+  if row.SKU == "" {
+    note := fmt.Sprintf("line %d", row.Line)
+    if err = moveDefaultStock(ctx, tx, productID, nil, row.Stock-stock, enInventory.ReasonImport, note); err != nil {
+      log.Printf("[ImportProducts] failed to record stock at line %d. err: %+v", row.Line, err)
+      return 0, false, err
+    }
+  }
+
+  ids := make([]int64, 0, len(row.Categories))
+  for _, slug := range row.Categories {
+    ids = append(ids, categoryIDs[slug])
+  }
+
+  _, err = tx.ExecContext(ctx, `
+    delete from product_categories
+    where product_id = $1 and not (category_id = any($2))
+  `, productID, pq.Array(ids))
+  if err != nil {
+    log.Printf("[ImportProducts] failed to clear categories at line %d. err: %+v", row.Line, err)
+    return 0, false, err
+  }
+
+  if err = setProductCategories(ctx, tx, productID, ids); err != nil {
+    log.Printf("[ImportProducts] failed to save categories at line %d. err: %+v", row.Line, err)
+    return 0, false, err
+  }
+
+  if row.SKU != "" {
+    var variantID, ownerID, variantStock int64
+    err = tx.QueryRowContext(ctx, `
+      select product_id, stock from product_variants where sku = $1 for update
+    `, row.SKU).Scan(&ownerID, &variantStock)
+    if err != nil && err != sql.ErrNoRows {
+      log.Printf("[ImportProducts] failed to find variant at line %d. err: %+v", row.Line, err)
+      return 0, false, err
+    }
+
+    if ownerID != 0 && ownerID != productID {
+      return 0, false, enProduct.ErrSKUTaken
+    }
+
+    err = tx.QueryRowContext(ctx, `
+      insert into product_variants
+        (product_id, sku, options, price, stock)
+      values ($1, $2, $3, $4, $5)
+      on conflict (sku) do update
+        set options = excluded.options, price = excluded.price,
+          stock = excluded.stock, updated_time = now()
+      returning id
+    `, productID, row.SKU, row.Options, row.VariantPrice, row.Stock).Scan(&variantID)
+    if err != nil {
+      log.Printf("[ImportProducts] failed to save variant at line %d. err: %+v", row.Line, err)
+      return 0, false, err
+    }
+
+    note := fmt.Sprintf("line %d", row.Line)
+    if err = moveDefaultStock(ctx, tx, productID, &variantID, row.Stock-variantStock, enInventory.ReasonImport, note); err != nil {
+      log.Printf("[ImportProducts] failed to record variant stock at line %d. err: %+v", row.Line, err)
+      return 0, false, err
+    }
+
+    if err = syncVariantTotals(ctx, tx, productID); err != nil {
+      log.Printf("[ImportProducts] failed to sync product stock at line %d. err: %+v", row.Line, err)
+      return 0, false, err
+    }
+  }
+
+  if err = recordAudit(ctx, tx, productID, enProduct.AuditActionImport, before); err != nil {
+    log.Printf("[ImportProducts] failed to audit product at line %d. err: %+v", row.Line, err)
+    return 0, false, err
+  }
+
+  return productID, isNew, nil
+}
+
+// GetSKUOwners return the name of the product owning each of the SKUs that
+// exist, as catalog rows with name and SKU
+func (r *Repository) GetSKUOwners(ctx context.Context, skus []string) ([]enProduct.CatalogRow, error) {
+  rows := make([]enProduct.CatalogRow, 0)
+  if len(skus) == 0 {
+    return rows, nil
+  }
+
+  err := r.database.SelectContext(ctx, &rows, `
+    select p.name, v.sku
+    from product_variants v
+    inner join products p on p.id = v.product_id
+    where v.sku = any($1) and p.deleted_at is null
+  `, pq.Array(skus))
+  if err != nil {
+    log.Printf("[GetSKUOwners] failed to get products of %d SKUs. err: %+v", len(skus), err)
+    return rows, err
+  }
+
+  return rows, nil
+}
+
+// productStock is the stock of a newly created product, variant rows add
+// their stock through the variant instead
+func productStock(row enProduct.CatalogRow) int64 {
+  if row.SKU != "" {
+    return 0
+  }
+  return row.Stock
+}
+
+// ExportProducts stream the whole catalog, one row per product without
+// variants and one row per variant otherwise
+func (r *Repository) ExportProducts(ctx context.Context, fn func(row enProduct.CatalogRow) error) error {
+  rows, err := r.database.QueryxContext(ctx, `
+    select
+      p.name, p.price, coalesce(v.stock, p.stock) as stock,
+      coalesce(v.sku, '') as sku, coalesce(v.options, '{}'::jsonb) as options,
+      v.price as variant_price, coalesce(c.slugs, '') as categories
+    from products p
+    left join product_variants v on v.product_id = p.id
+    left join lateral (
+      select string_agg(c.slug, '|' order by c.id) as slugs
+      from product_categories pc
+      inner join categories c on c.id = pc.category_id
+      where pc.product_id = p.id
+    ) c on true
+    where p.deleted_at is null
+    order by p.id, v.id
+  `)
+  if err != nil {
+    log.Printf("[ExportProducts] failed to query catalog. err: %+v", err)
+    return err
+  }
+  defer rows.Close()
+
+  for rows.Next() {
+    row := enProduct.CatalogRow{}
+    if err = rows.StructScan(&row); err != nil {
+      log.Printf("[ExportProducts] failed to scan catalog row. err: %+v", err)
+      return err
+    }
+
+    row.Categories = []string{}
+    if row.CategoryList != "" {
+      row.Categories = strings.Split(row.CategoryList, "|")
+    }
+
+    if err = fn(row); err != nil {
+      return err
+    }
+  }
+
+  return rows.Err()
+}
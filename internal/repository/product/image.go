@@ -0,0 +1,125 @@
+package product
+
+import (
+	"context"
+	"database/sql"
+	"log"
+
+	enProduct "ordent/internal/entity/product"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+func (r *Repository) InsertImage(ctx context.Context, image enProduct.Image) (int64, error) {
+  var id int64
+
+  err := r.mutate(ctx, image.ProductID, enProduct.AuditActionUploadImage, func(tx *sqlx.Tx) error {
+    // without explicit position the image goes to the end of the gallery
+    err := tx.QueryRowContext(ctx, `
+      insert into product_images
+        (product_id, storage_key, thumbnail_key, url, thumbnail_url, content_type, size, position)
+      values ($1, $2, $3, $4, $5, $6, $7,
+        case when $8 >= 0 then $8
+        else (select coalesce(max(position) + 1, 0) from product_images where product_id = $1) end)
+      returning id
+    `, image.ProductID, image.StorageKey, image.ThumbnailKey, image.URL, image.ThumbnailURL,
+      image.ContentType, image.Size, image.Position).Scan(&id)
+    if err != nil {
+      log.Printf("[InsertImage] failed to insert image. err: %+v", err)
+    }
+    return err
+  })
+  if err != nil {
+    return 0, err
+  }
+
+  return id, nil
+}
+
+func (r *Repository) DeleteImage(ctx context.Context, image enProduct.Image) error {
+  return r.mutate(ctx, image.ProductID, enProduct.AuditActionDeleteImage, func(tx *sqlx.Tx) error {
+    _, err := tx.ExecContext(ctx, `
+      delete from product_images
+      where id = $1
+    `, image.ID)
+    if err != nil {
+      log.Printf("[DeleteImage] failed to delete image. err: %+v", err)
+    }
+    return err
+  })
+}
+
+func (r *Repository) GetImage(ctx context.Context, imageID int64) (*enProduct.Image, error) {
+  image := &enProduct.Image{}
+
+  err := r.database.GetContext(ctx, image, `
+    select id, product_id, storage_key, thumbnail_key, url, thumbnail_url, content_type, size, position
+    from product_images
+    where id = $1
+  `, imageID)
+  if err != nil {
+    if err == sql.ErrNoRows {
+      return image, nil
+    }
+
+    log.Printf("[GetImage] failed to get image. err: %+v", err)
+    return image, err
+  }
+
+  return image, nil
+}
+
+// ReorderImages set image positions following the order of imageIDs
+func (r *Repository) ReorderImages(ctx context.Context, productID int64, imageIDs []int64) error {
+  return r.mutate(ctx, productID, enProduct.AuditActionReorderImages, func(tx *sqlx.Tx) error {
+    _, err := tx.ExecContext(ctx, `
+      update product_images i
+        set position = o.position - 1
+      from unnest($2::bigint[]) with ordinality as o(id, position)
+      where i.id = o.id and i.product_id = $1
+    `, productID, pq.Array(imageIDs))
+    if err != nil {
+      log.Printf("[ReorderImages] failed to reorder images. err: %+v", err)
+    }
+    return err
+  })
+}
+
+// attachImages load the images of the given products in one query
+func (r *Repository) attachImages(ctx context.Context, products []enProduct.Product) error {
+  if len(products) == 0 {
+    return nil
+  }
+
+  productIDs := make([]int64, len(products))
+  for i, product := range products {
+    productIDs[i] = product.ID
+  }
+
+  rows := make([]enProduct.Image, 0)
+  err := r.database.SelectContext(ctx, &rows, `
+    select id, product_id, storage_key, thumbnail_key, url, thumbnail_url, content_type, size, position
+    from product_images
+    where product_id = any($1)
+    order by product_id, position, id
+  `, pq.Array(productIDs))
+  if err != nil {
+    log.Printf("[attachImages] failed to get product images. err: %+v", err)
+    return err
+  }
+
+  images := make(map[int64][]enProduct.Image, len(products))
+  for _, image := range rows {
+    images[image.ProductID] = append(images[image.ProductID], image)
+  }
+
+  for i := range products {
+    products[i].Images = images[products[i].ID]
+    if products[i].Images == nil {
+      products[i].Images = make([]enProduct.Image, 0)
+    }
+  }
+
+  return nil
+}
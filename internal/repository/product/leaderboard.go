@@ -0,0 +1,239 @@
+package product
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	enProduct "ordent/internal/entity/product"
+)
+
+// Leaderboards are sorted sets of product ids scored by units sold. Every
+// scope, the whole catalog or a category with its sub categories, has an all
+// time set plus hourly and daily buckets the trending windows are summed from.
+const (
+  leaderboardKey = "leaderboard:sold"
+  hourBucket     = "%s:h:%s"
+  dayBucket      = "%s:d:%s"
+  trendingKey    = "%s:trending:%s"
+
+  // buckets outlive the window they are summed into by one bucket
+  hourBucketTTL = 25 * 60 * 60
+  dayBucketTTL  = 8 * 24 * 60 * 60
+  // trending is summed again at most once a minute
+  trendingTTL = 60
+  seedBatch   = 500
+)
+
+func scopeKey(categoryID int64) string {
+  if categoryID == 0 {
+    return leaderboardKey
+  }
+  return fmt.Sprintf("%s:category-%d", leaderboardKey, categoryID)
+}
+
+// RecordSale count the units of a sale on the leaderboards of the catalog
+// and of every category the product is in, parents included
+func (r *Repository) RecordSale(ctx context.Context, productID, amount int64) error {
+  categoryIDs := make([]int64, 0)
+  err := r.database.SelectContext(ctx, &categoryIDs, `
+    with recursive tree as (
+      select category_id as id from product_categories where product_id = $1
+      union
+      select c.parent_id from categories c
+      inner join tree t on c.id = t.id
+      where c.parent_id is not null
+    )
+    select id from tree
+  `, productID)
+  if err != nil {
+    log.Printf("[RecordSale] failed to get categories of product %d. err: %+v", productID, err)
+    return err
+  }
+
+  now := time.Now().UTC()
+  member := strconv.FormatInt(productID, 10)
+
+  for _, categoryID := range append([]int64{0}, categoryIDs...) {
+    scope := scopeKey(categoryID)
+
+    // an all time set missing is seeded from sold, which has this sale already
+    exists, _ := r.redis.Exists(scope).Value.(int64)
+    if exists == 1 {
+      if err = r.redis.ZIncrBy(scope, amount, member); err != nil {
+        log.Printf("[RecordSale] failed to count sale on %s. err: %v", scope, err)
+        return err
+      }
+    }
+
+    buckets := map[string]int{
+      fmt.Sprintf(hourBucket, scope, now.Format("2006010215")): hourBucketTTL,
+      fmt.Sprintf(dayBucket, scope, now.Format("20060102")):    dayBucketTTL,
+    }
+    for key, ttl := range buckets {
+      if err = r.redis.ZIncrBy(key, amount, member); err != nil {
+        log.Printf("[RecordSale] failed to count sale on %s. err: %v", key, err)
+        return err
+      }
+
+      if err = r.redis.Expire(key, ttl); err != nil {
+        log.Printf("[RecordSale] failed to expire %s. err: %v", key, err)
+        return err
+      }
+    }
+  }
+
+  return nil
+}
+
+// GetBestsellers return the top of the all time leaderboard of the scope,
+// categoryID 0 being the whole catalog
+func (r *Repository) GetBestsellers(ctx context.Context, categoryID int64, limit int) ([]enProduct.Ranking, error) {
+  scope := scopeKey(categoryID)
+
+  exists, _ := r.redis.Exists(scope).Value.(int64)
+  if exists == 0 {
+    if err := r.seedBestsellers(ctx, scope, categoryID); err != nil {
+      return make([]enProduct.Ranking, 0), err
+    }
+  }
+
+  return r.getRankings(scope, limit)
+}
+
+// seedBestsellers fill the all time leaderboard of a scope from sold. Sales
+// recorded while seeding may have created the set already, the higher count
+// of a product wins.
+func (r *Repository) seedBestsellers(ctx context.Context, scope string, categoryID int64) error {
+  rows := make([]struct {
+    ID   int64 `db:"id"`
+    Sold int64 `db:"sold"`
+  }, 0)
+
+  err := r.database.SelectContext(ctx, &rows, `
+    with recursive tree as (
+      select id from categories where id = $1
+      union
+      select c.id from categories c
+      inner join tree t on c.parent_id = t.id
+    )
+    select p.id, p.sold
+    from products p
+    where p.sold > 0 and p.deleted_at is null
+      and ($1 = 0 or exists (
+        select 1 from product_categories pc
+        inner join tree t on t.id = pc.category_id
+        where pc.product_id = p.id
+      ))
+  `, categoryID)
+  if err != nil {
+    log.Printf("[seedBestsellers] failed to get sold of %s. err: %+v", scope, err)
+    return err
+  }
+
+  if len(rows) == 0 {
+    return nil
+  }
+
+  seed := scope + ":seed"
+  for start := 0; start < len(rows); start += seedBatch {
+    end := start + seedBatch
+    if end > len(rows) {
+      end = len(rows)
+    }
+
+    scores := make(map[string]int64, end-start)
+    for _, row := range rows[start:end] {
+      scores[strconv.FormatInt(row.ID, 10)] = row.Sold
+    }
+
+    if err = r.redis.ZAdd(seed, scores); err != nil {
+      log.Printf("[seedBestsellers] failed to seed %s. err: %v", scope, err)
+      return err
+    }
+  }
+
+  err = r.redis.ZUnionStore(scope, []string{scope, seed}, "MAX")
+  r.redis.Del(seed)
+  if err != nil {
+    log.Printf("[seedBestsellers] failed to store %s. err: %v", scope, err)
+    return err
+  }
+
+  return nil
+}
+
+// GetTrending return the top of the scope over the window, summed from its
+// buckets. The window moves by whole hours for 24h and whole days for 7d.
+func (r *Repository) GetTrending(ctx context.Context, window string, categoryID int64, limit int) ([]enProduct.Ranking, error) {
+  scope := scopeKey(categoryID)
+  now := time.Now().UTC()
+
+  keys := make([]string, 0)
+  switch window {
+  case enProduct.WindowDay:
+    for i := 0; i < 24; i++ {
+      keys = append(keys, fmt.Sprintf(hourBucket, scope, now.Add(-time.Duration(i)*time.Hour).Format("2006010215")))
+    }
+  case enProduct.WindowWeek:
+    for i := 0; i < 7; i++ {
+      keys = append(keys, fmt.Sprintf(dayBucket, scope, now.AddDate(0, 0, -i).Format("20060102")))
+    }
+  default:
+    return make([]enProduct.Ranking, 0), enProduct.ErrUnknownWindow
+  }
+
+  key := fmt.Sprintf(trendingKey, scope, window)
+
+  exists, _ := r.redis.Exists(key).Value.(int64)
+  if exists == 0 {
+    if err := r.redis.ZUnionStore(key, keys, ""); err != nil {
+      log.Printf("[GetTrending] failed to sum %s. err: %v", key, err)
+      return make([]enProduct.Ranking, 0), err
+    }
+
+    if err := r.redis.Expire(key, trendingTTL); err != nil {
+      log.Printf("[GetTrending] failed to expire %s. err: %v", key, err)
+    }
+  }
+
+  return r.getRankings(key, limit)
+}
+
+// getRankings read the top limit products of a leaderboard
+func (r *Repository) getRankings(key string, limit int) ([]enProduct.Ranking, error) {
+  rankings := make([]enProduct.Ranking, 0)
+
+  result := r.redis.ZRevRange(key, 0, limit-1, true)
+  if result.Error != nil {
+    log.Printf("[getRankings] failed to read %s. err: %v", key, result.Error)
+    return rankings, result.Error
+  }
+
+  values, _ := result.Value.([]interface{})
+  for i := 0; i+1 < len(values); i += 2 {
+    member, _ := values[i].([]byte)
+    score, _ := values[i+1].([]byte)
+
+    productID, err := strconv.ParseInt(string(member), 10, 64)
+    if err != nil {
+      return rankings, errors.New(fmt.Sprintf("invalid member %q in %s", member, key))
+    }
+
+    units, err := strconv.ParseFloat(string(score), 64)
+    if err != nil {
+      return rankings, errors.New(fmt.Sprintf("invalid score %q in %s", score, key))
+    }
+
+    rankings = append(rankings, enProduct.Ranking{
+      Rank:      len(rankings) + 1,
+      ProductID: productID,
+      Units:     int64(units),
+    })
+  }
+
+  return rankings, nil
+}
@@ -3,13 +3,20 @@ package product
 import (
 	"context"
 	"database/sql"
-	"encoding/json"
 	"fmt"
 	"log"
+	"strings"
+	"time"
+	enCategory "ordent/internal/entity/category"
+	enInventory "ordent/internal/entity/inventory"
+	enPagination "ordent/internal/entity/pagination"
 	enProduct "ordent/internal/entity/product"
+	"ordent/internal/pkg/cache"
+	cursorPkg "ordent/internal/pkg/cursor"
 	"ordent/internal/pkg/redigo"
 
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 )
 
 type (
@@ -18,12 +25,25 @@ type (
 		Get(key string) *redigo.Result
 		Keys(key string) *redigo.Result
 		Setex(key string, expireTime int, value interface{}) error
+		SetexMulti(values map[string]interface{}, expireTime int) error
+		Incr(keys ...string) error
+		Exists(key string) *redigo.Result
+		Expire(key string, seconds int) error
+		ZAdd(key string, scores map[string]int64) error
+		ZIncrBy(key string, increment int64, member string) error
+		ZRevRange(key string, start int, end int, withScores bool) *redigo.Result
+		ZUnionStore(destination string, keys []string, aggregate string) error
+		MGet(keys ...string) *redigo.Result
+		Multi(commands ...redigo.Command) *redigo.Result
+		LRange(key string, start int, stop int) *redigo.Result
 	}
 )
 
 type Repository struct {
-	database *sqlx.DB
-	redis    redis
+	database     *sqlx.DB
+	redis        redis
+	productCache *cache.Cache
+	listCache    *cache.Cache
 }
 
 func NewRepository(
@@ -33,108 +53,318 @@ func NewRepository(
 	return &Repository{
 		database: db,
 		redis:    redis,
+		productCache: cache.New(redis, cache.Options{
+			TTL:         expireTime * time.Second,
+			NegativeTTL: negativeExpireTime * time.Second,
+			Beta:        1,
+		}),
+		listCache: cache.New(redis, cache.Options{
+			TTL:  listExpireTime * time.Second,
+			Beta: 1,
+		}),
 	}
 }
 
+// unknown product ids are remembered for a short while, so requests for ids
+// that do not exist do not all reach the database
 const (
-  expireTime = 3600
-  productKey = "product-%d"
+  expireTime         = 3600
+  negativeExpireTime = 30
+  productKey         = "product-%d"
 )
 
+// sortKeys map a listing sort to its keyset column, the average rating is
+// keyed in hundredths to fit the integer cursor
+var sortKeys = map[string]string{
+  enProduct.SortSold:   "sold",
+  enProduct.SortRating: "(rating_average * 100)::bigint",
+}
+
 func (r *Repository) InsertProduct(ctx context.Context, form enProduct.ProductRequest) (int64, error) {
-  result, err := r.database.ExecContext(ctx, `
+  tx, err := r.database.BeginTxx(ctx, nil)
+  if err != nil {
+    log.Printf("[InsertProduct] failed to begin transaction. err: %+v", err)
+    return 0, err
+  }
+  defer tx.Rollback()
+
+  var id int64
+  err = tx.QueryRowContext(ctx, `
     insert into products
-      (name, "type", price, stock)
-    values ($1, $2, $3, $4)
-  `, form.Name, form.Type, form.Price, form.Stock)
+      (name, price, stock)
+    values ($1, $2, $3)
+    returning id
+  `, form.Name, form.Price, form.Stock).Scan(&id)
   if err != nil {
     log.Printf("[InsertProduct] failed to insert product. err: %+v", err)
     return 0, err 
   }
 
-  id, err := result.LastInsertId()
+  err = setProductCategories(ctx, tx, id, form.CategoryIDs)
   if err != nil {
-    log.Printf("[InsertProduct] failed to insert product. err: %+v", err)
-    return 0, err 
+    log.Printf("[InsertProduct] failed to insert product categories. err: %+v", err)
+    return 0, err
+  }
+
+  if err = moveDefaultStock(ctx, tx, id, nil, form.Stock, enInventory.ReasonInitial, ""); err != nil {
+    log.Printf("[InsertProduct] failed to record initial stock. err: %+v", err)
+    return 0, err
   }
 
+  if err = recordAudit(ctx, tx, id, enProduct.AuditActionInsert, nil); err != nil {
+    log.Printf("[InsertProduct] failed to audit product. err: %+v", err)
+    return 0, err
+  }
+
+  if err = tx.Commit(); err != nil {
+    log.Printf("[InsertProduct] failed to commit product. err: %+v", err)
+    return 0, err
+  }
+
+  // a lookup of the id before it existed may have cached it as missing
+  r.deleteProductCache(id)
+
   return id, nil
 }
 
-func (r *Repository) UpdateProduct(ctx context.Context, form enProduct.Product) error {
-  _, err := r.database.ExecContext(ctx, `
-    update products
-     set name=$1, "type"=$2, price=$3, stock=$4, sold=$5
-    where id = $6
-  `, form.Name, form.Type, form.Price, form.Stock, form.Sold, form.ID)
-  if err != nil {
-    log.Printf("[InsertProduct] failed to insert product. err: %+v", err)
-    return err 
-  }
+// PatchProduct update only the supplied fields. With version set the update
+// only applies when the product is still at that version.
+func (r *Repository) PatchProduct(ctx context.Context, productID int64, patch enProduct.ProductPatch, version int64) error {
+  return r.mutate(ctx, productID, enProduct.AuditActionUpdate, func(tx *sqlx.Tx) error {
+    result, err := tx.ExecContext(ctx, `
+      update products
+       set name=coalesce($1, name), price=coalesce($2, price), updated_time=now()
+      where id = $3 and deleted_at is null and ($4 = 0 or version = $4)
+    `, patch.Name, patch.Price, productID, version)
+    if err != nil {
+      log.Printf("[PatchProduct] failed to update product. err: %+v", err)
+      return err 
+    }
 
-  key := fmt.Sprintf(productKey, form.ID)
+    affected, err := result.RowsAffected()
+    if err != nil {
+      log.Printf("[PatchProduct] failed to update product. err: %+v", err)
+      return err
+    }
 
-  err = r.redis.Del(key)
-  if err != nil {
-    log.Printf("Failed to delete redis for key %s. err: %v", key, err)
+    if affected == 0 {
+      return enProduct.ErrVersionConflict
+    }
+
+    // categories are only replaced when the request carries them
+    if patch.CategoryIDs != nil {
+      _, err = tx.ExecContext(ctx, `
+        delete from product_categories where product_id = $1
+      `, productID)
+      if err != nil {
+        log.Printf("[PatchProduct] failed to clear product categories. err: %+v", err)
+        return err
+      }
+
+      err = setProductCategories(ctx, tx, productID, *patch.CategoryIDs)
+      if err != nil {
+        log.Printf("[PatchProduct] failed to update product categories. err: %+v", err)
+        return err
+      }
+    }
+
+    return nil
+  })
+}
+
+func setProductCategories(ctx context.Context, tx *sqlx.Tx, productID int64, categoryIDs []int64) error {
+  _, err := tx.ExecContext(ctx, `
+    insert into product_categories (product_id, category_id)
+    select $1, unnest($2::bigint[])
+    on conflict do nothing
+  `, productID, pq.Array(categoryIDs))
+  return err
+}
+
+// attachRelations load categories, images and running promotions of listed products
+func (r *Repository) attachRelations(ctx context.Context, products []enProduct.Product) error {
+  if err := r.attachCategories(ctx, products); err != nil {
+    return err
   }
 
-  return nil
+  if err := r.attachPromotions(ctx, products); err != nil {
+    return err
+  }
+
+  return r.attachImages(ctx, products)
 }
 
-func (r *Repository) UpdateSoldAndStock(ctx context.Context, sold, stock int64, productID int64) error {
-  _, err := r.database.ExecContext(ctx, `
-    update products
-     set stock=stock-$1, sold=sold+$2
-    where id = $3
-  `, stock, sold, productID)
-  if err != nil {
-    log.Printf("[InsertProduct] failed to insert product. err: %+v", err)
-    return err 
+// attachCategories load the categories of the given products in one query
+func (r *Repository) attachCategories(ctx context.Context, products []enProduct.Product) error {
+  if len(products) == 0 {
+    return nil
   }
 
-  key := fmt.Sprintf(productKey, productID)
+  productIDs := make([]int64, len(products))
+  for i, product := range products {
+    productIDs[i] = product.ID
+  }
 
-  err = r.redis.Del(key)
+  links := make([]enCategory.ProductCategory, 0)
+  err := r.database.SelectContext(ctx, &links, `
+    select pc.product_id, c.id, c.parent_id, c.name, c.slug
+    from product_categories pc
+    inner join categories c on c.id = pc.category_id
+    where pc.product_id = any($1)
+    order by c.id
+  `, pq.Array(productIDs))
   if err != nil {
-    log.Printf("Failed to delete redis for key %s. err: %v", key, err)
+    log.Printf("[attachCategories] failed to get product categories. err: %+v", err)
+    return err
+  }
+
+  categories := make(map[int64][]enCategory.Category, len(products))
+  for _, link := range links {
+    categories[link.ProductID] = append(categories[link.ProductID], link.Category)
+  }
+
+  for i := range products {
+    products[i].Categories = categories[products[i].ID]
+    if products[i].Categories == nil {
+      products[i].Categories = make([]enCategory.Category, 0)
+    }
   }
 
   return nil
 }
 
+// DeleteProduct soft delete the product, it is hidden from the catalog but
+// stays resolvable from order history
 func (r *Repository) DeleteProduct(ctx context.Context, productID int64) error {
-  _, err := r.database.ExecContext(ctx, `
-    delete from products 
-    where id = $1
+  return r.mutate(ctx, productID, enProduct.AuditActionDelete, func(tx *sqlx.Tx) error {
+    _, err := tx.ExecContext(ctx, `
+      update products
+        set deleted_at = now()
+      where id = $1 and deleted_at is null
+    `, productID)
+    if err != nil {
+      log.Printf("[DeleteProduct] failed to delete product. err: %+v", err)
+    }
+    return err
+  })
+}
+
+func (r *Repository) RestoreProduct(ctx context.Context, productID int64) error {
+  return r.mutate(ctx, productID, enProduct.AuditActionRestore, func(tx *sqlx.Tx) error {
+    _, err := tx.ExecContext(ctx, `
+      update products
+        set deleted_at = null, updated_time = now()
+      where id = $1 and deleted_at is not null
+    `, productID)
+    if err != nil {
+      log.Printf("[RestoreProduct] failed to restore product. err: %+v", err)
+    }
+    return err
+  })
+}
+
+// PurgeProduct permanently remove a soft deleted product, its audit trail and
+// price history are kept
+func (r *Repository) PurgeProduct(ctx context.Context, productID int64) error {
+  return r.mutate(ctx, productID, enProduct.AuditActionPurge, func(tx *sqlx.Tx) error {
+    _, err := tx.ExecContext(ctx, `
+      delete from products
+      where id = $1 and deleted_at is not null
+    `, productID)
+    if err != nil {
+      log.Printf("[PurgeProduct] failed to purge product. err: %+v", err)
+    }
+    return err
+  })
+}
+
+// CountOrders return the number of transactions referring to the product
+func (r *Repository) CountOrders(ctx context.Context, productID int64) (int64, error) {
+  var count int64
+
+  err := r.database.QueryRowContext(ctx, `
+    select count(*) from transactions where product_id = $1
+  `, productID).Scan(&count)
+  if err != nil {
+    log.Printf("[CountOrders] failed to count orders of product %d. err: %+v", productID, err)
+    return 0, err
+  }
+
+  return count, nil
+}
+
+func (r *Repository) GetDeletedProduct(ctx context.Context, productID int64) (*enProduct.Product, error) {
+  product := &enProduct.Product{}
+
+  err := r.database.GetContext(ctx, product, `
+    select id, name, price, stock, sold, version, rating_average, rating_count, deleted_at
+    from products
+    where id = $1 and deleted_at is not null
   `, productID)
   if err != nil {
-    log.Printf("[InsertProduct] failed to insert product. err: %+v", err)
-    return err 
+    if err == sql.ErrNoRows {
+      return product, nil
+    }
+
+    log.Printf("[GetDeletedProduct] failed to get product. err: %+v", err)
+    return product, err
   }
 
-  key := fmt.Sprintf(productKey, productID)
+  products := []enProduct.Product{*product}
+  if err = r.attachRelations(ctx, products); err != nil {
+    return product, err
+  }
+
+  return &products[0], nil
+}
+
+func (r *Repository) GetDeletedProducts(ctx context.Context, cursor enPagination.Cursor, limit int) ([]enProduct.Product, error) {
+  products := make([]enProduct.Product, 0)
+
+  condition, order := cursorPkg.Keyset(cursor, "deleted_at", "id", 2)
+  args := []interface{}{limit + 1}
+  if !cursor.IsZero() {
+    args = append(args, time.UnixMicro(cursor.Key), cursor.ID)
+  }
 
-  err = r.redis.Del(key)
+  err := r.database.SelectContext(ctx, &products, fmt.Sprintf(`
+    select
+      id, name, price, stock, sold, version, rating_average, rating_count, deleted_at
+    from products
+    where deleted_at is not null and %s
+    order by %s
+    limit $1
+  `, condition, order), args...)
   if err != nil {
-    log.Printf("Failed to delete redis for key %s. err: %v", key, err)
+    log.Printf("[GetDeletedProducts] Failed to get deleted products. err: %+v", err)
+    return products, err
   }
 
-  return nil
+  if err = r.attachRelations(ctx, products); err != nil {
+    return products, err
+  }
 
+  return products, nil
 }
 
-func (r *Repository) GetProducts(ctx context.Context, limit, offset int) ([]enProduct.Product, error) {
+func (r *Repository) getProducts(ctx context.Context, cursor enPagination.Cursor, limit int, sort string) ([]enProduct.Product, error) {
   products := make([]enProduct.Product, 0)
 
-  err := r.database.SelectContext(ctx, &products, `
+  condition, order := cursorPkg.Keyset(cursor, sortKeys[sort], "id", 2)
+  args := []interface{}{limit + 1}
+  if !cursor.IsZero() {
+    args = append(args, cursor.Key, cursor.ID)
+  }
+
+  err := r.database.SelectContext(ctx, &products, fmt.Sprintf(`
     select
-      id, name, price, stock, "type", sold
+      id, name, price, stock, sold, version, rating_average, rating_count,
+      stock - coalesce((select sum(ws.reserved) from warehouse_stocks ws where ws.product_id = products.id), 0) as available
     from products
-    order by sold desc 
+    where deleted_at is null and %s
+    order by %s
     limit $1
-    offset $2
-  `, limit, offset)
+  `, condition, order), args...)
   if err != nil {
     if err == sql.ErrNoRows {
       return products, nil
@@ -144,21 +374,33 @@ func (r *Repository) GetProducts(ctx context.Context, limit, offset int) ([]enPr
     return products, err
   }
 
+  if err = r.attachRelations(ctx, products); err != nil {
+    return products, err
+  }
+
   return products, nil
 }
 
-func (r *Repository) GetProductsByType(ctx context.Context, limit, offset int, productType enProduct.ProductType) ([]enProduct.Product, error) {
+func (r *Repository) getProductsByCategory(ctx context.Context, cursor enPagination.Cursor, limit int, sort string, categoryIDs []int64) ([]enProduct.Product, error) {
   products := make([]enProduct.Product, 0)
 
-  err := r.database.SelectContext(ctx, &products, `
+  condition, order := cursorPkg.Keyset(cursor, sortKeys[sort], "id", 3)
+  args := []interface{}{limit + 1, pq.Array(categoryIDs)}
+  if !cursor.IsZero() {
+    args = append(args, cursor.Key, cursor.ID)
+  }
+
+  err := r.database.SelectContext(ctx, &products, fmt.Sprintf(`
     select
-      id, name, price, stock, "type", sold
+      id, name, price, stock, sold, version, rating_average, rating_count,
+      stock - coalesce((select sum(ws.reserved) from warehouse_stocks ws where ws.product_id = products.id), 0) as available
     from products
-    where "type"=$1
-    order by sold desc 
-    limit $2
-    offset $3
-  `, productType, limit, offset)
+    where deleted_at is null and id in (
+      select product_id from product_categories where category_id = any($2)
+    ) and %s
+    order by %s
+    limit $1
+  `, condition, order), args...)
   if err != nil {
     if err == sql.ErrNoRows {
       return products, nil
@@ -168,58 +410,92 @@ func (r *Repository) GetProductsByType(ctx context.Context, limit, offset int, p
     return products, err
   }
 
+  if err = r.attachRelations(ctx, products); err != nil {
+    return products, err
+  }
+
   return products, nil
 }
 
 func (r *Repository) GetProduct(ctx context.Context, productID int64) (*enProduct.Product, error) {
-  product := &enProduct.Product{}
-
   key := fmt.Sprintf(productKey, productID)
-  cache := r.redis.Get(key)
-  cacheByte, ok := cache.Value.([]byte)
-  if ok {
-    err := json.Unmarshal(cacheByte, product)
-    if err != nil {
-      log.Printf("[GetProduct] failed to unmarshal product")
-      return product, nil
+
+  product, found, err := cache.Fetch(ctx, r.productCache, key, func(ctx context.Context) (enProduct.Product, bool, error) {
+    products, err := r.loadProducts(ctx, []int64{productID})
+    if err != nil || len(products) == 0 {
+      return enProduct.Product{}, false, err
     }
+
+    return products[0], true, nil
+  })
+  if err != nil {
+    log.Printf("[GetProduct] failed to get product. err: %+v", err)
+    return &enProduct.Product{}, err
   }
 
-  err := r.database.GetContext(ctx, product, `
-    select * from products where id=$1
-  `, productID)
+  if !found {
+    return &enProduct.Product{}, nil
+  }
+
+  return &product, nil
+}
 
+// loadProducts read the detail of the given products from the database, in
+// one query per relation whatever the number of products. Deleted and
+// unknown products are left out, the order of productIDs is not kept.
+func (r *Repository) loadProducts(ctx context.Context, productIDs []int64) ([]enProduct.Product, error) {
+  products := make([]enProduct.Product, 0, len(productIDs))
+
+  err := r.database.SelectContext(ctx, &products, `
+    select id, name, price, stock, sold, version, rating_average, rating_count, updated_time,
+      stock - coalesce((select sum(ws.reserved) from warehouse_stocks ws where ws.product_id = products.id), 0) as available
+    from products where id = any($1) and deleted_at is null
+  `, pq.Array(productIDs))
   if err != nil {
-    if err == sql.ErrNoRows {
-      return product, nil
-    }
+    return products, err
+  }
 
-    log.Printf("[GetProduct] failed to get product. err: %+v", err)
-    return product, err
+  if err = r.attachRelations(ctx, products); err != nil {
+    return products, err
   }
 
-  productsByte, _ := json.Marshal(*product)
+  if err = r.attachVariants(ctx, products); err != nil {
+    return products, err
+  }
 
-  err = r.redis.Setex(key, expireTime, productsByte)
-  if err != nil {
-    log.Printf("Failed to get redis for key %s. err: %v", key, err)
+  if err = r.attachAvailability(ctx, products); err != nil {
+    return products, err
+  }
+
+  for i := range products {
+    products[i].Reserved = products[i].Stock - products[i].Available
   }
 
-  return product, nil
+  return products, nil
 }
 
-func (r *Repository) SearchProduct(ctx context.Context, query string, limit, offset int) ([]enProduct.Product, error) {
+// likeEscaper escape the wildcards of user input matched with like, the
+// queries declare backslash as escape character
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+func (r *Repository) searchProduct(ctx context.Context, query string, cursor enPagination.Cursor, limit int, sort string) ([]enProduct.Product, error) {
   products := make([]enProduct.Product, 0)
 
-  err := r.database.SelectContext(ctx, &products, `
+  condition, order := cursorPkg.Keyset(cursor, sortKeys[sort], "id", 3)
+  args := []interface{}{limit + 1, likeEscaper.Replace(query)}
+  if !cursor.IsZero() {
+    args = append(args, cursor.Key, cursor.ID)
+  }
+
+  err := r.database.SelectContext(ctx, &products, fmt.Sprintf(`
     select
-      id, name, price, stock, "type", sold
+      id, name, price, stock, sold, version, rating_average, rating_count,
+      stock - coalesce((select sum(ws.reserved) from warehouse_stocks ws where ws.product_id = products.id), 0) as available
     from products
-    where name ilike '%$1%'
-    order by sold desc 
-    limit $2
-    offset $3
-  `, query, limit, offset)
+    where deleted_at is null and lower(name) like '%%' || lower($2) || '%%' escape '\' and %s
+    order by %s
+    limit $1
+  `, condition, order), args...)
   if err != nil {
     if err == sql.ErrNoRows {
       return products, nil
@@ -229,5 +505,9 @@ func (r *Repository) SearchProduct(ctx context.Context, query string, limit, off
     return products, err
   }
 
+  if err = r.attachRelations(ctx, products); err != nil {
+    return products, err
+  }
+
   return products, nil
 }
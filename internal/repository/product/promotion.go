@@ -0,0 +1,99 @@
+package product
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	enProduct "ordent/internal/entity/product"
+	enPromotion "ordent/internal/entity/promotion"
+
+	"github.com/lib/pq"
+)
+
+// attachPromotions load the running promotions of the given products in one
+// query, directly or through one of their categories or its parents
+func (r *Repository) attachPromotions(ctx context.Context, products []enProduct.Product) error {
+  if len(products) == 0 {
+    return nil
+  }
+
+  productIDs := make([]int64, len(products))
+  for i, product := range products {
+    productIDs[i] = product.ID
+  }
+
+  links := make([]enPromotion.ProductPromotion, 0)
+  err := r.database.SelectContext(ctx, &links, `
+    with recursive tree as (
+      select distinct category_id as root, category_id as id from promotion_categories
+      union
+      select t.root, c.id from categories c
+      inner join tree t on c.parent_id = t.id
+    ), targets as (
+      select promotion_id, product_id from promotion_products
+      where product_id = any($1)
+      union
+      select pc.promotion_id, prc.product_id from promotion_categories pc
+      inner join tree t on t.root = pc.category_id
+      inner join product_categories prc on prc.category_id = t.id
+      where prc.product_id = any($1)
+    )
+    select tg.product_id, p.id, p.name, p.kind, p.percent_off, p.sale_price,
+      p.buy_quantity, p.free_quantity, p.starts_at, p.ends_at, p.active, p.state, p.created_time
+    from targets tg
+    inner join promotions p on p.id = tg.promotion_id
+    where p.active and p.starts_at <= now() and p.ends_at > now()
+    order by p.id
+  `, pq.Array(productIDs))
+  if err != nil {
+    log.Printf("[attachPromotions] failed to get product promotions. err: %+v", err)
+    return err
+  }
+
+  promotions := make(map[int64][]enPromotion.Promotion, len(products))
+  for _, link := range links {
+    promotions[link.ProductID] = append(promotions[link.ProductID], link.Promotion)
+  }
+
+  for i := range products {
+    products[i].Promotions = promotions[products[i].ID]
+    if products[i].Promotions == nil {
+      products[i].Promotions = make([]enPromotion.Promotion, 0)
+    }
+  }
+
+  return nil
+}
+
+// InvalidateProducts drop the cached detail of the given products, e.g. when
+// a promotion on them starts or ends. Their sale price changes, so their
+// updated_time moves as well for the Last-Modified of the product.
+func (r *Repository) InvalidateProducts(ctx context.Context, productIDs []int64) error {
+  if len(productIDs) == 0 {
+    return nil
+  }
+
+  _, err := r.database.ExecContext(ctx, `
+    update products set updated_time = now() where id = any($1)
+  `, pq.Array(productIDs))
+  if err != nil {
+    // the cache still has to go, only Last-Modified lags behind
+    log.Printf("[InvalidateProducts] failed to touch %d products. err: %+v", len(productIDs), err)
+  }
+
+  keys := make([]string, len(productIDs))
+  for i, productID := range productIDs {
+    keys[i] = fmt.Sprintf(productKey, productID)
+  }
+
+  err = r.redis.Del(keys...)
+  if err != nil {
+    log.Printf("[InvalidateProducts] failed to delete redis for %d products. err: %v", len(keys), err)
+    return err
+  }
+
+  r.invalidateLists()
+
+  return nil
+}
@@ -0,0 +1,127 @@
+package product
+
+import (
+	"context"
+	"log"
+
+	enProduct "ordent/internal/entity/product"
+
+	"github.com/lib/pq"
+)
+
+// EachBasket call fn with the distinct products bought by each customer,
+// the most recent maxBasket of them. Orders hold a single line, so products
+// of the same order are products of the same customer.
+func (r *Repository) EachBasket(ctx context.Context, maxBasket int, fn func(basket []int64)) error {
+  rows, err := r.database.QueryxContext(ctx, `
+    select user_id, product_id
+    from (
+      select t.user_id, t.product_id,
+        row_number() over (partition by t.user_id order by max(t.created_time) desc, t.product_id) as recency
+      from transactions t
+      inner join products p on p.id = t.product_id
+      where p.deleted_at is null
+      group by t.user_id, t.product_id
+    ) b
+    where recency <= $1
+    order by user_id
+  `, maxBasket)
+  if err != nil {
+    log.Printf("[EachBasket] failed to get purchases. err: %+v", err)
+    return err
+  }
+  defer rows.Close()
+
+  var (
+    current int64
+    basket  = make([]int64, 0)
+  )
+  for rows.Next() {
+    var userID, productID int64
+    if err = rows.Scan(&userID, &productID); err != nil {
+      log.Printf("[EachBasket] failed to scan purchase. err: %+v", err)
+      return err
+    }
+
+    if userID != current && len(basket) > 0 {
+      fn(basket)
+      basket = make([]int64, 0)
+    }
+    current = userID
+    basket = append(basket, productID)
+  }
+
+  if err = rows.Err(); err != nil {
+    log.Printf("[EachBasket] failed to read purchases. err: %+v", err)
+    return err
+  }
+
+  if len(basket) > 0 {
+    fn(basket)
+  }
+
+  return nil
+}
+
+// ReplaceRelations swap every stored relation for the given ones at once
+func (r *Repository) ReplaceRelations(ctx context.Context, relations []enProduct.Relation) error {
+  tx, err := r.database.BeginTxx(ctx, nil)
+  if err != nil {
+    log.Printf("[ReplaceRelations] failed to begin transaction. err: %+v", err)
+    return err
+  }
+  defer tx.Rollback()
+
+  _, err = tx.ExecContext(ctx, `delete from product_relations`)
+  if err != nil {
+    log.Printf("[ReplaceRelations] failed to clear relations. err: %+v", err)
+    return err
+  }
+
+  productIDs := make([]int64, len(relations))
+  relatedIDs := make([]int64, len(relations))
+  scores := make([]int64, len(relations))
+  ranks := make([]int64, len(relations))
+  for i, relation := range relations {
+    productIDs[i] = relation.ProductID
+    relatedIDs[i] = relation.RelatedID
+    scores[i] = relation.Score
+    ranks[i] = int64(relation.Rank)
+  }
+
+  _, err = tx.ExecContext(ctx, `
+    insert into product_relations (product_id, related_id, score, rank)
+    select unnest($1::bigint[]), unnest($2::bigint[]), unnest($3::bigint[]), unnest($4::bigint[])
+  `, pq.Array(productIDs), pq.Array(relatedIDs), pq.Array(scores), pq.Array(ranks))
+  if err != nil {
+    log.Printf("[ReplaceRelations] failed to insert relations. err: %+v", err)
+    return err
+  }
+
+  if err = tx.Commit(); err != nil {
+    log.Printf("[ReplaceRelations] failed to commit relations. err: %+v", err)
+    return err
+  }
+
+  return nil
+}
+
+// GetRelatedIDs return the products frequently bought with the product, best first
+func (r *Repository) GetRelatedIDs(ctx context.Context, productID int64, limit int) ([]int64, error) {
+  ids := make([]int64, 0)
+
+  err := r.database.SelectContext(ctx, &ids, `
+    select pr.related_id
+    from product_relations pr
+    inner join products p on p.id = pr.related_id
+    where pr.product_id = $1 and p.deleted_at is null
+    order by pr.rank
+    limit $2
+  `, productID, limit)
+  if err != nil {
+    log.Printf("[GetRelatedIDs] failed to get related products of %d. err: %+v", productID, err)
+    return ids, err
+  }
+
+  return ids, nil
+}
@@ -0,0 +1,214 @@
+package product
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"time"
+
+	enInventory "ordent/internal/entity/inventory"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// ReserveStock hold quantity of a product or SKU in the warehouse picked by
+// allocation until ttl passed. Concurrent reservations can not hold more than
+// the unreserved stock, the guarded update fails instead.
+func (r *Repository) ReserveStock(ctx context.Context, form enInventory.ReservationRequest, allocation enInventory.Allocation, ttl time.Duration) (*enInventory.Reservation, error) {
+  tx, err := r.database.BeginTxx(ctx, nil)
+  if err != nil {
+    log.Printf("[ReserveStock] failed to begin transaction. err: %+v", err)
+    return nil, err
+  }
+  defer tx.Rollback()
+
+  var variantID *int64
+  if form.VariantID != 0 {
+    variantID = &form.VariantID
+  }
+
+  warehouseID, err := allocateWarehouse(ctx, tx, form.ProductID, variantID, form.Quantity, allocation)
+  if err != nil {
+    log.Printf("[ReserveStock] failed to allocate product %d. err: %+v", form.ProductID, err)
+    return nil, err
+  }
+
+  result, err := tx.ExecContext(ctx, `
+    update warehouse_stocks
+      set reserved = reserved + $1
+    where warehouse_id = $2 and product_id = $3 and variant_id is not distinct from $4
+      and stock - reserved >= $1
+  `, form.Quantity, warehouseID, form.ProductID, variantID)
+  if err = checkStockUpdated(result, err); err != nil {
+    log.Printf("[ReserveStock] failed to reserve in warehouse %d. err: %+v", warehouseID, err)
+    return nil, err
+  }
+
+  reservation := &enInventory.Reservation{}
+  err = tx.GetContext(ctx, reservation, `
+    insert into stock_reservations
+      (user_id, product_id, variant_id, warehouse_id, quantity, expires_time)
+    values ($1, $2, $3, $4, $5, now() + $6 * interval '1 second')
+    returning id, user_id, product_id, variant_id, warehouse_id, quantity, status, expires_time, created_time
+  `, form.UserID, form.ProductID, variantID, warehouseID, form.Quantity, ttl.Seconds())
+  if err != nil {
+    log.Printf("[ReserveStock] failed to insert reservation. err: %+v", err)
+    return nil, err
+  }
+
+  if err = tx.Commit(); err != nil {
+    log.Printf("[ReserveStock] failed to commit reservation. err: %+v", err)
+    return nil, err
+  }
+
+  r.deleteProductCache(form.ProductID)
+
+  return reservation, nil
+}
+
+func (r *Repository) GetReservation(ctx context.Context, reservationID int64) (*enInventory.Reservation, error) {
+  reservation := &enInventory.Reservation{}
+
+  err := r.database.GetContext(ctx, reservation, `
+    select id, user_id, product_id, variant_id, warehouse_id, quantity, status, expires_time, created_time
+    from stock_reservations
+    where id = $1
+  `, reservationID)
+  if err != nil {
+    if err == sql.ErrNoRows {
+      return reservation, nil
+    }
+
+    log.Printf("[GetReservation] failed to get reservation. err: %+v", err)
+    return reservation, err
+  }
+
+  return reservation, nil
+}
+
+// ReleaseReservation give the stock of a held reservation back
+func (r *Repository) ReleaseReservation(ctx context.Context, reservationID int64) error {
+  tx, err := r.database.BeginTxx(ctx, nil)
+  if err != nil {
+    log.Printf("[ReleaseReservation] failed to begin transaction. err: %+v", err)
+    return err
+  }
+  defer tx.Rollback()
+
+  reservation, err := closeReservation(ctx, tx, reservationID, enInventory.ReservationReleased)
+  if err != nil {
+    log.Printf("[ReleaseReservation] failed to release reservation %d. err: %+v", reservationID, err)
+    return err
+  }
+
+  if err = tx.Commit(); err != nil {
+    log.Printf("[ReleaseReservation] failed to commit release. err: %+v", err)
+    return err
+  }
+
+  r.deleteProductCache(reservation.ProductID)
+
+  return nil
+}
+
+// ReleaseExpiredReservations expire up to limit overdue holds and return how
+// many were released. Rows locked by a running checkout are skipped, so
+// several sweepers can run at once.
+func (r *Repository) ReleaseExpiredReservations(ctx context.Context, limit int) (int, error) {
+  tx, err := r.database.BeginTxx(ctx, nil)
+  if err != nil {
+    log.Printf("[ReleaseExpiredReservations] failed to begin transaction. err: %+v", err)
+    return 0, err
+  }
+  defer tx.Rollback()
+
+  ids := make([]int64, 0)
+  err = tx.SelectContext(ctx, &ids, `
+    select id from stock_reservations
+    where status = $1 and expires_time <= now()
+    order by expires_time
+    limit $2
+    for update skip locked
+  `, enInventory.ReservationHeld, limit)
+  if err != nil {
+    log.Printf("[ReleaseExpiredReservations] failed to find expired reservations. err: %+v", err)
+    return 0, err
+  }
+
+  products := make(map[int64]bool)
+  for _, id := range ids {
+    reservation, err := closeReservation(ctx, tx, id, enInventory.ReservationExpired)
+    if err != nil {
+      log.Printf("[ReleaseExpiredReservations] failed to expire reservation %d. err: %+v", id, err)
+      return 0, err
+    }
+    products[reservation.ProductID] = true
+  }
+
+  if err = tx.Commit(); err != nil {
+    log.Printf("[ReleaseExpiredReservations] failed to commit release. err: %+v", err)
+    return 0, err
+  }
+
+  for productID := range products {
+    r.deleteProductCache(productID)
+  }
+
+  return len(ids), nil
+}
+
+// closeReservation move a held reservation to status and give its stock back
+func closeReservation(ctx context.Context, tx *sqlx.Tx, reservationID int64, status string) (*enInventory.Reservation, error) {
+  reservation := &enInventory.Reservation{}
+
+  err := tx.GetContext(ctx, reservation, `
+    update stock_reservations
+      set status = $1, updated_time = now()
+    where id = $2 and status = $3
+    returning id, user_id, product_id, variant_id, warehouse_id, quantity, status, expires_time, created_time
+  `, status, reservationID, enInventory.ReservationHeld)
+  if err != nil {
+    if err == sql.ErrNoRows {
+      return nil, enInventory.ErrReservationNotHeld
+    }
+    return nil, err
+  }
+
+  _, err = tx.ExecContext(ctx, `
+    update warehouse_stocks
+      set reserved = reserved - $1
+    where warehouse_id = $2 and product_id = $3 and variant_id is not distinct from $4
+  `, reservation.Quantity, reservation.WarehouseID, reservation.ProductID, reservation.VariantID)
+  if err != nil {
+    return nil, err
+  }
+
+  return reservation, nil
+}
+
+// commitReservation close the reservation fulfilling a sale and return its
+// warehouse, it has to be held, unexpired and match the line
+func commitReservation(ctx context.Context, tx *sqlx.Tx, reservationID, productID int64, variantID *int64, quantity int64) (int64, error) {
+  reservation, err := closeReservation(ctx, tx, reservationID, enInventory.ReservationCommitted)
+  if err != nil {
+    return 0, err
+  }
+
+  if time.Now().After(reservation.ExpiresTime) {
+    return 0, enInventory.ErrReservationNotHeld
+  }
+
+  if reservation.ProductID != productID || reservation.Quantity != quantity || !sameVariant(reservation.VariantID, variantID) {
+    return 0, errors.New("reservation does not match the order")
+  }
+
+  return reservation.WarehouseID, nil
+}
+
+func sameVariant(a, b *int64) bool {
+  if a == nil || b == nil {
+    return a == b
+  }
+  return *a == *b
+}
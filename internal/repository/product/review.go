@@ -0,0 +1,193 @@
+package product
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+
+	enPagination "ordent/internal/entity/pagination"
+	enProduct "ordent/internal/entity/product"
+	cursorPkg "ordent/internal/pkg/cursor"
+
+	"github.com/jmoiron/sqlx"
+)
+
+const reviewColumns = `
+  r.id, r.product_id, r.user_id, coalesce(u.username, '') as username, r.rating, r.body,
+  r.status, r.moderation_note, r.created_time, r.updated_time
+`
+
+// HasPurchased check the user has a recorded order of the product, orders
+// are only recorded once their stock is taken
+func (r *Repository) HasPurchased(ctx context.Context, userID, productID int64) (bool, error) {
+  var purchased bool
+
+  err := r.database.QueryRowContext(ctx, `
+    select exists (
+      select 1 from transactions where user_id = $1 and product_id = $2
+    )
+  `, userID, productID).Scan(&purchased)
+  if err != nil {
+    log.Printf("[HasPurchased] failed to check orders of user %d. err: %+v", userID, err)
+    return false, err
+  }
+
+  return purchased, nil
+}
+
+// UpsertReview create the review of the user or replace it, either way it
+// goes back to moderation and leaves the rating until approved
+func (r *Repository) UpsertReview(ctx context.Context, form enProduct.ReviewRequest) (int64, error) {
+  var id int64
+
+  err := r.withRating(ctx, form.ProductID, func(tx *sqlx.Tx) error {
+    return tx.QueryRowContext(ctx, `
+      insert into product_reviews
+        (product_id, user_id, rating, body)
+      values ($1, $2, $3, $4)
+      on conflict (product_id, user_id) do update
+        set rating = excluded.rating, body = excluded.body, status = 'pending',
+        moderation_note = '', moderated_by = null, updated_time = now()
+      returning id
+    `, form.ProductID, form.UserID, form.Rating, form.Body).Scan(&id)
+  })
+  if err != nil {
+    log.Printf("[UpsertReview] failed to save review of product %d. err: %+v", form.ProductID, err)
+    return 0, err
+  }
+
+  return id, nil
+}
+
+// ModerateReview set the status of a review and refresh the rating of its product
+func (r *Repository) ModerateReview(ctx context.Context, productID int64, form enProduct.ModerationRequest) error {
+  err := r.withRating(ctx, productID, func(tx *sqlx.Tx) error {
+    _, err := tx.ExecContext(ctx, `
+      update product_reviews
+        set status = $1, moderation_note = $2, moderated_by = $3, updated_time = now()
+      where id = $4
+    `, form.Status, form.Note, form.ModeratorID, form.ID)
+    return err
+  })
+  if err != nil {
+    log.Printf("[ModerateReview] failed to moderate review %d. err: %+v", form.ID, err)
+    return err
+  }
+
+  return nil
+}
+
+// withRating run fn and recompute the rating aggregate of the product from
+// its approved reviews in the same transaction. The rating is not a product
+// change, it is neither versioned nor audited, it only moves updated_time so
+// the Last-Modified of the product follows.
+func (r *Repository) withRating(ctx context.Context, productID int64, fn func(tx *sqlx.Tx) error) error {
+  tx, err := r.database.BeginTxx(ctx, nil)
+  if err != nil {
+    return err
+  }
+  defer tx.Rollback()
+
+  if err = fn(tx); err != nil {
+    return err
+  }
+
+  _, err = tx.ExecContext(ctx, `
+    update products p
+      set rating_average = coalesce(a.average, 0), rating_count = a.count, updated_time = now()
+    from (
+      select round(avg(rating), 2) as average, count(*) as count
+      from product_reviews
+      where product_id = $1 and status = 'approved'
+    ) a
+    where p.id = $1
+      and (p.rating_average, p.rating_count) is distinct from (coalesce(a.average, 0), a.count)
+  `, productID)
+  if err != nil {
+    return err
+  }
+
+  if err = tx.Commit(); err != nil {
+    return err
+  }
+
+  r.deleteProductCache(productID)
+
+  return nil
+}
+
+func (r *Repository) GetReview(ctx context.Context, reviewID int64) (*enProduct.Review, error) {
+  review := &enProduct.Review{}
+
+  err := r.database.GetContext(ctx, review, fmt.Sprintf(`
+    select %s
+    from product_reviews r
+    left join users u on u.id = r.user_id
+    where r.id = $1
+  `, reviewColumns), reviewID)
+  if err != nil {
+    if err == sql.ErrNoRows {
+      return review, nil
+    }
+
+    log.Printf("[GetReview] failed to get review. err: %+v", err)
+    return review, err
+  }
+
+  return review, nil
+}
+
+// GetReviews list the reviews of a product with the given status, newest first
+func (r *Repository) GetReviews(ctx context.Context, productID int64, status string, cursor enPagination.Cursor, limit int) ([]enProduct.Review, error) {
+  reviews := make([]enProduct.Review, 0)
+
+  condition, order := cursorPkg.Keyset(cursor, "r.created_time", "r.id", 4)
+  args := []interface{}{limit + 1, productID, status}
+  if !cursor.IsZero() {
+    args = append(args, time.UnixMicro(cursor.Key), cursor.ID)
+  }
+
+  err := r.database.SelectContext(ctx, &reviews, fmt.Sprintf(`
+    select %s
+    from product_reviews r
+    left join users u on u.id = r.user_id
+    where r.product_id = $2 and r.status = $3 and %s
+    order by %s
+    limit $1
+  `, reviewColumns, condition, order), args...)
+  if err != nil {
+    log.Printf("[GetReviews] failed to get reviews of product %d. err: %+v", productID, err)
+    return reviews, err
+  }
+
+  return reviews, nil
+}
+
+// GetReviewsByStatus list the reviews of every product with the given
+// status, e.g. the moderation queue
+func (r *Repository) GetReviewsByStatus(ctx context.Context, status string, cursor enPagination.Cursor, limit int) ([]enProduct.Review, error) {
+  reviews := make([]enProduct.Review, 0)
+
+  condition, order := cursorPkg.Keyset(cursor, "r.created_time", "r.id", 3)
+  args := []interface{}{limit + 1, status}
+  if !cursor.IsZero() {
+    args = append(args, time.UnixMicro(cursor.Key), cursor.ID)
+  }
+
+  err := r.database.SelectContext(ctx, &reviews, fmt.Sprintf(`
+    select %s
+    from product_reviews r
+    left join users u on u.id = r.user_id
+    where r.status = $2 and %s
+    order by %s
+    limit $1
+  `, reviewColumns, condition, order), args...)
+  if err != nil {
+    log.Printf("[GetReviewsByStatus] failed to get %s reviews. err: %+v", status, err)
+    return reviews, err
+  }
+
+  return reviews, nil
+}
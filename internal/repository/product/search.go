@@ -0,0 +1,95 @@
+package product
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	enPagination "ordent/internal/entity/pagination"
+	enProduct "ordent/internal/entity/product"
+	cursorPkg "ordent/internal/pkg/cursor"
+)
+
+// SuggestNames complete the prefix with product names, names starting with
+// it first, then names containing it, best selling first
+func (r *Repository) SuggestNames(ctx context.Context, prefix string, limit int) ([]string, error) {
+  names := make([]string, 0)
+
+  err := r.database.SelectContext(ctx, &names, `
+    select name
+    from products
+    where deleted_at is null and lower(name) like '%' || lower($1) || '%' escape '\'
+    order by lower(name) like lower($1) || '%' escape '\' desc, sold desc, id
+    limit $2
+  `, likeEscaper.Replace(prefix), limit)
+  if err != nil {
+    log.Printf("[SuggestNames] failed to complete %q. err: %+v", prefix, err)
+    return names, err
+  }
+
+  return names, nil
+}
+
+// SuggestQueries complete the prefix with the most searched queries that
+// found products the last time
+func (r *Repository) SuggestQueries(ctx context.Context, prefix string, limit int) ([]string, error) {
+  queries := make([]string, 0)
+
+  err := r.database.SelectContext(ctx, &queries, `
+    select query
+    from search_queries
+    where query like $1 || '%' escape '\' and last_results > 0
+    order by searches desc, query
+    limit $2
+  `, likeEscaper.Replace(prefix), limit)
+  if err != nil {
+    log.Printf("[SuggestQueries] failed to complete %q. err: %+v", prefix, err)
+    return queries, err
+  }
+
+  return queries, nil
+}
+
+// LogSearch count a search of the normalized query and how many products it found
+func (r *Repository) LogSearch(ctx context.Context, query string, results int) error {
+  _, err := r.database.ExecContext(ctx, `
+    insert into search_queries
+      (query, searches, zero_results, last_results)
+    values ($1, 1, case when $2 = 0 then 1 else 0 end, $2)
+    on conflict (query) do update
+      set searches = search_queries.searches + 1,
+      zero_results = search_queries.zero_results + excluded.zero_results,
+      last_results = excluded.last_results, last_searched_time = now()
+  `, query, results)
+  if err != nil {
+    log.Printf("[LogSearch] failed to log search %q. err: %+v", query, err)
+    return err
+  }
+
+  return nil
+}
+
+// GetZeroResultQueries list the queries that found nothing, most often first
+func (r *Repository) GetZeroResultQueries(ctx context.Context, cursor enPagination.Cursor, limit int) ([]enProduct.SearchQuery, error) {
+  queries := make([]enProduct.SearchQuery, 0)
+
+  condition, order := cursorPkg.Keyset(cursor, "zero_results", "id", 2)
+  args := []interface{}{limit + 1}
+  if !cursor.IsZero() {
+    args = append(args, cursor.Key, cursor.ID)
+  }
+
+  err := r.database.SelectContext(ctx, &queries, fmt.Sprintf(`
+    select id, query, searches, zero_results, last_results, last_searched_time
+    from search_queries
+    where zero_results > 0 and %s
+    order by %s
+    limit $1
+  `, condition, order), args...)
+  if err != nil {
+    log.Printf("[GetZeroResultQueries] failed to get queries. err: %+v", err)
+    return queries, err
+  }
+
+  return queries, nil
+}
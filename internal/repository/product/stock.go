@@ -0,0 +1,420 @@
+package product
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	enInventory "ordent/internal/entity/inventory"
+	enPagination "ordent/internal/entity/pagination"
+	enProduct "ordent/internal/entity/product"
+	enUser "ordent/internal/entity/user"
+	cursorPkg "ordent/internal/pkg/cursor"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// AdjustStock apply a signed delta to a product, or to one of its SKUs when
+// variantID is set, in one warehouse and log it as stock movement and audit
+func (r *Repository) AdjustStock(ctx context.Context, form enInventory.AdjustmentRequest) (*enInventory.Movement, error) {
+  var movement *enInventory.Movement
+
+  err := r.mutate(ctx, form.ProductID, enProduct.AuditActionAdjustStock, func(tx *sqlx.Tx) error {
+    var err error
+
+    warehouseID := form.WarehouseID
+    if warehouseID == 0 {
+      warehouseID, err = defaultWarehouse(ctx, tx)
+      if err != nil {
+        log.Printf("[AdjustStock] failed to get default warehouse. err: %+v", err)
+        return err
+      }
+    }
+
+    var variantID *int64
+    if form.VariantID != 0 {
+      variantID = &form.VariantID
+
+      result, err := tx.ExecContext(ctx, `
+        update product_variants
+         set stock=stock+$1, updated_time=now()
+        where id = $2 and product_id = $3 and stock+$1 >= 0
+      `, form.Delta, form.VariantID, form.ProductID)
+      if err = checkStockUpdated(result, err); err != nil {
+        log.Printf("[AdjustStock] failed to update variant %d. err: %+v", form.VariantID, err)
+        return err
+      }
+    }
+
+    result, err := tx.ExecContext(ctx, `
+      update products
+       set stock=stock+$1, updated_time=now()
+      where id = $2 and stock+$1 >= 0 and deleted_at is null
+    `, form.Delta, form.ProductID)
+    if err = checkStockUpdated(result, err); err != nil {
+      log.Printf("[AdjustStock] failed to update product %d. err: %+v", form.ProductID, err)
+      return err
+    }
+
+    if err = moveWarehouseStock(ctx, tx, warehouseID, form.ProductID, variantID, form.Delta); err != nil {
+      log.Printf("[AdjustStock] failed to update warehouse %d. err: %+v", warehouseID, err)
+      return err
+    }
+
+    movement, err = recordMovement(ctx, tx, form.ProductID, variantID, warehouseID, form.Delta, form.Reason, form.Note)
+    if err != nil {
+      log.Printf("[AdjustStock] failed to record movement. err: %+v", err)
+      return err
+    }
+
+    return nil
+  })
+  if err != nil {
+    return nil, err
+  }
+
+  return movement, nil
+}
+
+// recordMovement log a stock change, it must run inside the transaction that
+// changed the stock. Zero deltas are not logged.
+func recordMovement(ctx context.Context, tx *sqlx.Tx, productID int64, variantID *int64, warehouseID, delta int64, reason, note string) (*enInventory.Movement, error) {
+  movement := &enInventory.Movement{}
+  if delta == 0 {
+    return movement, nil
+  }
+
+  var userID *int64
+  username := ""
+  if sess, ok := enUser.SessionFromContext(ctx); ok {
+    userID = &sess.ID
+    username = sess.Username
+  }
+
+  err := tx.GetContext(ctx, movement, `
+    insert into stock_movements
+      (product_id, variant_id, warehouse_id, delta, reason, note, user_id, username)
+    values ($1, $2, $3, $4, $5, $6, $7, $8)
+    returning id, product_id, variant_id, warehouse_id, delta, reason, note, user_id, username, created_time
+  `, productID, variantID, warehouseID, delta, reason, note, userID, username)
+  if err != nil {
+    return nil, err
+  }
+
+  return movement, nil
+}
+
+func (r *Repository) GetStockMovements(ctx context.Context, productID int64, cursor enPagination.Cursor, limit int) ([]enInventory.Movement, error) {
+  movements := make([]enInventory.Movement, 0)
+
+  condition, order := cursorPkg.Keyset(cursor, "created_time", "id", 3)
+  args := []interface{}{limit + 1, productID}
+  if !cursor.IsZero() {
+    args = append(args, time.UnixMicro(cursor.Key), cursor.ID)
+  }
+
+  err := r.database.SelectContext(ctx, &movements, fmt.Sprintf(`
+    select id, product_id, variant_id, warehouse_id, delta, reason, note, user_id, username, created_time
+    from stock_movements
+    where product_id = $2 and %s
+    order by %s
+    limit $1
+  `, condition, order), args...)
+  if err != nil {
+    log.Printf("[GetStockMovements] failed to get movements of product %d. err: %+v", productID, err)
+    return movements, err
+  }
+
+  return movements, nil
+}
+
+// ReconcileStock sum the movements and the warehouse stocks of every product
+// without variants and of every SKU next to its stored stock. productID 0
+// check the whole catalog.
+func (r *Repository) ReconcileStock(ctx context.Context, productID int64) ([]enInventory.Reconciliation, error) {
+  rows := make([]enInventory.Reconciliation, 0)
+
+  err := r.database.SelectContext(ctx, &rows, `
+    select p.id as product_id, null::bigint as variant_id, '' as sku, p.stock,
+      coalesce(m.total, 0) as movement_total, coalesce(w.total, 0) as warehouse_total
+    from products p
+    left join (
+      select product_id, sum(delta) as total
+      from stock_movements
+      where variant_id is null
+      group by product_id
+    ) m on m.product_id = p.id
+    left join (
+      select product_id, sum(stock) as total
+      from warehouse_stocks
+      where variant_id is null
+      group by product_id
+    ) w on w.product_id = p.id
+    where p.deleted_at is null and ($1 = 0 or p.id = $1)
+      and not exists (select 1 from product_variants v where v.product_id = p.id)
+    union all
+    select v.product_id, v.id, v.sku, v.stock, coalesce(m.total, 0), coalesce(w.total, 0)
+    from product_variants v
+    join products p on p.id = v.product_id and p.deleted_at is null
+    left join (
+      select variant_id, sum(delta) as total
+      from stock_movements
+      where variant_id is not null
+      group by variant_id
+    ) m on m.variant_id = v.id
+    left join (
+      select variant_id, sum(stock) as total
+      from warehouse_stocks
+      where variant_id is not null
+      group by variant_id
+    ) w on w.variant_id = v.id
+    where $1 = 0 or v.product_id = $1
+    order by product_id, variant_id nulls first
+  `, productID)
+  if err != nil {
+    log.Printf("[ReconcileStock] failed to reconcile stock. err: %+v", err)
+    return rows, err
+  }
+
+  for i := range rows {
+    rows[i].Difference = rows[i].Stock - rows[i].MovementTotal
+  }
+
+  return rows, nil
+}
+
+// defaultWarehouse is the active warehouse with the best priority, it
+// receives opening and imported stock
+func defaultWarehouse(ctx context.Context, tx *sqlx.Tx) (int64, error) {
+  var id int64
+
+  err := tx.QueryRowContext(ctx, `
+    select id from warehouses
+    where active
+    order by priority, id
+    limit 1
+  `).Scan(&id)
+  if err != nil {
+    if err == sql.ErrNoRows {
+      return 0, errors.New("no active warehouse")
+    }
+    return 0, err
+  }
+
+  return id, nil
+}
+
+// moveDefaultStock book stock changes that do not name a warehouse, opening
+// and imported stock, on the default warehouse and log them
+func moveDefaultStock(ctx context.Context, tx *sqlx.Tx, productID int64, variantID *int64, delta int64, reason, note string) error {
+  if delta == 0 {
+    return nil
+  }
+
+  warehouseID, err := defaultWarehouse(ctx, tx)
+  if err != nil {
+    return err
+  }
+
+  if err = moveWarehouseStock(ctx, tx, warehouseID, productID, variantID, delta); err != nil {
+    return err
+  }
+
+  _, err = recordMovement(ctx, tx, productID, variantID, warehouseID, delta, reason, note)
+  return err
+}
+
+// moveWarehouseStock apply delta to the stock of a product or SKU in one
+// warehouse, taking more than the unreserved stock is insufficient stock
+func moveWarehouseStock(ctx context.Context, tx *sqlx.Tx, warehouseID, productID int64, variantID *int64, delta int64) error {
+  if delta == 0 {
+    return nil
+  }
+
+  if delta > 0 {
+    _, err := tx.ExecContext(ctx, `
+      insert into warehouse_stocks
+        (warehouse_id, product_id, variant_id, stock)
+      values ($1, $2, $3, $4)
+      on conflict (warehouse_id, product_id, (coalesce(variant_id, 0))) do update
+        set stock = warehouse_stocks.stock + excluded.stock
+    `, warehouseID, productID, variantID, delta)
+    return err
+  }
+
+  result, err := tx.ExecContext(ctx, `
+    update warehouse_stocks
+      set stock = stock + $1
+    where warehouse_id = $2 and product_id = $3 and variant_id is not distinct from $4
+      and stock - reserved + $1 >= 0
+  `, delta, warehouseID, productID, variantID)
+  return checkStockUpdated(result, err)
+}
+
+// allocationOrder rank the warehouses able to fulfil a line, the location
+// placeholders are $4 and $5
+var allocationOrder = map[string]string{
+  enInventory.AllocationPriority:  "w.priority, w.id",
+  enInventory.AllocationMostStock: "ws.stock desc, w.priority, w.id",
+  enInventory.AllocationNearest:   "w.latitude is null, power(w.latitude - $4, 2) + power((w.longitude - $5) * cos(radians($4)), 2), w.priority, w.id",
+}
+
+// allocateWarehouse pick the warehouse shipping the whole quantity of a line
+// and lock its stock row
+func allocateWarehouse(ctx context.Context, tx *sqlx.Tx, productID int64, variantID *int64, quantity int64, allocation enInventory.Allocation) (int64, error) {
+  strategy := allocation.Strategy
+  args := []interface{}{productID, variantID, quantity}
+
+  if strategy == enInventory.AllocationNearest && (allocation.Latitude == nil || allocation.Longitude == nil) {
+    strategy = enInventory.AllocationPriority
+  }
+
+  order, ok := allocationOrder[strategy]
+  if !ok {
+    order = allocationOrder[enInventory.AllocationPriority]
+  }
+
+  if strategy == enInventory.AllocationNearest {
+    args = append(args, *allocation.Latitude, *allocation.Longitude)
+  }
+
+  var warehouseID int64
+  err := tx.QueryRowContext(ctx, fmt.Sprintf(`
+    select ws.warehouse_id
+    from warehouse_stocks ws
+    join warehouses w on w.id = ws.warehouse_id and w.active
+    where ws.product_id = $1 and ws.variant_id is not distinct from $2 and ws.stock - ws.reserved >= $3
+    order by %s
+    limit 1
+    for update of ws
+  `, order), args...).Scan(&warehouseID)
+  if err != nil {
+    if err == sql.ErrNoRows {
+      return 0, enProduct.ErrInsufficientStock
+    }
+    return 0, err
+  }
+
+  return warehouseID, nil
+}
+
+// attachAvailability load the stock of the given products and their SKUs per
+// warehouse in one query, reserved stock excluded
+func (r *Repository) attachAvailability(ctx context.Context, products []enProduct.Product) error {
+  if len(products) == 0 {
+    return nil
+  }
+
+  productIDs := make([]int64, len(products))
+  for i, product := range products {
+    productIDs[i] = product.ID
+  }
+
+  rows := make([]struct {
+    ProductID int64 `db:"product_id"`
+    enProduct.Availability
+  }, 0)
+  err := r.database.SelectContext(ctx, &rows, `
+    select ws.product_id, ws.warehouse_id, w.name as warehouse, ws.variant_id, ws.stock - ws.reserved as stock
+    from warehouse_stocks ws
+    join warehouses w on w.id = ws.warehouse_id and w.active
+    where ws.product_id = any($1) and ws.stock > ws.reserved
+    order by ws.variant_id nulls first, w.priority, w.id
+  `, pq.Array(productIDs))
+  if err != nil {
+    log.Printf("[attachAvailability] failed to get availability. err: %+v", err)
+    return err
+  }
+
+  byProduct := make(map[int64][]enProduct.Availability, len(products))
+  for _, row := range rows {
+    byProduct[row.ProductID] = append(byProduct[row.ProductID], row.Availability)
+  }
+
+  for i := range products {
+    products[i].Availability = byProduct[products[i].ID]
+    if products[i].Availability == nil {
+      products[i].Availability = make([]enProduct.Availability, 0)
+    }
+  }
+
+  return nil
+}
+
+func (r *Repository) SetReorderThreshold(ctx context.Context, productID, threshold int64) error {
+  return r.mutate(ctx, productID, enProduct.AuditActionSetThreshold, func(tx *sqlx.Tx) error {
+    _, err := tx.ExecContext(ctx, `
+      update products
+        set reorder_threshold = $1, updated_time = now()
+      where id = $2 and deleted_at is null
+    `, threshold, productID)
+    if err != nil {
+      log.Printf("[SetReorderThreshold] failed to update threshold. err: %+v", err)
+      return err
+    }
+
+    return nil
+  })
+}
+
+// MarkLowStock flag the product when its stock dropped below the reorder
+// threshold and return it, so concurrent sales only alert once. Products back
+// above the threshold are unflagged and return nil like products that were
+// already alerted.
+func (r *Repository) MarkLowStock(ctx context.Context, productID int64) (*enInventory.LowStock, error) {
+  var (
+    lowStock enInventory.LowStock
+    alerted  bool
+  )
+
+  err := r.database.QueryRowContext(ctx, `
+    update products
+      set low_stock_alerted = stock < reorder_threshold
+    where id = $1 and low_stock_alerted <> (stock < reorder_threshold)
+    returning id, name, stock, reorder_threshold, reorder_threshold - stock, low_stock_alerted
+  `, productID).Scan(&lowStock.ProductID, &lowStock.Name, &lowStock.Stock, &lowStock.Threshold, &lowStock.Shortfall, &alerted)
+  if err != nil {
+    if err == sql.ErrNoRows {
+      return nil, nil
+    }
+
+    log.Printf("[MarkLowStock] failed to check product %d. err: %+v", productID, err)
+    return nil, err
+  }
+
+  if !alerted {
+    return nil, nil
+  }
+
+  return &lowStock, nil
+}
+
+// GetLowStockProducts list products below their reorder threshold, the
+// largest shortfall first
+func (r *Repository) GetLowStockProducts(ctx context.Context, cursor enPagination.Cursor, limit int) ([]enInventory.LowStock, error) {
+  products := make([]enInventory.LowStock, 0)
+
+  condition, order := cursorPkg.Keyset(cursor, "(reorder_threshold - stock)", "id", 2)
+  args := []interface{}{limit + 1}
+  if !cursor.IsZero() {
+    args = append(args, cursor.Key, cursor.ID)
+  }
+
+  err := r.database.SelectContext(ctx, &products, fmt.Sprintf(`
+    select id, name, stock, reorder_threshold, reorder_threshold - stock as shortfall
+    from products
+    where stock < reorder_threshold and deleted_at is null and %s
+    order by %s
+    limit $1
+  `, condition, order), args...)
+  if err != nil {
+    log.Printf("[GetLowStockProducts] failed to get low stock products. err: %+v", err)
+    return products, err
+  }
+
+  return products, nil
+}
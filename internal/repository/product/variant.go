@@ -0,0 +1,349 @@
+package product
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+
+	enInventory "ordent/internal/entity/inventory"
+	enProduct "ordent/internal/entity/product"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+func (r *Repository) InsertVariant(ctx context.Context, form enProduct.VariantRequest) (int64, error) {
+  var id int64
+
+  err := r.mutate(ctx, form.ProductID, enProduct.AuditActionInsertVariant, func(tx *sqlx.Tx) error {
+    err := tx.QueryRowContext(ctx, `
+      insert into product_variants
+        (product_id, sku, options, price, stock)
+      values ($1, $2, $3, $4, $5)
+      returning id
+    `, form.ProductID, form.SKU, form.Options, form.Price, form.Stock).Scan(&id)
+    if err != nil {
+      log.Printf("[InsertVariant] failed to insert variant. err: %+v", err)
+      return err
+    }
+
+    if err = moveDefaultStock(ctx, tx, form.ProductID, &id, form.Stock, enInventory.ReasonInitial, ""); err != nil {
+      log.Printf("[InsertVariant] failed to record initial stock. err: %+v", err)
+      return err
+    }
+
+    if err = transferProductStock(ctx, tx, form.ProductID, id); err != nil {
+      log.Printf("[InsertVariant] failed to move product stock to variant %d. err: %+v", id, err)
+      return err
+    }
+
+    if err = syncVariantTotals(ctx, tx, form.ProductID); err != nil {
+      log.Printf("[InsertVariant] failed to sync product stock. err: %+v", err)
+      return err
+    }
+
+    return nil
+  })
+  if err != nil {
+    return 0, err
+  }
+
+  return id, nil
+}
+
+func (r *Repository) UpdateVariant(ctx context.Context, form enProduct.VariantRequest) error {
+  return r.mutate(ctx, form.ProductID, enProduct.AuditActionUpdateVariant, func(tx *sqlx.Tx) error {
+    _, err := tx.ExecContext(ctx, `
+      update product_variants
+        set sku=$1, options=$2, price=$3, updated_time=now()
+      where id = $4
+    `, form.SKU, form.Options, form.Price, form.ID)
+    if err != nil {
+      log.Printf("[UpdateVariant] failed to update variant. err: %+v", err)
+      return err
+    }
+
+    return nil
+  })
+}
+
+func (r *Repository) DeleteVariant(ctx context.Context, variant enProduct.Variant) error {
+  return r.mutate(ctx, variant.ProductID, enProduct.AuditActionDeleteVariant, func(tx *sqlx.Tx) error {
+    _, err := tx.ExecContext(ctx, `
+      delete from product_variants
+      where id = $1
+    `, variant.ID)
+    if err != nil {
+      log.Printf("[DeleteVariant] failed to delete variant. err: %+v", err)
+      return err
+    }
+
+    if err = syncVariantTotals(ctx, tx, variant.ProductID); err != nil {
+      log.Printf("[DeleteVariant] failed to sync product stock. err: %+v", err)
+      return err
+    }
+
+    return nil
+  })
+}
+
+func (r *Repository) GetVariant(ctx context.Context, variantID int64) (*enProduct.Variant, error) {
+  variant := &enProduct.Variant{}
+
+  err := r.database.GetContext(ctx, variant, `
+    select id, product_id, sku, options, price, stock, sold
+    from product_variants
+    where id = $1
+  `, variantID)
+  if err != nil {
+    if err == sql.ErrNoRows {
+      return variant, nil
+    }
+
+    log.Printf("[GetVariant] failed to get variant. err: %+v", err)
+    return variant, err
+  }
+
+  return variant, nil
+}
+
+func (r *Repository) GetVariantBySKU(ctx context.Context, sku string) (*enProduct.Variant, error) {
+  variant := &enProduct.Variant{}
+
+  err := r.database.GetContext(ctx, variant, `
+    select id, product_id, sku, options, price, stock, sold
+    from product_variants
+    where sku = $1
+  `, sku)
+  if err != nil {
+    if err == sql.ErrNoRows {
+      return variant, nil
+    }
+
+    log.Printf("[GetVariantBySKU] failed to get variant. err: %+v", err)
+    return variant, err
+  }
+
+  return variant, nil
+}
+
+// attachVariants load the variants of the given products in one query
+func (r *Repository) attachVariants(ctx context.Context, products []enProduct.Product) error {
+  if len(products) == 0 {
+    return nil
+  }
+
+  productIDs := make([]int64, len(products))
+  for i, product := range products {
+    productIDs[i] = product.ID
+  }
+
+  variants := make([]enProduct.Variant, 0)
+  err := r.database.SelectContext(ctx, &variants, `
+    select id, product_id, sku, options, price, stock, sold,
+      coalesce((select sum(ws.reserved) from warehouse_stocks ws where ws.variant_id = product_variants.id), 0) as reserved
+    from product_variants
+    where product_id = any($1)
+    order by id
+  `, pq.Array(productIDs))
+  if err != nil {
+    log.Printf("[attachVariants] failed to get variants. err: %+v", err)
+    return err
+  }
+
+  byProduct := make(map[int64][]enProduct.Variant, len(products))
+  for _, variant := range variants {
+    byProduct[variant.ProductID] = append(byProduct[variant.ProductID], variant)
+  }
+
+  for i := range products {
+    products[i].Variants = byProduct[products[i].ID]
+    if products[i].Variants == nil {
+      products[i].Variants = make([]enProduct.Variant, 0)
+    }
+  }
+
+  return nil
+}
+
+// TakeStock move stock to sold for a product, or for one of its SKUs when
+// variantID is set, and take it from the warehouse chosen by allocation or
+// held by its reservation. It runs inside the transaction recording the
+// order, the caller drops the product cache once it is committed.
+// The product totals are moved together with the SKU. It returns the
+// warehouse fulfilling the line.
+func (r *Repository) TakeStock(ctx context.Context, tx *sqlx.Tx, sold, stock int64, productID, variantID int64, allocation enInventory.Allocation) (int64, error) {
+  var variant *int64
+  if variantID != 0 {
+    variant = &variantID
+  }
+
+  var (
+    warehouseID int64
+    err         error
+  )
+  if allocation.ReservationID != 0 {
+    warehouseID, err = commitReservation(ctx, tx, allocation.ReservationID, productID, variant, stock)
+  } else {
+    warehouseID, err = allocateWarehouse(ctx, tx, productID, variant, stock, allocation)
+  }
+  if err != nil {
+    log.Printf("[TakeStock] failed to allocate product %d. err: %+v", productID, err)
+    return 0, err
+  }
+
+  if variantID != 0 {
+    result, err := tx.ExecContext(ctx, `
+      update product_variants
+       set stock=stock-$1, sold=sold+$2, updated_time=now()
+      where id = $3 and product_id = $4 and stock >= $1
+    `, stock, sold, variantID, productID)
+    if err = checkStockUpdated(result, err); err != nil {
+      log.Printf("[TakeStock] failed to update variant %d. err: %+v", variantID, err)
+      return 0, err
+    }
+  }
+
+  result, err := tx.ExecContext(ctx, `
+    update products
+     set stock=stock-$1, sold=sold+$2, updated_time=now()
+    where id = $3 and stock >= $1 and deleted_at is null
+  `, stock, sold, productID)
+  if err = checkStockUpdated(result, err); err != nil {
+    log.Printf("[TakeStock] failed to update product %d. err: %+v", productID, err)
+    return 0, err
+  }
+
+  if err = moveWarehouseStock(ctx, tx, warehouseID, productID, variant, -stock); err != nil {
+    log.Printf("[TakeStock] failed to update warehouse %d. err: %+v", warehouseID, err)
+    return 0, err
+  }
+
+  if _, err = recordMovement(ctx, tx, productID, variant, warehouseID, -stock, enInventory.ReasonSale, ""); err != nil {
+    log.Printf("[TakeStock] failed to record movement. err: %+v", err)
+    return 0, err
+  }
+
+  return warehouseID, nil
+}
+
+// DropProductCache drop the cached product once a change made through
+// TakeStock is committed
+func (r *Repository) DropProductCache(productID int64) {
+  r.deleteProductCache(productID)
+}
+
+func checkStockUpdated(result sql.Result, err error) error {
+  if err != nil {
+    return err
+  }
+
+  affected, err := result.RowsAffected()
+  if err != nil {
+    return err
+  }
+
+  if affected == 0 {
+    return enProduct.ErrInsufficientStock
+  }
+
+  return nil
+}
+
+// syncVariantTotals recompute product stock from its variants. Sold is left
+// alone so sales made before the product had variants are kept.
+func syncVariantTotals(ctx context.Context, tx *sqlx.Tx, productID int64) error {
+  _, err := tx.ExecContext(ctx, `
+    update products p
+      set stock = v.stock, updated_time = now()
+    from (
+      select coalesce(sum(stock), 0) as stock
+      from product_variants
+      where product_id = $1
+    ) v
+    where p.id = $1
+      and exists (select 1 from product_variants where product_id = $1)
+  `, productID)
+  return err
+}
+
+// transferProductStock hand the stock a product kept per warehouse before it
+// had variants to its first variant, the product stock becomes the sum of its
+// variants and would lose it otherwise. Reserved stock can not be moved, the
+// reservations point at the product.
+func transferProductStock(ctx context.Context, tx *sqlx.Tx, productID, variantID int64) error {
+  var others int64
+  err := tx.QueryRowContext(ctx, `
+    select count(*) from product_variants
+    where product_id = $1 and id <> $2
+  `, productID, variantID).Scan(&others)
+  if err != nil || others > 0 {
+    return err
+  }
+
+  stocks := make([]struct {
+    WarehouseID int64 `db:"warehouse_id"`
+    Stock       int64 `db:"stock"`
+    Reserved    int64 `db:"reserved"`
+  }, 0)
+  err = tx.SelectContext(ctx, &stocks, `
+    select warehouse_id, stock, reserved
+    from warehouse_stocks
+    where product_id = $1 and variant_id is null and stock > 0
+    order by warehouse_id
+    for update
+  `, productID)
+  if err != nil {
+    return err
+  }
+
+  var moved int64
+  for _, stock := range stocks {
+    if stock.Reserved > 0 {
+      return enProduct.ErrStockReserved
+    }
+
+    if err = moveWarehouseStock(ctx, tx, stock.WarehouseID, productID, nil, -stock.Stock); err != nil {
+      return err
+    }
+
+    if _, err = recordMovement(ctx, tx, productID, nil, stock.WarehouseID, -stock.Stock, enInventory.ReasonTransfer, ""); err != nil {
+      return err
+    }
+
+    if err = moveWarehouseStock(ctx, tx, stock.WarehouseID, productID, &variantID, stock.Stock); err != nil {
+      return err
+    }
+
+    if _, err = recordMovement(ctx, tx, productID, &variantID, stock.WarehouseID, stock.Stock, enInventory.ReasonTransfer, ""); err != nil {
+      return err
+    }
+
+    moved += stock.Stock
+  }
+
+  if moved == 0 {
+    return nil
+  }
+
+  _, err = tx.ExecContext(ctx, `
+    update product_variants
+      set stock = stock + $1, updated_time = now()
+    where id = $2
+  `, moved, variantID)
+  return err
+}
+
+// deleteProductCache drop the cached detail of the product and the cached
+// listing pages, which may show it
+func (r *Repository) deleteProductCache(productID int64) {
+  key := fmt.Sprintf(productKey, productID)
+
+  err := r.redis.Del(key)
+  if err != nil {
+    log.Printf("Failed to delete redis for key %s. err: %v", key, err)
+  }
+
+  r.invalidateLists()
+}
@@ -0,0 +1,66 @@
+package product
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+
+	enProduct "ordent/internal/entity/product"
+	"ordent/internal/pkg/redigo"
+)
+
+// the recently viewed products of a user are a list of product ids, the
+// latest view first and each product once
+const (
+  viewedKey        = "recently-viewed-%d"
+  maxViewed        = 20
+  viewedExpireTime = 30 * 24 * 3600
+)
+
+// TrackView move the product to the front of the recently viewed products of
+// the user, dropping the oldest beyond maxViewed. The commands run in one
+// MULTI block so concurrent views can not leave duplicates or a longer list.
+func (r *Repository) TrackView(ctx context.Context, userID, productID int64) error {
+  key := fmt.Sprintf(viewedKey, userID)
+  member := strconv.FormatInt(productID, 10)
+
+  result := r.redis.Multi(
+    redigo.Command{Name: "LREM", Args: []interface{}{key, 0, member}},
+    redigo.Command{Name: "LPUSH", Args: []interface{}{key, member}},
+    redigo.Command{Name: "LTRIM", Args: []interface{}{key, 0, maxViewed - 1}},
+    redigo.Command{Name: "EXPIRE", Args: []interface{}{key, viewedExpireTime}},
+  )
+  if result.Error != nil {
+    log.Printf("[TrackView] failed to track view in %s. err: %v", key, result.Error)
+    return result.Error
+  }
+
+  return nil
+}
+
+// GetRecentlyViewed return the products the user viewed last, hydrated in
+// one batch, deleted products are left out
+func (r *Repository) GetRecentlyViewed(ctx context.Context, userID int64, limit int) ([]enProduct.Product, error) {
+  key := fmt.Sprintf(viewedKey, userID)
+  result := r.redis.LRange(key, 0, limit-1)
+  if result.Error != nil {
+    log.Printf("[GetRecentlyViewed] failed to read %s. err: %v", key, result.Error)
+    return make([]enProduct.Product, 0), result.Error
+  }
+
+  members, _ := result.Value.([]interface{})
+
+  productIDs := make([]int64, 0, len(members))
+  for _, member := range members {
+    memberByte, _ := member.([]byte)
+    productID, err := strconv.ParseInt(string(memberByte), 10, 64)
+    if err != nil {
+      continue
+    }
+
+    productIDs = append(productIDs, productID)
+  }
+
+  return r.GetProductsByIDs(ctx, productIDs)
+}
@@ -0,0 +1,291 @@
+package promotion
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+
+	enPagination "ordent/internal/entity/pagination"
+	enPromotion "ordent/internal/entity/promotion"
+	cursorPkg "ordent/internal/pkg/cursor"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+type Repository struct {
+	database *sqlx.DB
+}
+
+func NewRepository(
+	db *sqlx.DB,
+) *Repository {
+	return &Repository{
+		database: db,
+	}
+}
+
+const promotionColumns = `
+  id, name, kind, percent_off, sale_price, buy_quantity, free_quantity,
+  starts_at, ends_at, active, state, created_time
+`
+
+// promotionState is the schedule state matching the current time, the
+// scheduler only has to handle boundaries crossed afterwards
+const promotionState = `
+  case
+    when starts_at > now() then 'scheduled'
+    when ends_at > now() then 'running'
+    else 'ended'
+  end
+`
+
+func (r *Repository) InsertPromotion(ctx context.Context, form enPromotion.PromotionRequest) (int64, error) {
+  tx, err := r.database.BeginTxx(ctx, nil)
+  if err != nil {
+    log.Printf("[InsertPromotion] failed to begin transaction. err: %+v", err)
+    return 0, err
+  }
+  defer tx.Rollback()
+
+  var id int64
+  err = tx.QueryRowContext(ctx, `
+    insert into promotions
+      (name, kind, percent_off, sale_price, buy_quantity, free_quantity, starts_at, ends_at, active)
+    values ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+    returning id
+  `, form.Name, form.Kind, form.PercentOff, form.SalePrice, form.BuyQuantity, form.FreeQuantity,
+    form.StartsAt, form.EndsAt, form.Active == nil || *form.Active).Scan(&id)
+  if err != nil {
+    log.Printf("[InsertPromotion] failed to insert promotion. err: %+v", err)
+    return 0, err
+  }
+
+  if err = syncState(ctx, tx, id); err != nil {
+    return 0, err
+  }
+
+  if err = setTargets(ctx, tx, id, form); err != nil {
+    return 0, err
+  }
+
+  if err = tx.Commit(); err != nil {
+    log.Printf("[InsertPromotion] failed to commit promotion. err: %+v", err)
+    return 0, err
+  }
+
+  return id, nil
+}
+
+func (r *Repository) UpdatePromotion(ctx context.Context, form enPromotion.PromotionRequest) error {
+  tx, err := r.database.BeginTxx(ctx, nil)
+  if err != nil {
+    log.Printf("[UpdatePromotion] failed to begin transaction. err: %+v", err)
+    return err
+  }
+  defer tx.Rollback()
+
+  _, err = tx.ExecContext(ctx, `
+    update promotions
+      set name=$1, kind=$2, percent_off=$3, sale_price=$4, buy_quantity=$5, free_quantity=$6,
+      starts_at=$7, ends_at=$8, active=coalesce($9, active), updated_time=now()
+    where id = $10
+  `, form.Name, form.Kind, form.PercentOff, form.SalePrice, form.BuyQuantity, form.FreeQuantity,
+    form.StartsAt, form.EndsAt, form.Active, form.ID)
+  if err != nil {
+    log.Printf("[UpdatePromotion] failed to update promotion. err: %+v", err)
+    return err
+  }
+
+  if err = syncState(ctx, tx, form.ID); err != nil {
+    return err
+  }
+
+  if err = setTargets(ctx, tx, form.ID, form); err != nil {
+    return err
+  }
+
+  if err = tx.Commit(); err != nil {
+    log.Printf("[UpdatePromotion] failed to commit promotion. err: %+v", err)
+    return err
+  }
+
+  return nil
+}
+
+// syncState align the schedule state with a new schedule
+func syncState(ctx context.Context, tx *sqlx.Tx, promotionID int64) error {
+  _, err := tx.ExecContext(ctx, fmt.Sprintf(`
+    update promotions set state = %s where id = $1
+  `, promotionState), promotionID)
+  if err != nil {
+    log.Printf("[syncState] failed to set state of promotion %d. err: %+v", promotionID, err)
+    return err
+  }
+
+  return nil
+}
+
+// setTargets replace the products and categories of a promotion
+func setTargets(ctx context.Context, tx *sqlx.Tx, promotionID int64, form enPromotion.PromotionRequest) error {
+  _, err := tx.ExecContext(ctx, `
+    delete from promotion_products where promotion_id = $1
+  `, promotionID)
+  if err != nil {
+    log.Printf("[setTargets] failed to clear products of promotion %d. err: %+v", promotionID, err)
+    return err
+  }
+
+  _, err = tx.ExecContext(ctx, `
+    delete from promotion_categories where promotion_id = $1
+  `, promotionID)
+  if err != nil {
+    log.Printf("[setTargets] failed to clear categories of promotion %d. err: %+v", promotionID, err)
+    return err
+  }
+
+  _, err = tx.ExecContext(ctx, `
+    insert into promotion_products (promotion_id, product_id)
+    select $1, unnest($2::bigint[])
+    on conflict do nothing
+  `, promotionID, pq.Array(form.ProductIDs))
+  if err != nil {
+    log.Printf("[setTargets] failed to link products of promotion %d. err: %+v", promotionID, err)
+    return err
+  }
+
+  _, err = tx.ExecContext(ctx, `
+    insert into promotion_categories (promotion_id, category_id)
+    select $1, unnest($2::bigint[])
+    on conflict do nothing
+  `, promotionID, pq.Array(form.CategoryIDs))
+  if err != nil {
+    log.Printf("[setTargets] failed to link categories of promotion %d. err: %+v", promotionID, err)
+    return err
+  }
+
+  return nil
+}
+
+func (r *Repository) DeletePromotion(ctx context.Context, promotionID int64) error {
+  _, err := r.database.ExecContext(ctx, `
+    delete from promotions
+    where id = $1
+  `, promotionID)
+  if err != nil {
+    log.Printf("[DeletePromotion] failed to delete promotion. err: %+v", err)
+    return err
+  }
+
+  return nil
+}
+
+func (r *Repository) GetPromotion(ctx context.Context, promotionID int64) (*enPromotion.Promotion, error) {
+  promotion := &enPromotion.Promotion{}
+
+  err := r.database.GetContext(ctx, promotion, fmt.Sprintf(`
+    select %s
+    from promotions
+    where id = $1
+  `, promotionColumns), promotionID)
+  if err != nil {
+    if err == sql.ErrNoRows {
+      return promotion, nil
+    }
+
+    log.Printf("[GetPromotion] failed to get promotion. err: %+v", err)
+    return promotion, err
+  }
+
+  promotion.ProductIDs = make([]int64, 0)
+  err = r.database.SelectContext(ctx, &promotion.ProductIDs, `
+    select product_id from promotion_products where promotion_id = $1 order by product_id
+  `, promotionID)
+  if err != nil {
+    log.Printf("[GetPromotion] failed to get products of promotion %d. err: %+v", promotionID, err)
+    return promotion, err
+  }
+
+  promotion.CategoryIDs = make([]int64, 0)
+  err = r.database.SelectContext(ctx, &promotion.CategoryIDs, `
+    select category_id from promotion_categories where promotion_id = $1 order by category_id
+  `, promotionID)
+  if err != nil {
+    log.Printf("[GetPromotion] failed to get categories of promotion %d. err: %+v", promotionID, err)
+    return promotion, err
+  }
+
+  return promotion, nil
+}
+
+func (r *Repository) GetPromotions(ctx context.Context, cursor enPagination.Cursor, limit int) ([]enPromotion.Promotion, error) {
+  promotions := make([]enPromotion.Promotion, 0)
+
+  condition, order := cursorPkg.Keyset(cursor, "created_time", "id", 2)
+  args := []interface{}{limit + 1}
+  if !cursor.IsZero() {
+    args = append(args, time.UnixMicro(cursor.Key), cursor.ID)
+  }
+
+  err := r.database.SelectContext(ctx, &promotions, fmt.Sprintf(`
+    select %s
+    from promotions
+    where %s
+    order by %s
+    limit $1
+  `, promotionColumns, condition, order), args...)
+  if err != nil {
+    log.Printf("[GetPromotions] failed to get promotions. err: %+v", err)
+    return promotions, err
+  }
+
+  return promotions, nil
+}
+
+// GetProductIDs return every product the promotion applies to, directly or
+// through one of its categories and their sub categories
+func (r *Repository) GetProductIDs(ctx context.Context, promotionID int64) ([]int64, error) {
+  ids := make([]int64, 0)
+
+  err := r.database.SelectContext(ctx, &ids, `
+    with recursive tree as (
+      select category_id as id from promotion_categories where promotion_id = $1
+      union
+      select c.id from categories c
+      inner join tree t on c.parent_id = t.id
+    )
+    select product_id from promotion_products where promotion_id = $1
+    union
+    select pc.product_id from product_categories pc
+    inner join tree t on t.id = pc.category_id
+  `, promotionID)
+  if err != nil {
+    log.Printf("[GetProductIDs] failed to get products of promotion %d. err: %+v", promotionID, err)
+    return ids, err
+  }
+
+  return ids, nil
+}
+
+// AdvanceSchedule move promotions whose start or end has passed to their new
+// state and return them. The update claims the rows, so with several
+// instances each boundary is handled once.
+func (r *Repository) AdvanceSchedule(ctx context.Context) ([]int64, error) {
+  ids := make([]int64, 0)
+
+  err := r.database.SelectContext(ctx, &ids, fmt.Sprintf(`
+    update promotions
+      set state = %s, updated_time = now()
+    where (state = 'scheduled' and starts_at <= now())
+      or (state = 'running' and ends_at <= now())
+    returning id
+  `, promotionState))
+  if err != nil {
+    log.Printf("[AdvanceSchedule] failed to advance promotions. err: %+v", err)
+    return ids, err
+  }
+
+  return ids, nil
+}
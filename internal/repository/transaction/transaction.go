@@ -2,9 +2,16 @@ package transaction
 
 import (
 	"context"
+	"database/sql"
+	"fmt"
 	"log"
+	enCoupon "ordent/internal/entity/coupon"
+	enInventory "ordent/internal/entity/inventory"
+	enPagination "ordent/internal/entity/pagination"
 	enTransaction "ordent/internal/entity/transaction"
+	cursorPkg "ordent/internal/pkg/cursor"
 	"ordent/internal/pkg/redigo"
+	"time"
 
 	"github.com/jmoiron/sqlx"
 )
@@ -16,55 +23,172 @@ type (
 		Keys(key string) *redigo.Result
 		Setex(key string, expireTime int, value interface{}) error
 	}
+
+	stockRepository interface {
+		TakeStock(ctx context.Context, tx *sqlx.Tx, sold, stock int64, productID, variantID int64, allocation enInventory.Allocation) (int64, error)
+		DropProductCache(productID int64)
+	}
 )
 
 type Repository struct {
-	database *sqlx.DB
-	redis    redis
+	database  *sqlx.DB
+	redis     redis
+	stockRepo stockRepository
 }
 
 func NewRepository(
 	db *sqlx.DB,
 	redis redis,
+	stockRepo stockRepository,
 ) *Repository {
 	return &Repository{
-		database: db,
-		redis:    redis,
+		database:  db,
+		redis:     redis,
+		stockRepo: stockRepo,
 	}
 }
 
-func (r *Repository) CreateTransaction(ctx context.Context, form enTransaction.TransactionRequest) (int64, error) {
-  result, err := r.database.ExecContext(ctx, `
-    insert into transactions 
-      (user_id, product_id, amount)
-    values ($1, $2, $3)
-  `, form.UserID, form.ProductID, form.ItemAmount)
+// CreateTransaction take the stock of the order, redeem its coupon and record
+// it in one database transaction, so a failed step leaves stock, reservation
+// and coupon untouched. The coupon row stays locked until commit so
+// concurrent orders can not go over its limits.
+func (r *Repository) CreateTransaction(ctx context.Context, form enTransaction.TransactionRequest, allocation enInventory.Allocation) (int64, error) {
+  var variantID, reservationID, couponID *int64
+  if form.VariantID != 0 {
+    variantID = &form.VariantID
+  }
+  if form.ReservationID != 0 {
+    reservationID = &form.ReservationID
+  }
+  if form.CouponID != 0 {
+    couponID = &form.CouponID
+  }
+
+  tx, err := r.database.BeginTxx(ctx, nil)
   if err != nil {
-    log.Printf("[CreateTransaction] failed to create transaction. err: %+v", err)
-    return 0, err 
+    log.Printf("[CreateTransaction] failed to begin transaction. err: %+v", err)
+    return 0, err
   }
+  defer tx.Rollback()
 
-  id, err := result.LastInsertId()
+  if couponID != nil {
+    if err = redeemCoupon(ctx, tx, form.CouponID, form.UserID); err != nil {
+      return 0, err
+    }
+  }
+
+  form.WarehouseID, err = r.stockRepo.TakeStock(ctx, tx, form.ItemAmount, form.ItemAmount, form.ProductID, form.VariantID, allocation)
+  if err != nil {
+    return 0, err
+  }
+
+  var id int64
+  err = tx.QueryRowContext(ctx, `
+    insert into transactions 
+      (user_id, product_id, variant_id, warehouse_id, reservation_id, item_amount,
+      unit_price, free_items, coupon_id, subtotal, discount, total)
+    values ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+    returning id
+  `, form.UserID, form.ProductID, variantID, form.WarehouseID, reservationID, form.ItemAmount,
+    form.UnitPrice, form.FreeItems, couponID, form.Subtotal, form.Discount, form.Total).Scan(&id)
   if err != nil {
     log.Printf("[CreateTransaction] failed to create transaction. err: %+v", err)
     return 0, err 
   }
 
+  if couponID != nil {
+    _, err = tx.ExecContext(ctx, `
+      insert into coupon_redemptions
+        (coupon_id, user_id, transaction_id, discount)
+      values ($1, $2, $3, $4)
+    `, form.CouponID, form.UserID, id, form.Discount)
+    if err != nil {
+      log.Printf("[CreateTransaction] failed to record redemption of coupon %d. err: %+v", form.CouponID, err)
+      return 0, err
+    }
+  }
+
+  if err = tx.Commit(); err != nil {
+    log.Printf("[CreateTransaction] failed to commit transaction. err: %+v", err)
+    return 0, err
+  }
+
+  r.stockRepo.DropProductCache(form.ProductID)
+
   return id, nil
 }
 
-func (r *Repository) GetTransactionsByUser(ctx context.Context, userID int64) ([]enTransaction.Transaction, error) {
+// redeemCoupon take one use of the coupon if it is still active, valid and
+// under both of its limits
+func redeemCoupon(ctx context.Context, tx *sqlx.Tx, couponID, userID int64) error {
+  var perUserLimit *int64
+  err := tx.QueryRowContext(ctx, `
+    update coupons
+      set used_count = used_count + 1, updated_time = now()
+    where id = $1 and active
+      and (usage_limit is null or used_count < usage_limit)
+      and (valid_from is null or valid_from <= now())
+      and (valid_until is null or valid_until > now())
+    returning per_user_limit
+  `, couponID).Scan(&perUserLimit)
+  if err != nil {
+    if err == sql.ErrNoRows {
+      return enCoupon.ErrCouponUnavailable
+    }
+
+    log.Printf("[redeemCoupon] failed to redeem coupon %d. err: %+v", couponID, err)
+    return err
+  }
+
+  if perUserLimit == nil {
+    return nil
+  }
+
+  var used int64
+  err = tx.QueryRowContext(ctx, `
+    select count(*) from coupon_redemptions
+    where coupon_id = $1 and user_id = $2
+  `, couponID, userID).Scan(&used)
+  if err != nil {
+    log.Printf("[redeemCoupon] failed to count redemptions of coupon %d. err: %+v", couponID, err)
+    return err
+  }
+
+  if used >= *perUserLimit {
+    return enCoupon.ErrCouponUsed
+  }
+
+  return nil
+}
+
+func (r *Repository) GetTransactionsByUser(ctx context.Context, userID int64, cursor enPagination.Cursor, limit int) ([]enTransaction.Transaction, error) {
   transaction := make([]enTransaction.Transaction, 0)
 
-  err := r.database.SelectContext(ctx, &transaction, `
+  condition, order := cursorPkg.Keyset(cursor, "t.created_time", "t.id", 3)
+  args := []interface{}{limit + 1, userID}
+  if !cursor.IsZero() {
+    args = append(args, time.UnixMicro(cursor.Key), cursor.ID)
+  }
+
+  err := r.database.SelectContext(ctx, &transaction, fmt.Sprintf(`
     select 
-      t.id, t.user_id, t.product_id, t.item_amount,
-      p.name as product_name, p."type" as product_type, p.price as product_price
+      t.id, t.user_id, t.product_id, t.variant_id, coalesce(v.sku, '') as sku, t.warehouse_id,
+      t.item_amount, t.unit_price, t.free_items, t.coupon_id, t.subtotal, t.discount, t.total, t.created_time,
+      p.name as product_name, p.price as product_price,
+      coalesce((
+        select c.slug from product_categories pc
+        inner join categories c on c.id = pc.category_id
+        where pc.product_id = p.id
+        order by c.id
+        limit 1
+      ), '') as product_type
     from transactions t
     inner join products p on t.product_id = p.id
-    where t.user_id = $1
-    order by t.created_time desc
-    `, userID)
+    left join product_variants v on t.variant_id = v.id
+    where t.user_id = $2 and %s
+    order by %s
+    limit $1
+    `, condition, order), args...)
   if err != nil {
     log.Printf("[GetTransactionsByUser] failed to get transaction for user_id %d. err: %+v", userID, err)
     return transaction, err
@@ -73,17 +197,34 @@ func (r *Repository) GetTransactionsByUser(ctx context.Context, userID int64) ([
   return transaction, nil
 }
 
-func (r *Repository) GetAllTransactions(ctx context.Context) ([]enTransaction.Transaction, error) {
+func (r *Repository) GetAllTransactions(ctx context.Context, cursor enPagination.Cursor, limit int) ([]enTransaction.Transaction, error) {
   transaction := make([]enTransaction.Transaction, 0)
 
-  err := r.database.SelectContext(ctx, &transaction, `
+  condition, order := cursorPkg.Keyset(cursor, "t.created_time", "t.id", 2)
+  args := []interface{}{limit + 1}
+  if !cursor.IsZero() {
+    args = append(args, time.UnixMicro(cursor.Key), cursor.ID)
+  }
+
+  err := r.database.SelectContext(ctx, &transaction, fmt.Sprintf(`
     select 
-      t.id, t.user_id, t.product_id, t.item_amount,
-      p.name as product_name, p."type" as product_type, p.price as product_price
+      t.id, t.user_id, t.product_id, t.variant_id, coalesce(v.sku, '') as sku, t.warehouse_id,
+      t.item_amount, t.unit_price, t.free_items, t.coupon_id, t.subtotal, t.discount, t.total, t.created_time,
+      p.name as product_name, p.price as product_price,
+      coalesce((
+        select c.slug from product_categories pc
+        inner join categories c on c.id = pc.category_id
+        where pc.product_id = p.id
+        order by c.id
+        limit 1
+      ), '') as product_type
     from transactions t
     inner join products p on t.product_id = p.id
-    order by t.created_time desc
-    `) 
+    left join product_variants v on t.variant_id = v.id
+    where %s
+    order by %s
+    limit $1
+    `, condition, order), args...) 
   if err != nil {
     log.Printf("[GetAllTransactions] failed to get transaction. err: %+v", err)
     return transaction, err
@@ -48,10 +48,10 @@ func (r *Repository) InsertUser(ctx context.Context, form enUser.RegisterForm) (
 
 	err := r.database.QueryRowContext(ctx, `
     insert into users
-      (username, password, salt)
-    values ($1, $2, $3)
+      (username, password, salt, email)
+    values ($1, $2, $3, nullif($4, ''))
     returning id, username, is_admin  
-    `, form.Username, form.Password, form.Salt).Scan(&id, &username, &isAdmin)
+    `, form.Username, form.Password, form.Salt, form.Email).Scan(&id, &username, &isAdmin)
 	if err != nil {
 		log.Printf("[InsertUser] Failed to insert user. err: %v", err)
 		return user, err
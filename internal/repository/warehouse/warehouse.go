@@ -0,0 +1,128 @@
+package warehouse
+
+import (
+	"context"
+	"database/sql"
+	"log"
+
+	enInventory "ordent/internal/entity/inventory"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type Repository struct {
+	database *sqlx.DB
+}
+
+func NewRepository(
+	db *sqlx.DB,
+) *Repository {
+	return &Repository{
+		database: db,
+	}
+}
+
+func (r *Repository) InsertWarehouse(ctx context.Context, form enInventory.WarehouseRequest) (int64, error) {
+  var id int64
+
+  err := r.database.QueryRowContext(ctx, `
+    insert into warehouses
+      (code, name, latitude, longitude, priority, active)
+    values ($1, $2, $3, $4, $5, $6)
+    returning id
+  `, form.Code, form.Name, form.Latitude, form.Longitude, form.Priority, *form.Active).Scan(&id)
+  if err != nil {
+    log.Printf("[InsertWarehouse] failed to insert warehouse. err: %+v", err)
+    return 0, err
+  }
+
+  return id, nil
+}
+
+func (r *Repository) UpdateWarehouse(ctx context.Context, form enInventory.WarehouseRequest) error {
+  _, err := r.database.ExecContext(ctx, `
+    update warehouses
+      set code=$1, name=$2, latitude=$3, longitude=$4, priority=$5, active=$6, updated_time=now()
+    where id = $7
+  `, form.Code, form.Name, form.Latitude, form.Longitude, form.Priority, *form.Active, form.ID)
+  if err != nil {
+    log.Printf("[UpdateWarehouse] failed to update warehouse. err: %+v", err)
+    return err
+  }
+
+  return nil
+}
+
+func (r *Repository) GetWarehouse(ctx context.Context, warehouseID int64) (*enInventory.Warehouse, error) {
+  warehouse := &enInventory.Warehouse{}
+
+  err := r.database.GetContext(ctx, warehouse, `
+    select id, code, name, latitude, longitude, priority, active
+    from warehouses
+    where id = $1
+  `, warehouseID)
+  if err != nil {
+    if err == sql.ErrNoRows {
+      return warehouse, nil
+    }
+
+    log.Printf("[GetWarehouse] failed to get warehouse. err: %+v", err)
+    return warehouse, err
+  }
+
+  return warehouse, nil
+}
+
+func (r *Repository) GetWarehouseByCode(ctx context.Context, code string) (*enInventory.Warehouse, error) {
+  warehouse := &enInventory.Warehouse{}
+
+  err := r.database.GetContext(ctx, warehouse, `
+    select id, code, name, latitude, longitude, priority, active
+    from warehouses
+    where code = $1
+  `, code)
+  if err != nil {
+    if err == sql.ErrNoRows {
+      return warehouse, nil
+    }
+
+    log.Printf("[GetWarehouseByCode] failed to get warehouse. err: %+v", err)
+    return warehouse, err
+  }
+
+  return warehouse, nil
+}
+
+func (r *Repository) GetWarehouses(ctx context.Context) ([]enInventory.Warehouse, error) {
+  warehouses := make([]enInventory.Warehouse, 0)
+
+  err := r.database.SelectContext(ctx, &warehouses, `
+    select id, code, name, latitude, longitude, priority, active
+    from warehouses
+    order by priority, id
+  `)
+  if err != nil {
+    log.Printf("[GetWarehouses] failed to get warehouses. err: %+v", err)
+    return warehouses, err
+  }
+
+  return warehouses, nil
+}
+
+// CountStock return the units held by a warehouse and the number of other
+// active warehouses
+func (r *Repository) CountStock(ctx context.Context, warehouseID int64) (int64, int64, error) {
+  var stock, others int64
+
+  err := r.database.QueryRowContext(ctx, `
+    select
+      (select coalesce(sum(stock), 0) from warehouse_stocks where warehouse_id = $1),
+      (select count(*) from warehouses where active and id <> $1)
+  `, warehouseID).Scan(&stock, &others)
+  if err != nil {
+    log.Printf("[CountStock] failed to count stock of warehouse %d. err: %+v", warehouseID, err)
+    return 0, 0, err
+  }
+
+  return stock, others, nil
+}
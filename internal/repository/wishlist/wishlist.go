@@ -0,0 +1,223 @@
+package wishlist
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	enPagination "ordent/internal/entity/pagination"
+	enWishlist "ordent/internal/entity/wishlist"
+	cursorPkg "ordent/internal/pkg/cursor"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type Repository struct {
+	database *sqlx.DB
+}
+
+func NewRepository(
+	db *sqlx.DB,
+) *Repository {
+	return &Repository{
+		database: db,
+	}
+}
+
+func (r *Repository) InsertItem(ctx context.Context, form enWishlist.ItemRequest) error {
+  _, err := r.database.ExecContext(ctx, `
+    insert into wishlists
+      (user_id, product_id)
+    values ($1, $2)
+    on conflict do nothing
+  `, form.UserID, form.ProductID)
+  if err != nil {
+    log.Printf("[InsertItem] failed to add product %d to wishlist. err: %+v", form.ProductID, err)
+    return err
+  }
+
+  return nil
+}
+
+func (r *Repository) DeleteItem(ctx context.Context, form enWishlist.ItemRequest) error {
+  _, err := r.database.ExecContext(ctx, `
+    delete from wishlists
+    where user_id = $1 and product_id = $2
+  `, form.UserID, form.ProductID)
+  if err != nil {
+    log.Printf("[DeleteItem] failed to remove product %d from wishlist. err: %+v", form.ProductID, err)
+    return err
+  }
+
+  return nil
+}
+
+// GetItems list the wishlist of a user, last saved first
+func (r *Repository) GetItems(ctx context.Context, userID int64, cursor enPagination.Cursor, limit int) ([]enWishlist.Item, error) {
+  items := make([]enWishlist.Item, 0)
+
+  condition, order := cursorPkg.Keyset(cursor, "w.created_time", "w.product_id", 3)
+  args := []interface{}{limit + 1, userID}
+  if !cursor.IsZero() {
+    args = append(args, time.UnixMicro(cursor.Key), cursor.ID)
+  }
+
+  err := r.database.SelectContext(ctx, &items, fmt.Sprintf(`
+    select w.product_id, p.name, p.price, p.stock, p.in_stock, w.created_time
+    from wishlists w
+    inner join products p on p.id = w.product_id
+    where w.user_id = $2 and p.deleted_at is null and %s
+    order by %s
+    limit $1
+  `, condition, order), args...)
+  if err != nil {
+    log.Printf("[GetItems] failed to get wishlist of user %d. err: %+v", userID, err)
+    return items, err
+  }
+
+  return items, nil
+}
+
+// SyncRestock align in_stock with the stock of a product, productID 0 sync
+// every product. A product coming back in stock counts a new restock and
+// queues one notification for each user who wishlisted it, in the same
+// statement so no restock is notified twice.
+func (r *Repository) SyncRestock(ctx context.Context, productID int64) (int64, error) {
+  result, err := r.database.ExecContext(ctx, `
+    with flipped as (
+      update products
+        set in_stock = stock > 0,
+        restock_count = restock_count + (stock > 0)::int
+      where ($1 = 0 or id = $1) and in_stock <> (stock > 0)
+      returning id, in_stock, restock_count, deleted_at
+    )
+    insert into restock_notifications (product_id, user_id, restock)
+    select f.id, w.user_id, f.restock_count
+    from flipped f
+    inner join wishlists w on w.product_id = f.id
+    where f.in_stock and f.deleted_at is null
+    on conflict do nothing
+  `, productID)
+  if err != nil {
+    log.Printf("[SyncRestock] failed to sync restock of product %d. err: %+v", productID, err)
+    return 0, err
+  }
+
+  queued, err := result.RowsAffected()
+  if err != nil {
+    return 0, err
+  }
+
+  return queued, nil
+}
+
+// DeliverNotifications claim up to limit due notifications as sending, hand
+// each to deliver outside of any transaction and record the outcome in its own
+// statement. Rows claimed by another instance are skipped, failed deliveries
+// are retried with a doubling backoff until MaxAttempts.
+func (r *Repository) DeliverNotifications(ctx context.Context, limit int, deliver func(notification enWishlist.Notification) error) (int, error) {
+  notifications, err := r.claimNotifications(ctx, limit)
+  if err != nil {
+    return 0, err
+  }
+
+  sent := 0
+  for _, notification := range notifications {
+    if err := deliver(notification); err != nil {
+      r.markNotificationFailed(ctx, notification, err)
+      continue
+    }
+
+    _, err = r.database.ExecContext(ctx, `
+      update restock_notifications
+        set status = 'sent', last_error = '', sent_time = now()
+      where id = $1 and status = 'sending'
+    `, notification.ID)
+    if err != nil {
+      // the claim expires and the notification is sent again
+      log.Printf("[DeliverNotifications] failed to mark notification %d sent. err: %+v", notification.ID, err)
+      continue
+    }
+
+    sent++
+  }
+
+  return sent, nil
+}
+
+// claimNotifications release the expired claims, then claim the due pending
+// notifications and count the attempt, both in one short transaction
+func (r *Repository) claimNotifications(ctx context.Context, limit int) ([]enWishlist.Notification, error) {
+  notifications := make([]enWishlist.Notification, 0)
+
+  tx, err := r.database.BeginTxx(ctx, nil)
+  if err != nil {
+    log.Printf("[claimNotifications] failed to begin transaction. err: %+v", err)
+    return notifications, err
+  }
+  defer tx.Rollback()
+
+  _, err = tx.ExecContext(ctx, `
+    update restock_notifications
+      set status = case when attempts >= $1 then 'failed' else 'pending' end,
+        last_error = 'delivery interrupted', next_attempt_time = now()
+    where status = 'sending' and claimed_time < $2
+  `, enWishlist.MaxAttempts, time.Now().Add(-enWishlist.ClaimTimeout))
+  if err != nil {
+    log.Printf("[claimNotifications] failed to release expired claims. err: %+v", err)
+    return notifications, err
+  }
+
+  err = tx.SelectContext(ctx, &notifications, `
+    with claimed as (
+      update restock_notifications n
+        set status = 'sending', attempts = n.attempts + 1, claimed_time = now()
+      where n.id in (
+        select id from restock_notifications
+        where status = 'pending' and next_attempt_time <= now()
+        order by id
+        limit $1
+        for update skip locked
+      )
+      returning n.id, n.product_id, n.user_id, n.restock, n.attempts
+    )
+    select c.id, c.product_id, p.name as product_name, p.stock, c.user_id, u.username, u.email,
+      c.restock, c.attempts
+    from claimed c
+    inner join products p on p.id = c.product_id
+    inner join users u on u.id = c.user_id
+    order by c.id
+  `, limit)
+  if err != nil {
+    log.Printf("[claimNotifications] failed to claim pending notifications. err: %+v", err)
+    return notifications, err
+  }
+
+  if err = tx.Commit(); err != nil {
+    log.Printf("[claimNotifications] failed to commit claims. err: %+v", err)
+    return make([]enWishlist.Notification, 0), err
+  }
+
+  return notifications, nil
+}
+
+// markNotificationFailed put a failed notification back in the queue after
+// its backoff, or fail it for good once it used up its attempts
+func (r *Repository) markNotificationFailed(ctx context.Context, notification enWishlist.Notification, cause error) {
+  status := enWishlist.NotificationPending
+  if notification.Attempts >= enWishlist.MaxAttempts {
+    status = enWishlist.NotificationFailed
+  }
+
+  backoff := enWishlist.RetryBackoff << (notification.Attempts - 1)
+
+  _, err := r.database.ExecContext(ctx, `
+    update restock_notifications
+      set status = $1, last_error = $2, next_attempt_time = $3
+    where id = $4 and status = 'sending'
+  `, status, cause.Error(), time.Now().Add(backoff), notification.ID)
+  if err != nil {
+    log.Printf("[DeliverNotifications] failed to mark notification %d failed. err: %+v", notification.ID, err)
+  }
+}
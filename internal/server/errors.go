@@ -0,0 +1,76 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"ordent/internal/pkg/apperror"
+
+	"github.com/labstack/echo/v4"
+)
+
+const mimeProblemJSON = "application/problem+json"
+
+// problem is an RFC 7807 problem detail. Code is stable for clients to
+// branch on, Errors hold the invalid fields of a validation error.
+type problem struct {
+	Type      string                `json:"type"`
+	Title     string                `json:"title"`
+	Status    int                   `json:"status"`
+	Detail    string                `json:"detail,omitempty"`
+	Instance  string                `json:"instance,omitempty"`
+	Code      string                `json:"code"`
+	Errors    []apperror.FieldError `json:"errors,omitempty"`
+	RequestID string                `json:"requestID,omitempty"`
+}
+
+// httpErrorHandler render every error returned by handlers and middlewares
+// as problem+json. Domain errors show their message, anything else is an
+// internal error, its cause is only logged.
+func httpErrorHandler(err error, ctx echo.Context) {
+	res := ctx.Response()
+	if res.Committed {
+		return
+	}
+
+	// the id is set by the RequestID middleware before routing
+	p := problem{
+		Type:      "about:blank",
+		Instance:  ctx.Request().URL.Path,
+		RequestID: res.Header().Get(echo.HeaderXRequestID),
+	}
+
+	var httpErr *echo.HTTPError
+	if appErr, ok := apperror.As(err); ok {
+		p.Status, p.Code, p.Detail, p.Errors = appErr.Status(), appErr.Code, appErr.Message, appErr.Fields
+	} else if errors.As(err, &httpErr) {
+		// routing, binding and jwt errors of echo
+		p.Status = httpErr.Code
+		p.Code = strings.ReplaceAll(strings.ToLower(http.StatusText(httpErr.Code)), " ", "_")
+		if message, ok := httpErr.Message.(string); ok {
+			p.Detail = message
+		}
+	} else {
+		p.Status, p.Code = http.StatusInternalServerError, "internal_error"
+	}
+	p.Title = http.StatusText(p.Status)
+
+	if p.Status >= http.StatusInternalServerError {
+		log.Printf("[httpErrorHandler] %s %s request %s failed. err: %+v", ctx.Request().Method, ctx.Request().URL.Path, p.RequestID, err)
+	}
+
+	if ctx.Request().Method == http.MethodHead {
+		if err = ctx.NoContent(p.Status); err != nil {
+			log.Printf("[httpErrorHandler] failed to send error. err: %+v", err)
+		}
+		return
+	}
+
+	body, _ := json.Marshal(p)
+	if err = ctx.Blob(p.Status, mimeProblemJSON, body); err != nil {
+		log.Printf("[httpErrorHandler] failed to send error. err: %+v", err)
+	}
+}
@@ -1,44 +1,146 @@
 package middleware
 
 import (
+	"fmt"
 	"net/http"
+	"net/url"
+	"strings"
 
 	"github.com/labstack/echo/v4"
   "github.com/golang-jwt/jwt/v4"
 
 	enUser "ordent/internal/entity/user"
+	"ordent/internal/pkg/apperror"
 )
 
+// headers set on deprecated routes, see Deprecated
+const (
+  HeaderDeprecation = "Deprecation"
+  HeaderLink        = "Link"
+)
 
 func MidParseSession(next echo.HandlerFunc) echo.HandlerFunc {
   return func(ctx echo.Context) error {
     token, ok := ctx.Get(enUser.SessionContextKey).(*jwt.Token)
     if token == nil || !ok {
-      return ctx.JSON(http.StatusUnauthorized,
-        map[string]interface{}{
-          "Error": "Failed to Get Session",
-        },
-      )
+      return apperror.ErrUnauthorized
     }
 
     claims, ok := token.Claims.(*enUser.TokenClaim)
     if !ok {
-      return ctx.JSON(http.StatusUnauthorized,
-        map[string]interface{}{
-          "Error": "Failed To Get JWT Claims",
-        },
-      )
+      return apperror.ErrUnauthorized
     }
 
-    sess := enUser.Session{
-      ID: claims.ID,
-      Username: claims.Username,
-      UniqueKey: claims.UniqueKey,
-      IsAdmin: claims.IsAdmin,
+    setSession(ctx, claims)
+
+    return next(ctx)
+  }
+}
+
+// MidParseOptionalSession set the session when the request carries a valid
+// token and let anonymous requests through, for public routes behaving
+// differently for logged in users
+func MidParseOptionalSession(next echo.HandlerFunc) echo.HandlerFunc {
+  return func(ctx echo.Context) error {
+    token, ok := ctx.Get(enUser.SessionContextKey).(*jwt.Token)
+    if token == nil || !ok {
+      return next(ctx)
     }
 
-    ctx.Set(enUser.SessionContextKey, sess)
+    if claims, ok := token.Claims.(*enUser.TokenClaim); ok {
+      setSession(ctx, claims)
+    }
 
     return next(ctx)
   }
 }
+
+func setSession(ctx echo.Context, claims *enUser.TokenClaim) {
+  sess := enUser.Session{
+    ID: claims.ID,
+    Username: claims.Username,
+    UniqueKey: claims.UniqueKey,
+    IsAdmin: claims.IsAdmin,
+  }
+
+  ctx.Set(enUser.SessionContextKey, sess)
+  ctx.SetRequest(ctx.Request().WithContext(enUser.ContextWithSession(ctx.Request().Context(), sess)))
+}
+
+// CacheControl set the Cache-Control of the route and the request headers
+// the response varies on, e.g. Authorization when logged in users are
+// handled differently. Errors are never cached.
+func CacheControl(policy string, vary ...string) echo.MiddlewareFunc {
+  return func(next echo.HandlerFunc) echo.HandlerFunc {
+    return func(ctx echo.Context) error {
+      res := ctx.Response()
+      res.Before(func() {
+        if res.Status >= http.StatusBadRequest {
+          res.Header().Set(echo.HeaderCacheControl, "no-store")
+          return
+        }
+
+        res.Header().Set(echo.HeaderCacheControl, policy)
+        for _, name := range vary {
+          res.Header().Add(echo.HeaderVary, name)
+        }
+      })
+
+      return next(ctx)
+    }
+  }
+}
+
+// SessionCacheControl is CacheControl with its own policy for requests
+// carrying an Authorization header, their responses belong to one user
+func SessionCacheControl(anonymous, authenticated string, vary ...string) echo.MiddlewareFunc {
+  return func(next echo.HandlerFunc) echo.HandlerFunc {
+    anonymousNext := CacheControl(anonymous, vary...)(next)
+    authenticatedNext := CacheControl(authenticated, vary...)(next)
+
+    return func(ctx echo.Context) error {
+      if ctx.Request().Header.Get(echo.HeaderAuthorization) != "" {
+        return authenticatedNext(ctx)
+      }
+
+      return anonymousNext(ctx)
+    }
+  }
+}
+
+// ParamFromQuery expose the query param as path param, so a legacy route
+// like /product/one?productID= can share the handler of /products/:id
+func ParamFromQuery(query, param string) echo.MiddlewareFunc {
+  return func(next echo.HandlerFunc) echo.HandlerFunc {
+    return func(ctx echo.Context) error {
+      // the names are shared by every request of the route, never append in place
+      names := append(append([]string{}, ctx.ParamNames()...), param)
+      values := append(append([]string{}, ctx.ParamValues()...), ctx.QueryParam(query))
+
+      ctx.SetParamNames(names...)
+      ctx.SetParamValues(values...)
+
+      return next(ctx)
+    }
+  }
+}
+
+// Deprecated mark a legacy route and link the route replacing it, path
+// params like :id in successor are filled from the request. Routes taking
+// the id in the body link the successor as written.
+func Deprecated(successor string) echo.MiddlewareFunc {
+  return func(next echo.HandlerFunc) echo.HandlerFunc {
+    return func(ctx echo.Context) error {
+      link := successor
+      for _, name := range ctx.ParamNames() {
+        link = strings.ReplaceAll(link, ":"+name, url.PathEscape(ctx.Param(name)))
+      }
+
+      header := ctx.Response().Header()
+      header.Set(HeaderDeprecation, "true")
+      header.Add(HeaderLink, fmt.Sprintf(`<%s>; rel="successor-version"`, link))
+
+      return next(ctx)
+    }
+  }
+}
@@ -0,0 +1,23 @@
+package category
+
+import (
+	ctrls "ordent/internal/controller"
+
+	"ordent/internal/server/middleware"
+
+	"github.com/labstack/echo/v4"
+)
+
+func Register(e *echo.Echo, controllers *ctrls.Controllers, jwt echo.MiddlewareFunc) {
+
+  // public
+	category := e.Group("/category")
+  category.GET("/all", controllers.Category.GetCategories)
+  category.GET("/one", controllers.Category.GetCategory)
+
+  // need auth
+  categoryAdmin := e.Group("/category", jwt, middleware.MidParseSession)
+  categoryAdmin.POST("/insert", controllers.Category.InsertCategory)
+  categoryAdmin.PUT("/update", controllers.Category.UpdateCategory)
+  categoryAdmin.DELETE("/delete", controllers.Category.DeleteCategory)
+}
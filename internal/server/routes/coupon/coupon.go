@@ -0,0 +1,25 @@
+package coupon
+
+import (
+	ctrls "ordent/internal/controller"
+
+	"ordent/internal/server/middleware"
+
+	"github.com/labstack/echo/v4"
+)
+
+func Register(e *echo.Echo, controllers *ctrls.Controllers, jwt echo.MiddlewareFunc) {
+
+  coupon := e.Group("/coupon", jwt, middleware.MidParseSession)
+  coupon.POST("/apply", controllers.Coupon.ApplyCoupon)
+
+  // need auth
+  couponAdmin := e.Group("/admin/coupon", jwt, middleware.MidParseSession)
+  couponAdmin.POST("/insert", controllers.Coupon.InsertCoupon)
+  couponAdmin.PUT("/update", controllers.Coupon.UpdateCoupon)
+  couponAdmin.DELETE("/delete", controllers.Coupon.DeleteCoupon)
+  couponAdmin.GET("/one", controllers.Coupon.GetCoupon)
+  couponAdmin.GET("/all", controllers.Coupon.GetCoupons)
+  couponAdmin.GET("/redemptions", controllers.Coupon.GetRedemptions)
+  couponAdmin.GET("/report", controllers.Coupon.GetReport)
+}
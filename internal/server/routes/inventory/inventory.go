@@ -0,0 +1,30 @@
+package inventory
+
+import (
+	ctrls "ordent/internal/controller"
+
+	"ordent/internal/server/middleware"
+
+	"github.com/labstack/echo/v4"
+)
+
+func Register(e *echo.Echo, controllers *ctrls.Controllers, jwt echo.MiddlewareFunc) {
+
+  reservation := e.Group("/reservation", jwt, middleware.MidParseSession)
+  reservation.POST("/create", controllers.Inventory.ReserveStock)
+  reservation.GET("/one", controllers.Inventory.GetReservation)
+  reservation.DELETE("/release", controllers.Inventory.ReleaseReservation)
+
+  // need auth
+  inventoryAdmin := e.Group("/admin/inventory", jwt, middleware.MidParseSession)
+  inventoryAdmin.POST("/adjust", controllers.Inventory.AdjustStock)
+  inventoryAdmin.GET("/movements", controllers.Inventory.GetStockMovements)
+  inventoryAdmin.GET("/reconcile", controllers.Inventory.ReconcileStock)
+  inventoryAdmin.PUT("/threshold", controllers.Inventory.SetReorderThreshold)
+  inventoryAdmin.GET("/low-stock", controllers.Inventory.GetLowStockProducts)
+
+  warehouseAdmin := e.Group("/admin/warehouse", jwt, middleware.MidParseSession)
+  warehouseAdmin.POST("/insert", controllers.Inventory.InsertWarehouse)
+  warehouseAdmin.PUT("/update", controllers.Inventory.UpdateWarehouse)
+  warehouseAdmin.GET("/all", controllers.Inventory.GetWarehouses)
+}
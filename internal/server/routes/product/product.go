@@ -8,18 +8,57 @@ import (
 	"github.com/labstack/echo/v4"
 )
 
-func Register(e *echo.Echo, controllers *ctrls.Controllers, jwt echo.MiddlewareFunc) {
+// Cache-Control of the catalog, shared with the api routes. Listings move
+// with every product write. The detail of a logged in user is private and
+// revalidated every time, so shared caches never store it and each view
+// reaches the server to be tracked.
+const (
+  ListingCache       = "public, max-age=30, stale-while-revalidate=30"
+  DetailCache        = "public, max-age=60, stale-while-revalidate=60"
+  DetailSessionCache = "private, no-cache"
+)
+
+func Register(e *echo.Echo, controllers *ctrls.Controllers, jwt, optionalJwt echo.MiddlewareFunc) {
 
   // public
 	product := e.Group("/product")
-  product.GET("/all", controllers.Product.GetProducts)
-  product.GET("/tag", controllers.Product.GetProductsByType)
-  product.GET("/one", controllers.Product.GetProduct)
-  product.GET("/search", controllers.Product.SearchProduct)
+  product.GET("/all", controllers.Product.GetProducts, middleware.CacheControl(ListingCache),
+    middleware.Deprecated("/api/v1/products"))
+  product.GET("/tag", controllers.Product.GetProductsByCategory, middleware.CacheControl(ListingCache),
+    middleware.Deprecated("/api/v1/products"))
+  product.GET("/batch", controllers.Product.GetProductsByIDs)
+  product.GET("/one", controllers.Product.GetProduct, optionalJwt, middleware.MidParseOptionalSession,
+    middleware.SessionCacheControl(DetailCache, DetailSessionCache, echo.HeaderAuthorization),
+    middleware.ParamFromQuery("productID", "id"), middleware.Deprecated("/api/v1/products/:id"))
+  product.GET("/search", controllers.Product.SearchProduct, middleware.Deprecated("/api/v1/products"))
+  product.GET("/suggest", controllers.Product.Suggest)
+  product.GET("/bestsellers", controllers.Product.GetBestsellers)
+  product.GET("/trending", controllers.Product.GetTrending)
+  product.GET("/:id/reviews", controllers.Product.GetReviews)
+  product.GET("/:id/related", controllers.Product.GetRelated)
 
   // need auth
   productAdmin := e.Group("/product", jwt, middleware.MidParseSession)
-  productAdmin.POST("/insert", controllers.Product.InsertProduct)
-  productAdmin.PUT("/update", controllers.Product.UpdateProduct)
-  productAdmin.DELETE("/delete", controllers.Product.DeleteProduct)
+  productAdmin.POST("/insert", controllers.Product.InsertProduct, middleware.Deprecated("/api/v1/products"))
+  productAdmin.PUT("/update", controllers.Product.UpdateProduct, middleware.Deprecated("/api/v1/products/:id"))
+  productAdmin.PATCH("/:id", controllers.Product.PatchProduct, middleware.Deprecated("/api/v1/products/:id"))
+  productAdmin.DELETE("/delete", controllers.Product.DeleteProduct,
+    middleware.ParamFromQuery("productID", "id"), middleware.Deprecated("/api/v1/products/:id"))
+  productAdmin.GET("/deleted", controllers.Product.GetDeletedProducts)
+  productAdmin.PUT("/restore", controllers.Product.RestoreProduct)
+  productAdmin.DELETE("/purge", controllers.Product.PurgeProduct)
+  productAdmin.POST("/variant/insert", controllers.Product.InsertVariant)
+  productAdmin.PUT("/variant/update", controllers.Product.UpdateVariant)
+  productAdmin.DELETE("/variant/delete", controllers.Product.DeleteVariant)
+  productAdmin.POST("/image/upload", controllers.Product.UploadImage)
+  productAdmin.PUT("/image/order", controllers.Product.ReorderImages)
+  productAdmin.DELETE("/image/delete", controllers.Product.DeleteImage)
+  productAdmin.POST("/import", controllers.Product.ImportProducts)
+  productAdmin.GET("/export", controllers.Product.ExportProducts)
+  productAdmin.GET("/:id/history", controllers.Product.GetProductHistory)
+  productAdmin.GET("/:id/price", controllers.Product.GetPriceAt)
+  productAdmin.POST("/:id/review", controllers.Product.ReviewProduct)
+  productAdmin.GET("/review/all", controllers.Product.GetReviewsByStatus)
+  productAdmin.PUT("/review/moderate", controllers.Product.ModerateReview)
+  productAdmin.GET("/search/zero-results", controllers.Product.GetZeroResultQueries)
 }
@@ -0,0 +1,20 @@
+package promotion
+
+import (
+	ctrls "ordent/internal/controller"
+
+	"ordent/internal/server/middleware"
+
+	"github.com/labstack/echo/v4"
+)
+
+func Register(e *echo.Echo, controllers *ctrls.Controllers, jwt echo.MiddlewareFunc) {
+
+  // need auth
+  promotionAdmin := e.Group("/admin/promotion", jwt, middleware.MidParseSession)
+  promotionAdmin.POST("/insert", controllers.Promotion.InsertPromotion)
+  promotionAdmin.PUT("/update", controllers.Promotion.UpdatePromotion)
+  promotionAdmin.DELETE("/delete", controllers.Promotion.DeletePromotion)
+  promotionAdmin.GET("/one", controllers.Promotion.GetPromotion)
+  promotionAdmin.GET("/all", controllers.Promotion.GetPromotions)
+}
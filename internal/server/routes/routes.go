@@ -5,8 +5,13 @@ import (
 	ctrls "ordent/internal/controller"
 	enUser "ordent/internal/entity/user"
 	"ordent/internal/server/routes/user"
+  "ordent/internal/server/routes/category"
+  "ordent/internal/server/routes/coupon"
+  "ordent/internal/server/routes/inventory"
   "ordent/internal/server/routes/product"
+  "ordent/internal/server/routes/promotion"
   "ordent/internal/server/routes/transaction"
+  "ordent/internal/server/routes/v1"
 
   "github.com/golang-jwt/jwt/v4"
 	"github.com/labstack/echo-jwt/v4"
@@ -21,7 +26,24 @@ func Register(e *echo.Echo, controller *ctrls.Controllers, cfg config.JWT) {
       return new(enUser.TokenClaim)
     },
   }) 
+  // anonymous requests pass, a valid token still sets the session
+  optionalJwtMiddleware := echojwt.WithConfig(echojwt.Config{
+    SigningKey: []byte(cfg.Secret),
+    ContextKey: enUser.SessionContextKey,
+    NewClaimsFunc: func(c echo.Context) jwt.Claims {
+      return new(enUser.TokenClaim)
+    },
+    ContinueOnIgnoredError: true,
+    ErrorHandler: func(c echo.Context, err error) error {
+      return nil
+    },
+  })
   user.Register(e, controller, jwtMiddleware)
-  product.Register(e, controller, jwtMiddleware)
+  product.Register(e, controller, jwtMiddleware, optionalJwtMiddleware)
   transaction.Register(e, controller, jwtMiddleware)
+  category.Register(e, controller, jwtMiddleware)
+  inventory.Register(e, controller, jwtMiddleware)
+  coupon.Register(e, controller, jwtMiddleware)
+  promotion.Register(e, controller, jwtMiddleware)
+  v1.Register(e, controller, jwtMiddleware, optionalJwtMiddleware)
 }
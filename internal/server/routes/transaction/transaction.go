@@ -12,7 +12,7 @@ func Register(e *echo.Echo, controllers *ctrls.Controllers, jwt echo.MiddlewareF
 
 	transaction := e.Group("/transaction", jwt, middleware.MidParseSession)
 
-  transaction.POST("/create", controllers.Transcation.CreateTransaction)
-  transaction.GET("/user", controllers.Transcation.GetTransactionsByUser)
-  transaction.GET("/all", controllers.Transcation.GetAllTransactions)
+  transaction.POST("/create", controllers.Transcation.CreateTransaction, middleware.Deprecated("/api/v1/orders"))
+  transaction.GET("/user", controllers.Transcation.GetTransactionsByUser, middleware.Deprecated("/api/v1/me/orders"))
+  transaction.GET("/all", controllers.Transcation.GetAllTransactions, middleware.Deprecated("/api/v1/orders"))
 }
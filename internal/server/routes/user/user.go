@@ -21,4 +21,8 @@ func Register(e *echo.Echo, controllers *ctrls.Controllers, jwt echo.MiddlewareF
 	userAuth.POST("/logout", controllers.User.Logout)
 	userAuth.GET("/wallet", controllers.User.GetUserWallet)
 	userAuth.POST("/wallet", controllers.User.AddWallet)
+	userAuth.GET("/wishlist", controllers.Wishlist.GetItems)
+	userAuth.POST("/wishlist", controllers.Wishlist.InsertItem)
+	userAuth.DELETE("/wishlist", controllers.Wishlist.DeleteItem)
+	userAuth.GET("/recently-viewed", controllers.Product.GetRecentlyViewed)
 }
@@ -0,0 +1,31 @@
+package v1
+
+import (
+	ctrls "ordent/internal/controller"
+
+	"ordent/internal/server/middleware"
+	"ordent/internal/server/routes/product"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Register the resource routes of /api/v1. They share the handlers of the
+// legacy routes, which stay registered with deprecation headers pointing here.
+func Register(e *echo.Echo, controllers *ctrls.Controllers, jwt, optionalJwt echo.MiddlewareFunc) {
+
+	api := e.Group("/api/v1")
+
+  // public
+  api.GET("/products", controllers.Product.ListProducts, middleware.CacheControl(product.ListingCache))
+  api.GET("/products/:id", controllers.Product.GetProduct, optionalJwt, middleware.MidParseOptionalSession,
+    middleware.SessionCacheControl(product.DetailCache, product.DetailSessionCache, echo.HeaderAuthorization))
+
+  // need auth
+  apiAuth := e.Group("/api/v1", jwt, middleware.MidParseSession)
+  apiAuth.POST("/products", controllers.Product.InsertProduct)
+  apiAuth.PATCH("/products/:id", controllers.Product.PatchProduct)
+  apiAuth.DELETE("/products/:id", controllers.Product.DeleteProduct)
+  apiAuth.POST("/orders", controllers.Transcation.CreateTransaction)
+  apiAuth.GET("/orders", controllers.Transcation.GetAllTransactions)
+  apiAuth.GET("/me/orders", controllers.Transcation.GetTransactionsByUser)
+}
@@ -70,7 +70,13 @@ func (h *httpServer) connectCoreWithEcho(jwt config.JWT) {
 
   routes.Register(e, controller, jwt)
 
+	// uploaded media when stored on local filesystem
+	if storage := h.config.Storage; storage.Driver == "" || storage.Driver == "local" {
+		e.Static("/media", storage.Local.Directory)
+	}
+
 	// Set custom error handler
+	e.HTTPErrorHandler = httpErrorHandler
 	setServerObj(e, h.config.HTTPServer)
 }
 
@@ -0,0 +1,193 @@
+package category
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+
+	enCategory "ordent/internal/entity/category"
+	"ordent/internal/pkg/apperror"
+)
+
+type (
+	categoryRepository interface {
+		InsertCategory(ctx context.Context, form enCategory.CategoryRequest) (int64, error)
+		UpdateCategory(ctx context.Context, form enCategory.CategoryRequest) error
+		DeleteCategory(ctx context.Context, categoryID int64) error
+		GetCategory(ctx context.Context, categoryID int64) (*enCategory.Category, error)
+		GetCategoryBySlug(ctx context.Context, slug string) (*enCategory.Category, error)
+		GetCategories(ctx context.Context) ([]enCategory.Category, error)
+		GetDescendantIDs(ctx context.Context, categoryID int64) ([]int64, error)
+		CountUsage(ctx context.Context, categoryID int64) (int64, int64, error)
+		GetProductIDs(ctx context.Context, categoryID int64) ([]int64, error)
+	}
+
+	productRepository interface {
+		InvalidateProducts(ctx context.Context, productIDs []int64) error
+	}
+)
+
+var (
+	slugPattern   = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+	slugSeparator = regexp.MustCompile(`[^a-z0-9]+`)
+)
+
+type Usecase struct {
+  categoryRepo categoryRepository
+  productRepo  productRepository
+}
+
+func NewUsecase(
+  categoryRepo categoryRepository,
+  productRepo productRepository,
+) *Usecase {
+  return &Usecase{
+    categoryRepo: categoryRepo,
+    productRepo:  productRepo,
+  }
+}
+
+func (uc *Usecase) InsertCategory(ctx context.Context, form enCategory.CategoryRequest) (int64, error) {
+  form, err := uc.validate(ctx, form)
+  if err != nil {
+    return 0, err
+  }
+
+  categoryID, err := uc.categoryRepo.InsertCategory(ctx, form)
+  if err != nil {
+    return 0, fmt.Errorf("Failed to insert category. err: %w", err)
+  }
+
+  return categoryID, nil
+}
+
+func (uc *Usecase) UpdateCategory(ctx context.Context, form enCategory.CategoryRequest) error {
+  category, err := uc.categoryRepo.GetCategory(ctx, form.ID)
+  if err != nil {
+    return fmt.Errorf("Failed to check category. err: %w", err)
+  }
+
+  if category.ID == 0 {
+    return enCategory.ErrNotFound
+  }
+
+  form, err = uc.validate(ctx, form)
+  if err != nil {
+    return err
+  }
+
+  // moving a category below one of its own descendants would create a loop
+  if form.ParentID != nil {
+    descendantIDs, err := uc.categoryRepo.GetDescendantIDs(ctx, form.ID)
+    if err != nil {
+      return fmt.Errorf("Failed to check sub categories. err: %w", err)
+    }
+
+    for _, id := range descendantIDs {
+      if id == *form.ParentID {
+        return apperror.Invalid("parentID", "Parent category can not be the category itself or its sub category")
+      }
+    }
+  }
+
+  err = uc.categoryRepo.UpdateCategory(ctx, form)
+  if err != nil {
+    return fmt.Errorf("Failed to update category. err: %w", err)
+  }
+
+  // cached products carry the category and the promotions inherited through
+  // its parents, so the products of the whole subtree are stale
+  productIDs, err := uc.categoryRepo.GetProductIDs(ctx, form.ID)
+  if err != nil {
+    log.Printf("[UpdateCategory] failed to get products of category %d. err: %+v", form.ID, err)
+    return nil
+  }
+
+  uc.productRepo.InvalidateProducts(ctx, productIDs)
+
+  return nil
+}
+
+func (uc *Usecase) DeleteCategory(ctx context.Context, categoryID int64) error {
+  category, err := uc.categoryRepo.GetCategory(ctx, categoryID)
+  if err != nil {
+    return fmt.Errorf("Failed to check category. err: %w", err)
+  }
+
+  if category.ID == 0 {
+    return enCategory.ErrNotFound
+  }
+
+  children, products, err := uc.categoryRepo.CountUsage(ctx, categoryID)
+  if err != nil {
+    return fmt.Errorf("Failed to check category usage. err: %w", err)
+  }
+
+  if children > 0 || products > 0 {
+    return apperror.Conflict("category_not_empty", fmt.Sprintf("Category still has %d sub categories and %d products", children, products))
+  }
+
+  err = uc.categoryRepo.DeleteCategory(ctx, categoryID)
+  if err != nil {
+    return fmt.Errorf("Failed to delete category. err: %w", err)
+  }
+
+  return nil
+}
+
+func (uc *Usecase) GetCategory(ctx context.Context, categoryID int64) (*enCategory.Category, error) {
+  category, err := uc.categoryRepo.GetCategory(ctx, categoryID)
+  if err != nil {
+    return nil, fmt.Errorf("Failed to get category. err: %w", err)
+  }
+
+  return category, nil
+}
+
+func (uc *Usecase) GetCategories(ctx context.Context) ([]enCategory.Category, error) {
+  categories, err := uc.categoryRepo.GetCategories(ctx)
+  if err != nil {
+    return make([]enCategory.Category, 0), fmt.Errorf("Failed to get categories. err: %w", err)
+  }
+
+  return categories, nil
+}
+
+func (uc *Usecase) validate(ctx context.Context, form enCategory.CategoryRequest) (enCategory.CategoryRequest, error) {
+  form.Name = strings.TrimSpace(form.Name)
+  if form.Name == "" {
+    return form, apperror.Invalid("name", "Category name is required")
+  }
+
+  if form.Slug == "" {
+    form.Slug = strings.Trim(slugSeparator.ReplaceAllString(strings.ToLower(form.Name), "-"), "-")
+  }
+
+  if !slugPattern.MatchString(form.Slug) {
+    return form, apperror.Invalid("slug", "Slug may only contain lowercase letters, numbers and dashes")
+  }
+
+  existing, err := uc.categoryRepo.GetCategoryBySlug(ctx, form.Slug)
+  if err != nil {
+    return form, fmt.Errorf("Failed to check slug. err: %w", err)
+  }
+
+  if existing.ID != 0 && existing.ID != form.ID {
+    return form, apperror.Conflict("slug_exists", "Slug already existed")
+  }
+
+  if form.ParentID != nil {
+    parent, err := uc.categoryRepo.GetCategory(ctx, *form.ParentID)
+    if err != nil {
+      return form, fmt.Errorf("Failed to check parent category. err: %w", err)
+    }
+
+    if parent.ID == 0 {
+      return form, enCategory.ErrParentNotFound
+    }
+  }
+
+  return form, nil
+}
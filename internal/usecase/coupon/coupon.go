@@ -0,0 +1,353 @@
+package coupon
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	enCoupon "ordent/internal/entity/coupon"
+	enPagination "ordent/internal/entity/pagination"
+	enProduct "ordent/internal/entity/product"
+	"ordent/internal/pkg/apperror"
+	"ordent/internal/pkg/cursor"
+)
+
+type (
+	couponRepository interface {
+		InsertCoupon(ctx context.Context, form enCoupon.CouponRequest) (int64, error)
+		UpdateCoupon(ctx context.Context, form enCoupon.CouponRequest) error
+		DeleteCoupon(ctx context.Context, couponID int64) error
+		GetCoupon(ctx context.Context, couponID int64) (*enCoupon.Coupon, error)
+		GetCouponByCode(ctx context.Context, code string) (*enCoupon.Coupon, error)
+		GetCoupons(ctx context.Context, cursor enPagination.Cursor, limit int) ([]enCoupon.Coupon, error)
+		IsEligible(ctx context.Context, couponID, productID int64) (bool, error)
+		CountUserRedemptions(ctx context.Context, couponID, userID int64) (int64, error)
+		GetRedemptions(ctx context.Context, couponID int64, cursor enPagination.Cursor, limit int) ([]enCoupon.Redemption, error)
+		GetReport(ctx context.Context, from, to time.Time) ([]enCoupon.Report, error)
+	}
+
+	productRepository interface {
+		GetProduct(ctx context.Context, productID int64) (*enProduct.Product, error)
+		GetVariantBySKU(ctx context.Context, sku string) (*enProduct.Variant, error)
+	}
+)
+
+type Usecase struct {
+  couponRepo  couponRepository
+  productRepo productRepository
+  cursor      *cursor.Signer
+}
+
+func NewUsecase(
+  couponRepo couponRepository,
+  productRepo productRepository,
+  cursor *cursor.Signer,
+) *Usecase {
+  return &Usecase{
+    couponRepo:  couponRepo,
+    productRepo: productRepo,
+    cursor:      cursor,
+  }
+}
+
+// couponCursor position a coupon inside listings sorted by created time
+func couponCursor(coupon enCoupon.Coupon) enPagination.Cursor {
+  return enPagination.Cursor{Key: coupon.CreatedTime.UnixMicro(), ID: coupon.ID}
+}
+
+// redemptionCursor position a redemption inside listings sorted by created time
+func redemptionCursor(redemption enCoupon.Redemption) enPagination.Cursor {
+  return enPagination.Cursor{Key: redemption.CreatedTime.UnixMicro(), ID: redemption.ID}
+}
+
+// normalizeCode make codes case insensitive, customers type them by hand
+func normalizeCode(code string) string {
+  return strings.ToUpper(strings.TrimSpace(code))
+}
+
+func (uc *Usecase) InsertCoupon(ctx context.Context, form enCoupon.CouponRequest) (int64, error) {
+  form.ID = 0
+
+  if err := uc.validate(ctx, &form); err != nil {
+    return 0, err
+  }
+
+  couponID, err := uc.couponRepo.InsertCoupon(ctx, form)
+  if err != nil {
+    return 0, fmt.Errorf("Failed to insert coupon. err: %w", err)
+  }
+
+  return couponID, nil
+}
+
+func (uc *Usecase) UpdateCoupon(ctx context.Context, form enCoupon.CouponRequest) error {
+  coupon, err := uc.couponRepo.GetCoupon(ctx, form.ID)
+  if err != nil {
+    return fmt.Errorf("Failed to check coupon. err: %w", err)
+  }
+
+  if coupon.ID == 0 {
+    return enCoupon.ErrNotFound
+  }
+
+  if err = uc.validate(ctx, &form); err != nil {
+    return err
+  }
+
+  if form.UsageLimit != nil && *form.UsageLimit < coupon.UsedCount {
+    return apperror.Conflict("coupon_redeemed", fmt.Sprintf("Coupon is already used %d times", coupon.UsedCount))
+  }
+
+  err = uc.couponRepo.UpdateCoupon(ctx, form)
+  if err != nil {
+    return fmt.Errorf("Failed to update coupon. err: %w", err)
+  }
+
+  return nil
+}
+
+func (uc *Usecase) DeleteCoupon(ctx context.Context, couponID int64) error {
+  coupon, err := uc.couponRepo.GetCoupon(ctx, couponID)
+  if err != nil {
+    return fmt.Errorf("Failed to check coupon. err: %w", err)
+  }
+
+  if coupon.ID == 0 {
+    return enCoupon.ErrNotFound
+  }
+
+  // redemptions keep pointing at the coupon for the reports
+  if coupon.UsedCount > 0 {
+    return apperror.Conflict("coupon_redeemed", "Coupon is already redeemed, deactivate it instead")
+  }
+
+  err = uc.couponRepo.DeleteCoupon(ctx, couponID)
+  if err != nil {
+    return fmt.Errorf("Failed to delete coupon. err: %w", err)
+  }
+
+  return nil
+}
+
+func (uc *Usecase) GetCoupon(ctx context.Context, couponID int64) (*enCoupon.Coupon, error) {
+  coupon, err := uc.couponRepo.GetCoupon(ctx, couponID)
+  if err != nil {
+    return nil, fmt.Errorf("Failed to get coupon. err: %w", err)
+  }
+
+  if coupon.ID == 0 {
+    return nil, enCoupon.ErrNotFound
+  }
+
+  return coupon, nil
+}
+
+func (uc *Usecase) GetCoupons(ctx context.Context, page enPagination.Request) ([]enCoupon.Coupon, enPagination.Paging, error) {
+  signer := uc.cursor.Scope("coupons")
+  position, err := signer.Decode(page.Cursor)
+  if err != nil {
+    return make([]enCoupon.Coupon, 0), enPagination.Paging{}, err
+  }
+
+  limit := cursor.Limit(page.Limit)
+
+  coupons, err := uc.couponRepo.GetCoupons(ctx, position, limit)
+  if err != nil {
+    return make([]enCoupon.Coupon, 0), enPagination.Paging{}, fmt.Errorf("Failed to get coupons. err: %w", err)
+  }
+
+  coupons, paging := cursor.Page(signer, position, limit, coupons, couponCursor)
+
+  return coupons, paging, nil
+}
+
+func (uc *Usecase) GetRedemptions(ctx context.Context, couponID int64, page enPagination.Request) ([]enCoupon.Redemption, enPagination.Paging, error) {
+  signer := uc.cursor.Scope("redemptions", strconv.FormatInt(couponID, 10))
+  position, err := signer.Decode(page.Cursor)
+  if err != nil {
+    return make([]enCoupon.Redemption, 0), enPagination.Paging{}, err
+  }
+
+  limit := cursor.Limit(page.Limit)
+
+  redemptions, err := uc.couponRepo.GetRedemptions(ctx, couponID, position, limit)
+  if err != nil {
+    return make([]enCoupon.Redemption, 0), enPagination.Paging{}, fmt.Errorf("Failed to get redemptions. err: %w", err)
+  }
+
+  redemptions, paging := cursor.Page(signer, position, limit, redemptions, redemptionCursor)
+
+  return redemptions, paging, nil
+}
+
+func (uc *Usecase) GetReport(ctx context.Context, from, to time.Time) ([]enCoupon.Report, error) {
+  if !from.Before(to) {
+    return make([]enCoupon.Report, 0), apperror.Invalid("from", "Report start must be before its end")
+  }
+
+  reports, err := uc.couponRepo.GetReport(ctx, from, to)
+  if err != nil {
+    return make([]enCoupon.Report, 0), fmt.Errorf("Failed to get coupon report. err: %w", err)
+  }
+
+  return reports, nil
+}
+
+// ApplyCoupon price an order line with a coupon without redeeming it, the
+// redemption happens together with the order
+func (uc *Usecase) ApplyCoupon(ctx context.Context, form enCoupon.ApplyRequest) (*enCoupon.Quote, error) {
+  if form.ItemAmount <= 0 {
+    return nil, apperror.Invalid("itemAmount", "Item amount must be more than 0")
+  }
+
+  if form.SKU != "" {
+    variant, err := uc.productRepo.GetVariantBySKU(ctx, form.SKU)
+    if err != nil {
+      return nil, fmt.Errorf("Failed to get SKU. err: %w", err)
+    }
+
+    if variant.ID == 0 {
+      return nil, enProduct.ErrSKUNotFound
+    }
+
+    form.ProductID = variant.ProductID
+    form.VariantID = variant.ID
+  }
+
+  product, err := uc.productRepo.GetProduct(ctx, form.ProductID)
+  if err != nil {
+    return nil, fmt.Errorf("Failed to get product. err: %w", err)
+  }
+
+  if product.ID == 0 {
+    return nil, enProduct.ErrNotFound
+  }
+
+  if len(product.Variants) > 0 && form.VariantID == 0 {
+    return nil, apperror.Invalid("sku", "Product has variants, SKU is required")
+  }
+
+  subtotal := product.Quote(form.VariantID, form.ItemAmount, time.Now()).Subtotal
+
+  return uc.Quote(ctx, form.Code, form.UserID, form.ProductID, subtotal)
+}
+
+// Quote check every rule of the coupon for the user and the product and
+// return the discount on the subtotal
+func (uc *Usecase) Quote(ctx context.Context, code string, userID, productID, subtotal int64) (*enCoupon.Quote, error) {
+  coupon, err := uc.couponRepo.GetCouponByCode(ctx, normalizeCode(code))
+  if err != nil {
+    return nil, fmt.Errorf("Failed to get coupon. err: %w", err)
+  }
+
+  if coupon.ID == 0 || !coupon.Active {
+    return nil, enCoupon.ErrNotFound
+  }
+
+  now := time.Now()
+  if coupon.ValidFrom != nil && now.Before(*coupon.ValidFrom) {
+    return nil, apperror.New(apperror.KindValidation, "coupon_not_started", "Coupon is not valid yet")
+  }
+
+  if coupon.ValidUntil != nil && !now.Before(*coupon.ValidUntil) {
+    return nil, apperror.New(apperror.KindValidation, "coupon_expired", "Coupon has expired")
+  }
+
+  if subtotal < coupon.MinSpend {
+    return nil, apperror.New(apperror.KindValidation, "coupon_min_spend", fmt.Sprintf("Minimum spend for this coupon is %d", coupon.MinSpend))
+  }
+
+  if coupon.UsageLimit != nil && coupon.UsedCount >= *coupon.UsageLimit {
+    return nil, enCoupon.ErrCouponUsed
+  }
+
+  eligible, err := uc.couponRepo.IsEligible(ctx, coupon.ID, productID)
+  if err != nil {
+    return nil, fmt.Errorf("Failed to check coupon eligibility. err: %w", err)
+  }
+
+  if !eligible {
+    return nil, apperror.New(apperror.KindValidation, "coupon_not_applicable", "Coupon does not apply to this product")
+  }
+
+  if coupon.PerUserLimit != nil {
+    used, err := uc.couponRepo.CountUserRedemptions(ctx, coupon.ID, userID)
+    if err != nil {
+      return nil, fmt.Errorf("Failed to check coupon usage. err: %w", err)
+    }
+
+    if used >= *coupon.PerUserLimit {
+      return nil, enCoupon.ErrCouponUsed
+    }
+  }
+
+  discount := coupon.Discount(subtotal)
+
+  return &enCoupon.Quote{
+    CouponID: coupon.ID,
+    Code:     coupon.Code,
+    Subtotal: subtotal,
+    Discount: discount,
+    Total:    subtotal - discount,
+  }, nil
+}
+
+func (uc *Usecase) validate(ctx context.Context, form *enCoupon.CouponRequest) error {
+  form.Code = normalizeCode(form.Code)
+  form.Description = strings.TrimSpace(form.Description)
+
+  if form.Code == "" {
+    return apperror.Invalid("code", "Coupon code is required")
+  }
+
+  if strings.ContainsAny(form.Code, " \t\n") {
+    return apperror.Invalid("code", "Coupon code can not contain spaces")
+  }
+
+  switch form.Type {
+  case enCoupon.TypePercentage:
+    if form.Value <= 0 || form.Value > 100 {
+      return apperror.Invalid("value", "Percentage must be between 1 and 100")
+    }
+  case enCoupon.TypeFixed:
+    if form.Value <= 0 {
+      return apperror.Invalid("value", "Discount value must be more than 0")
+    }
+    form.MaxDiscount = nil
+  default:
+    return apperror.Invalid("type", fmt.Sprintf("Unknown coupon type %s", form.Type))
+  }
+
+  if form.MaxDiscount != nil && *form.MaxDiscount <= 0 {
+    return apperror.Invalid("maxDiscount", "Max discount must be more than 0")
+  }
+
+  if form.MinSpend < 0 {
+    return apperror.Invalid("minSpend", "Min spend can not be negative")
+  }
+
+  if form.UsageLimit != nil && *form.UsageLimit <= 0 {
+    return apperror.Invalid("usageLimit", "Usage limit must be more than 0")
+  }
+
+  if form.PerUserLimit != nil && *form.PerUserLimit <= 0 {
+    return apperror.Invalid("perUserLimit", "Per user limit must be more than 0")
+  }
+
+  if form.ValidFrom != nil && form.ValidUntil != nil && !form.ValidFrom.Before(*form.ValidUntil) {
+    return apperror.Invalid("validFrom", "Coupon must be valid from before valid until")
+  }
+
+  existing, err := uc.couponRepo.GetCouponByCode(ctx, form.Code)
+  if err != nil {
+    return fmt.Errorf("Failed to check coupon code. err: %w", err)
+  }
+
+  if existing.ID != 0 && existing.ID != form.ID {
+    return apperror.Conflict("coupon_code_exists", "Coupon code already existed")
+  }
+
+  return nil
+}
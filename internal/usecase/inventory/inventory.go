@@ -0,0 +1,302 @@
+package inventory
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"ordent/internal/config"
+	enInventory "ordent/internal/entity/inventory"
+	enPagination "ordent/internal/entity/pagination"
+	enProduct "ordent/internal/entity/product"
+	"ordent/internal/pkg/apperror"
+	"ordent/internal/pkg/cursor"
+	"ordent/internal/pkg/notifier"
+)
+
+type (
+	productRepository interface {
+		GetProduct(ctx context.Context, productID int64) (*enProduct.Product, error)
+		GetVariant(ctx context.Context, variantID int64) (*enProduct.Variant, error)
+		GetVariantBySKU(ctx context.Context, sku string) (*enProduct.Variant, error)
+		AdjustStock(ctx context.Context, form enInventory.AdjustmentRequest) (*enInventory.Movement, error)
+		GetStockMovements(ctx context.Context, productID int64, cursor enPagination.Cursor, limit int) ([]enInventory.Movement, error)
+		ReconcileStock(ctx context.Context, productID int64) ([]enInventory.Reconciliation, error)
+		SetReorderThreshold(ctx context.Context, productID, threshold int64) error
+		MarkLowStock(ctx context.Context, productID int64) (*enInventory.LowStock, error)
+		GetLowStockProducts(ctx context.Context, cursor enPagination.Cursor, limit int) ([]enInventory.LowStock, error)
+		ReserveStock(ctx context.Context, form enInventory.ReservationRequest, allocation enInventory.Allocation, ttl time.Duration) (*enInventory.Reservation, error)
+		GetReservation(ctx context.Context, reservationID int64) (*enInventory.Reservation, error)
+		ReleaseReservation(ctx context.Context, reservationID int64) error
+		ReleaseExpiredReservations(ctx context.Context, limit int) (int, error)
+	}
+
+	warehouseRepository interface {
+		InsertWarehouse(ctx context.Context, form enInventory.WarehouseRequest) (int64, error)
+		UpdateWarehouse(ctx context.Context, form enInventory.WarehouseRequest) error
+		GetWarehouse(ctx context.Context, warehouseID int64) (*enInventory.Warehouse, error)
+		GetWarehouseByCode(ctx context.Context, code string) (*enInventory.Warehouse, error)
+		GetWarehouses(ctx context.Context) ([]enInventory.Warehouse, error)
+		CountStock(ctx context.Context, warehouseID int64) (int64, int64, error)
+	}
+
+	restockWatcher interface {
+		CheckRestock(ctx context.Context, productID int64)
+	}
+)
+
+// notifyTimeout bound the delivery of one notification, it runs after the
+// request that triggered it has finished
+const notifyTimeout = 30 * time.Second
+
+type Usecase struct {
+  productRepo   productRepository
+  warehouseRepo  warehouseRepository
+  restockWatcher restockWatcher
+  notifier       notifier.Notifier
+  inventoryCfg   config.Inventory
+  cursor         *cursor.Signer
+}
+
+func NewUsecase(
+  productRepo productRepository,
+  warehouseRepo warehouseRepository,
+  restockWatcher restockWatcher,
+  notifier notifier.Notifier,
+  inventoryCfg config.Inventory,
+  cursor *cursor.Signer,
+) *Usecase {
+  return &Usecase{
+    productRepo:    productRepo,
+    warehouseRepo:  warehouseRepo,
+    restockWatcher: restockWatcher,
+    notifier:       notifier,
+    inventoryCfg:   inventoryCfg,
+    cursor:         cursor,
+  }
+}
+
+// AdjustStock post a signed stock change with its reason. Products with
+// variants are adjusted per SKU.
+func (uc *Usecase) AdjustStock(ctx context.Context, form enInventory.AdjustmentRequest) (*enInventory.Movement, error) {
+  sign, ok := enInventory.AdjustmentReasons[form.Reason]
+  if !ok {
+    return nil, apperror.Invalid("reason", fmt.Sprintf("Unknown reason %s", form.Reason))
+  }
+
+  if form.Delta == 0 {
+    return nil, apperror.Invalid("delta", "Delta can not be 0")
+  }
+
+  if sign > 0 && form.Delta < 0 {
+    return nil, apperror.Invalid("delta", fmt.Sprintf("Delta of %s must be positive", form.Reason))
+  }
+
+  if sign < 0 && form.Delta > 0 {
+    return nil, apperror.Invalid("delta", fmt.Sprintf("Delta of %s must be negative", form.Reason))
+  }
+
+  if err := uc.resolveVariant(ctx, &form); err != nil {
+    return nil, err
+  }
+
+  if form.WarehouseID != 0 {
+    warehouse, err := uc.warehouseRepo.GetWarehouse(ctx, form.WarehouseID)
+    if err != nil {
+      return nil, fmt.Errorf("Failed to check warehouse. err: %w", err)
+    }
+
+    if warehouse.ID == 0 {
+      return nil, enInventory.ErrWarehouseNotFound
+    }
+
+    if !warehouse.Active {
+      return nil, apperror.Conflict("warehouse_inactive", "Warehouse is not active")
+    }
+  }
+
+  product, err := uc.productRepo.GetProduct(ctx, form.ProductID)
+  if err != nil {
+    return nil, fmt.Errorf("Failed to check product. err: %w", err)
+  }
+
+  if product.ID == 0 {
+    return nil, enProduct.ErrNotFound
+  }
+
+  if len(product.Variants) > 0 && form.VariantID == 0 {
+    return nil, apperror.Invalid("sku", "Product has variants, adjust the stock of a SKU")
+  }
+
+  form.Note = strings.TrimSpace(form.Note)
+
+  movement, err := uc.productRepo.AdjustStock(ctx, form)
+  if err != nil {
+    return nil, fmt.Errorf("Failed to adjust stock. err: %w", err)
+  }
+
+  uc.CheckLowStock(ctx, form.ProductID)
+  uc.restockWatcher.CheckRestock(ctx, form.ProductID)
+
+  return movement, nil
+}
+
+// resolveVariant fill the variant and product of the request from the SKU or
+// the variant id
+func (uc *Usecase) resolveVariant(ctx context.Context, form *enInventory.AdjustmentRequest) error {
+  if form.SKU == "" && form.VariantID == 0 {
+    return nil
+  }
+
+  var (
+    variant *enProduct.Variant
+    err     error
+  )
+  if form.SKU != "" {
+    variant, err = uc.productRepo.GetVariantBySKU(ctx, form.SKU)
+  } else {
+    variant, err = uc.productRepo.GetVariant(ctx, form.VariantID)
+  }
+  if err != nil {
+    return fmt.Errorf("Failed to check variant. err: %w", err)
+  }
+
+  if variant.ID == 0 {
+    return enProduct.ErrVariantNotFound
+  }
+
+  if form.ProductID != 0 && form.ProductID != variant.ProductID {
+    return apperror.Invalid("variantID", "Variant does not belong to the product")
+  }
+
+  form.ProductID = variant.ProductID
+  form.VariantID = variant.ID
+
+  return nil
+}
+
+// GetStockMovements list the stock log of a product, newest first
+func (uc *Usecase) GetStockMovements(ctx context.Context, productID int64, page enPagination.Request) ([]enInventory.Movement, enPagination.Paging, error) {
+  signer := uc.cursor.Scope("stock-movements", strconv.FormatInt(productID, 10))
+  position, err := signer.Decode(page.Cursor)
+  if err != nil {
+    return make([]enInventory.Movement, 0), enPagination.Paging{}, err
+  }
+
+  limit := cursor.Limit(page.Limit)
+
+  movements, err := uc.productRepo.GetStockMovements(ctx, productID, position, limit)
+  if err != nil {
+    return make([]enInventory.Movement, 0), enPagination.Paging{}, fmt.Errorf("Failed to get stock movements. err: %w", err)
+  }
+
+  movements, paging := cursor.Page(signer, position, limit, movements, func(movement enInventory.Movement) enPagination.Cursor {
+    return enPagination.Cursor{Key: movement.CreatedTime.UnixMicro(), ID: movement.ID}
+  })
+
+  return movements, paging, nil
+}
+
+// ReconcileStock compare stored stock against the movement log. For one
+// product every SKU is returned, for the whole catalog only the mismatches.
+func (uc *Usecase) ReconcileStock(ctx context.Context, productID int64) ([]enInventory.Reconciliation, error) {
+  rows, err := uc.productRepo.ReconcileStock(ctx, productID)
+  if err != nil {
+    return make([]enInventory.Reconciliation, 0), fmt.Errorf("Failed to reconcile stock. err: %w", err)
+  }
+
+  if productID != 0 {
+    return rows, nil
+  }
+
+  mismatches := make([]enInventory.Reconciliation, 0)
+  for _, row := range rows {
+    if !row.Matched() {
+      mismatches = append(mismatches, row)
+    }
+  }
+
+  return mismatches, nil
+}
+
+// SetReorderThreshold set the stock level under which the product alerts,
+// 0 disable the alert
+func (uc *Usecase) SetReorderThreshold(ctx context.Context, form enInventory.ThresholdRequest) error {
+  if form.Threshold < 0 {
+    return apperror.Invalid("threshold", "Threshold can not be negative")
+  }
+
+  product, err := uc.productRepo.GetProduct(ctx, form.ProductID)
+  if err != nil {
+    return fmt.Errorf("Failed to check product. err: %w", err)
+  }
+
+  if product.ID == 0 {
+    return enProduct.ErrNotFound
+  }
+
+  err = uc.productRepo.SetReorderThreshold(ctx, form.ProductID, form.Threshold)
+  if err != nil {
+    return fmt.Errorf("Failed to set threshold. err: %w", err)
+  }
+
+  uc.CheckLowStock(ctx, form.ProductID)
+
+  return nil
+}
+
+// CheckLowStock notify once when the stock of a product dropped below its
+// reorder threshold. It is called after every stock change and never fails
+// the change itself.
+func (uc *Usecase) CheckLowStock(ctx context.Context, productID int64) {
+  lowStock, err := uc.productRepo.MarkLowStock(ctx, productID)
+  if err != nil {
+    log.Printf("[CheckLowStock] failed to check product %d. err: %+v", productID, err)
+    return
+  }
+
+  if lowStock == nil {
+    return
+  }
+
+  msg := notifier.Message{
+    Event:   enInventory.EventLowStock,
+    Subject: fmt.Sprintf("Low stock: %s", lowStock.Name),
+    Body:    fmt.Sprintf("%s has %d left, below the reorder threshold of %d.", lowStock.Name, lowStock.Stock, lowStock.Threshold),
+    Data:    lowStock,
+  }
+
+  go func() {
+    ctx, cancel := context.WithTimeout(context.Background(), notifyTimeout)
+    defer cancel()
+
+    if err := uc.notifier.Notify(ctx, msg); err != nil {
+      log.Printf("[CheckLowStock] failed to notify low stock of product %d. err: %+v", productID, err)
+    }
+  }()
+}
+
+// GetLowStockProducts list products below their threshold for the dashboard
+func (uc *Usecase) GetLowStockProducts(ctx context.Context, page enPagination.Request) ([]enInventory.LowStock, enPagination.Paging, error) {
+  signer := uc.cursor.Scope("low-stock")
+  position, err := signer.Decode(page.Cursor)
+  if err != nil {
+    return make([]enInventory.LowStock, 0), enPagination.Paging{}, err
+  }
+
+  limit := cursor.Limit(page.Limit)
+
+  products, err := uc.productRepo.GetLowStockProducts(ctx, position, limit)
+  if err != nil {
+    return make([]enInventory.LowStock, 0), enPagination.Paging{}, fmt.Errorf("Failed to get low stock products. err: %w", err)
+  }
+
+  products, paging := cursor.Page(signer, position, limit, products, func(product enInventory.LowStock) enPagination.Cursor {
+    return enPagination.Cursor{Key: product.Shortfall, ID: product.ProductID}
+  })
+
+  return products, paging, nil
+}
@@ -0,0 +1,151 @@
+package inventory
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	enInventory "ordent/internal/entity/inventory"
+	enProduct "ordent/internal/entity/product"
+	"ordent/internal/pkg/apperror"
+)
+
+const (
+	defaultReservationTTL = 15 * time.Minute
+	defaultSweepInterval  = time.Minute
+	sweepBatchSize        = 100
+)
+
+// ReserveStock hold stock for the checkout of the user. The hold counts as
+// sold for everybody else until it is used by a transaction, released or
+// expired.
+func (uc *Usecase) ReserveStock(ctx context.Context, form enInventory.ReservationRequest) (*enInventory.Reservation, error) {
+  if form.Quantity <= 0 {
+    return nil, apperror.Invalid("quantity", "Quantity must be more than 0")
+  }
+
+  if form.SKU != "" {
+    variant, err := uc.productRepo.GetVariantBySKU(ctx, form.SKU)
+    if err != nil {
+      return nil, fmt.Errorf("Failed to get SKU. err: %w", err)
+    }
+
+    if variant.ID == 0 {
+      return nil, enProduct.ErrSKUNotFound
+    }
+
+    form.ProductID = variant.ProductID
+    form.VariantID = variant.ID
+  }
+
+  product, err := uc.productRepo.GetProduct(ctx, form.ProductID)
+  if err != nil {
+    return nil, fmt.Errorf("Failed to get product. err: %w", err)
+  }
+
+  if product.ID == 0 {
+    return nil, enProduct.ErrNotFound
+  }
+
+  if len(product.Variants) > 0 && form.VariantID == 0 {
+    return nil, apperror.Invalid("sku", "Product has variants, SKU is required")
+  }
+
+  ttl := uc.inventoryCfg.ReservationTTL
+  if ttl <= 0 {
+    ttl = defaultReservationTTL
+  }
+
+  allocation := enInventory.Allocation{
+    Strategy:  uc.inventoryCfg.Allocation,
+    Latitude:  form.Latitude,
+    Longitude: form.Longitude,
+  }
+
+  reservation, err := uc.productRepo.ReserveStock(ctx, form, allocation, ttl)
+  if err != nil {
+    return nil, fmt.Errorf("Failed to reserve stock. err: %w", err)
+  }
+
+  return reservation, nil
+}
+
+// GetReservation return a reservation of the user
+func (uc *Usecase) GetReservation(ctx context.Context, userID, reservationID int64) (*enInventory.Reservation, error) {
+  reservation, err := uc.productRepo.GetReservation(ctx, reservationID)
+  if err != nil {
+    return nil, fmt.Errorf("Failed to get reservation. err: %w", err)
+  }
+
+  if reservation.ID == 0 || reservation.UserID != userID {
+    return nil, enInventory.ErrReservationNotFound
+  }
+
+  return reservation, nil
+}
+
+// ReleaseReservation give a held reservation of the user back, e.g. when the
+// checkout is abandoned
+func (uc *Usecase) ReleaseReservation(ctx context.Context, userID, reservationID int64) error {
+  reservation, err := uc.GetReservation(ctx, userID, reservationID)
+  if err != nil {
+    return err
+  }
+
+  if reservation.Status != enInventory.ReservationHeld {
+    return apperror.Conflict("reservation_not_held", fmt.Sprintf("Reservation is %s", reservation.Status))
+  }
+
+  err = uc.productRepo.ReleaseReservation(ctx, reservationID)
+  if err != nil {
+    return fmt.Errorf("Failed to release reservation. err: %w", err)
+  }
+
+  uc.restockWatcher.CheckRestock(ctx, reservation.ProductID)
+
+  return nil
+}
+
+// SweepReservations release every expired hold and return how many were released
+func (uc *Usecase) SweepReservations(ctx context.Context) (int, error) {
+  total := 0
+  for {
+    released, err := uc.productRepo.ReleaseExpiredReservations(ctx, sweepBatchSize)
+    if err != nil {
+      return total, fmt.Errorf("Failed to release expired reservations. err: %w", err)
+    }
+
+    total += released
+    if released < sweepBatchSize {
+      return total, nil
+    }
+  }
+}
+
+// RunReservationSweeper sweep expired reservations until ctx is done
+func (uc *Usecase) RunReservationSweeper(ctx context.Context) {
+  interval := uc.inventoryCfg.SweepInterval
+  if interval <= 0 {
+    interval = defaultSweepInterval
+  }
+
+  ticker := time.NewTicker(interval)
+  defer ticker.Stop()
+
+  for {
+    select {
+    case <-ctx.Done():
+      return
+    case <-ticker.C:
+      released, err := uc.SweepReservations(ctx)
+      if err != nil {
+        log.Printf("[RunReservationSweeper] %+v", err)
+      }
+
+      if released > 0 {
+        log.Printf("[RunReservationSweeper] released %d expired reservations", released)
+      }
+    }
+  }
+}
@@ -0,0 +1,108 @@
+package inventory
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	enInventory "ordent/internal/entity/inventory"
+	"ordent/internal/pkg/apperror"
+)
+
+func (uc *Usecase) InsertWarehouse(ctx context.Context, form enInventory.WarehouseRequest) (int64, error) {
+  form.ID = 0
+  if form.Active == nil {
+    active := true
+    form.Active = &active
+  }
+
+  if err := uc.validateWarehouse(ctx, &form); err != nil {
+    return 0, err
+  }
+
+  warehouseID, err := uc.warehouseRepo.InsertWarehouse(ctx, form)
+  if err != nil {
+    return 0, fmt.Errorf("Failed to insert warehouse. err: %w", err)
+  }
+
+  return warehouseID, nil
+}
+
+func (uc *Usecase) UpdateWarehouse(ctx context.Context, form enInventory.WarehouseRequest) error {
+  warehouse, err := uc.warehouseRepo.GetWarehouse(ctx, form.ID)
+  if err != nil {
+    return fmt.Errorf("Failed to check warehouse. err: %w", err)
+  }
+
+  if warehouse.ID == 0 {
+    return enInventory.ErrWarehouseNotFound
+  }
+
+  if form.Active == nil {
+    form.Active = &warehouse.Active
+  }
+
+  // stock of an inactive warehouse can not be sold, empty it first
+  if warehouse.Active && !*form.Active {
+    stock, others, err := uc.warehouseRepo.CountStock(ctx, form.ID)
+    if err != nil {
+      return fmt.Errorf("Failed to check warehouse stock. err: %w", err)
+    }
+
+    if stock > 0 {
+      return apperror.Conflict("warehouse_not_empty", fmt.Sprintf("Warehouse still holds %d units", stock))
+    }
+
+    if others == 0 {
+      return apperror.Conflict("last_active_warehouse", "At least one warehouse must stay active")
+    }
+  }
+
+  if err = uc.validateWarehouse(ctx, &form); err != nil {
+    return err
+  }
+
+  err = uc.warehouseRepo.UpdateWarehouse(ctx, form)
+  if err != nil {
+    return fmt.Errorf("Failed to update warehouse. err: %w", err)
+  }
+
+  return nil
+}
+
+func (uc *Usecase) GetWarehouses(ctx context.Context) ([]enInventory.Warehouse, error) {
+  warehouses, err := uc.warehouseRepo.GetWarehouses(ctx)
+  if err != nil {
+    return make([]enInventory.Warehouse, 0), fmt.Errorf("Failed to get warehouses. err: %w", err)
+  }
+
+  return warehouses, nil
+}
+
+func (uc *Usecase) validateWarehouse(ctx context.Context, form *enInventory.WarehouseRequest) error {
+  form.Code = strings.ToLower(strings.TrimSpace(form.Code))
+  form.Name = strings.TrimSpace(form.Name)
+
+  if form.Code == "" || form.Name == "" {
+    return apperror.Validation("Warehouse code and name are required")
+  }
+
+  if (form.Latitude == nil) != (form.Longitude == nil) {
+    return apperror.Validation("Latitude and longitude must be set together")
+  }
+
+  if form.Latitude != nil && (*form.Latitude < -90 || *form.Latitude > 90 || *form.Longitude < -180 || *form.Longitude > 180) {
+    return apperror.Validation("Location out of range")
+  }
+
+  existing, err := uc.warehouseRepo.GetWarehouseByCode(ctx, form.Code)
+  if err != nil {
+    return fmt.Errorf("Failed to check warehouse code. err: %w", err)
+  }
+
+  if existing.ID != 0 && existing.ID != form.ID {
+    return apperror.Conflict("warehouse_code_exists", "Warehouse code already existed")
+  }
+
+  return nil
+}
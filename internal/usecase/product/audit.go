@@ -0,0 +1,55 @@
+package product
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	enPagination "ordent/internal/entity/pagination"
+	enProduct "ordent/internal/entity/product"
+	"ordent/internal/pkg/apperror"
+	"ordent/internal/pkg/cursor"
+)
+
+// GetProductHistory list the audit trail of a product, newest first. Deleted
+// and purged products keep their history.
+func (uc *Usecase) GetProductHistory(ctx context.Context, productID int64, page enPagination.Request) ([]enProduct.Audit, enPagination.Paging, error) {
+  signer := uc.cursor.Scope("product-history", strconv.FormatInt(productID, 10))
+  position, err := signer.Decode(page.Cursor)
+  if err != nil {
+    return make([]enProduct.Audit, 0), enPagination.Paging{}, err
+  }
+
+  limit := cursor.Limit(page.Limit)
+
+  audits, err := uc.productRepo.GetProductAudits(ctx, productID, position, limit)
+  if err != nil {
+    return make([]enProduct.Audit, 0), enPagination.Paging{}, fmt.Errorf("Failed to get product history. err: %w", err)
+  }
+
+  audits, paging := cursor.Page(signer, position, limit, audits, func(audit enProduct.Audit) enPagination.Cursor {
+    return enPagination.Cursor{Key: audit.CreatedTime.UnixMicro(), ID: audit.ID}
+  })
+
+  return audits, paging, nil
+}
+
+// GetPriceAt answer what a product, or one of its SKUs, cost at a point in time
+func (uc *Usecase) GetPriceAt(ctx context.Context, productID, variantID int64, at time.Time) (*enProduct.PriceAt, error) {
+  price, found, err := uc.productRepo.GetPriceAt(ctx, productID, variantID, at)
+  if err != nil {
+    return nil, fmt.Errorf("Failed to get price history. err: %w", err)
+  }
+
+  if !found {
+    return nil, apperror.NotFound("price_not_found", "Product had no price at that time")
+  }
+
+  return &enProduct.PriceAt{
+    ProductID: productID,
+    VariantID: variantID,
+    At:        at,
+    Price:     price,
+  }, nil
+}
@@ -0,0 +1,370 @@
+package product
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	enProduct "ordent/internal/entity/product"
+	"ordent/internal/pkg/apperror"
+)
+
+// ImportProducts parse and validate a catalog file, then apply it in batches.
+// Nothing is written on dry run or when any row is invalid.
+func (uc *Usecase) ImportProducts(ctx context.Context, format string, file io.Reader, dryRun bool) (*enProduct.ImportResult, error) {
+  result := &enProduct.ImportResult{
+    DryRun: dryRun,
+    Errors: make([]enProduct.CatalogError, 0),
+  }
+
+  var (
+    rows []enProduct.CatalogRow
+    err  error
+  )
+
+  switch format {
+  case enProduct.CatalogFormatCSV:
+    rows, result.Errors, err = parseCatalogCSV(file)
+  case enProduct.CatalogFormatJSONL:
+    rows, result.Errors, err = parseCatalogJSONL(file)
+  default:
+    return nil, apperror.Invalid("format", fmt.Sprintf("Unknown catalog format %s", format))
+  }
+  if err != nil {
+    return nil, fmt.Errorf("Failed to read catalog. err: %w", err)
+  }
+
+  result.Total = len(rows) + len(result.Errors)
+
+  categoryIDs, err := uc.validateCatalog(ctx, rows, result)
+  if err != nil {
+    return nil, err
+  }
+
+  if dryRun || len(result.Errors) > 0 {
+    sortCatalogErrors(result.Errors)
+    return result, nil
+  }
+
+  for start := 0; start < len(rows); start += enProduct.CatalogBatchSize {
+    end := start + enProduct.CatalogBatchSize
+    if end > len(rows) {
+      end = len(rows)
+    }
+
+    created, updated, rowErrors, err := uc.productRepo.ImportProducts(ctx, rows[start:end], categoryIDs)
+    if err != nil {
+      // earlier batches are already committed, report where it stopped
+      result.Errors = append(result.Errors, enProduct.CatalogError{
+        Line:    rows[start].Line,
+        Message: fmt.Sprintf("Failed to import lines %d to %d, earlier lines are imported", rows[start].Line, rows[end-1].Line),
+      })
+      return result, nil
+    }
+
+    result.Created += created
+    result.Updated += updated
+    result.Errors = append(result.Errors, rowErrors...)
+  }
+
+  sortCatalogErrors(result.Errors)
+
+  return result, nil
+}
+
+func sortCatalogErrors(rowErrors []enProduct.CatalogError) {
+  sort.SliceStable(rowErrors, func(i, j int) bool {
+    return rowErrors[i].Line < rowErrors[j].Line
+  })
+}
+
+// ExportProducts write the whole catalog in the same format accepted by import
+func (uc *Usecase) ExportProducts(ctx context.Context, format string, w io.Writer) error {
+  switch format {
+  case enProduct.CatalogFormatCSV:
+    writer := csv.NewWriter(w)
+    if err := writer.Write(enProduct.CatalogColumns); err != nil {
+      return err
+    }
+
+    err := uc.productRepo.ExportProducts(ctx, func(row enProduct.CatalogRow) error {
+      return writer.Write(catalogRecord(row))
+    })
+    if err != nil {
+      return fmt.Errorf("Failed to export catalog. err: %w", err)
+    }
+
+    writer.Flush()
+    return writer.Error()
+
+  case enProduct.CatalogFormatJSONL:
+    encoder := json.NewEncoder(w)
+
+    err := uc.productRepo.ExportProducts(ctx, func(row enProduct.CatalogRow) error {
+      return encoder.Encode(row)
+    })
+    if err != nil {
+      return fmt.Errorf("Failed to export catalog. err: %w", err)
+    }
+
+    return nil
+  }
+
+  return apperror.Invalid("format", fmt.Sprintf("Unknown catalog format %s", format))
+}
+
+// validateCatalog check every row and resolve category slugs into ids
+func (uc *Usecase) validateCatalog(ctx context.Context, rows []enProduct.CatalogRow, result *enProduct.ImportResult) (map[string]int64, error) {
+  slugs := make([]string, 0)
+  seen := make(map[string]bool)
+  for _, row := range rows {
+    for _, slug := range row.Categories {
+      if !seen[slug] {
+        seen[slug] = true
+        slugs = append(slugs, slug)
+      }
+    }
+  }
+
+  categories, err := uc.categoryRepo.GetCategoriesBySlugs(ctx, slugs)
+  if err != nil {
+    return nil, fmt.Errorf("Failed to check categories. err: %w", err)
+  }
+
+  categoryIDs := make(map[string]int64, len(categories))
+  for _, category := range categories {
+    categoryIDs[category.Slug] = category.ID
+  }
+
+  skuList := make([]string, 0)
+  for _, row := range rows {
+    if row.SKU != "" {
+      skuList = append(skuList, row.SKU)
+    }
+  }
+
+  // a SKU stays with its product, an import can not move it to another one
+  owners, err := uc.productRepo.GetSKUOwners(ctx, skuList)
+  if err != nil {
+    return nil, fmt.Errorf("Failed to check SKUs. err: %w", err)
+  }
+
+  ownerOf := make(map[string]string, len(owners))
+  for _, owner := range owners {
+    ownerOf[owner.SKU] = owner.Name
+  }
+
+  skus := make(map[string]int)
+  for _, row := range rows {
+    addError := func(field, message string) {
+      result.Errors = append(result.Errors, enProduct.CatalogError{Line: row.Line, Field: field, Message: message})
+    }
+
+    if row.Name == "" {
+      addError("name", "Name is required")
+    }
+
+    if row.Price < 0 {
+      addError("price", "Price can not be negative")
+    }
+
+    if row.Stock < 0 {
+      addError("stock", "Stock can not be negative")
+    }
+
+    if row.VariantPrice != nil && *row.VariantPrice < 0 {
+      addError("variant_price", "Variant price can not be negative")
+    }
+
+    if row.SKU == "" && (len(row.Options) > 0 || row.VariantPrice != nil) {
+      addError("sku", "SKU is required for variant options and price")
+    }
+
+    if row.SKU != "" {
+      if line, ok := skus[row.SKU]; ok {
+        addError("sku", fmt.Sprintf("SKU already used at line %d", line))
+      }
+      skus[row.SKU] = row.Line
+
+      if owner, ok := ownerOf[row.SKU]; ok && !strings.EqualFold(owner, row.Name) {
+        addError("sku", fmt.Sprintf("SKU belongs to product %s", owner))
+      }
+    }
+
+    if len(row.Categories) == 0 {
+      addError("categories", "At least one category is required")
+    }
+
+    for _, slug := range row.Categories {
+      if _, ok := categoryIDs[slug]; !ok {
+        addError("categories", fmt.Sprintf("Category %s not existed", slug))
+      }
+    }
+  }
+
+  return categoryIDs, nil
+}
+
+func parseCatalogCSV(file io.Reader) ([]enProduct.CatalogRow, []enProduct.CatalogError, error) {
+  rows := make([]enProduct.CatalogRow, 0)
+  rowErrors := make([]enProduct.CatalogError, 0)
+
+  reader := csv.NewReader(file)
+  reader.FieldsPerRecord = -1
+  reader.TrimLeadingSpace = true
+
+  header, err := reader.Read()
+  if err != nil {
+    return rows, rowErrors, err
+  }
+
+  columns := make(map[string]int, len(header))
+  for i, name := range header {
+    columns[strings.ToLower(strings.TrimSpace(name))] = i
+  }
+
+  for _, required := range []string{"name", "categories", "price", "stock"} {
+    if _, ok := columns[required]; !ok {
+      return rows, rowErrors, apperror.Invalid("file", fmt.Sprintf("missing column %s", required))
+    }
+  }
+
+  for {
+    record, err := reader.Read()
+    if err == io.EOF {
+      break
+    }
+
+    line, _ := reader.FieldPos(0)
+    if err != nil {
+      rowErrors = append(rowErrors, enProduct.CatalogError{Line: line, Message: err.Error()})
+      continue
+    }
+
+    field := func(name string) string {
+      i, ok := columns[name]
+      if !ok || i >= len(record) {
+        return ""
+      }
+      return strings.TrimSpace(record[i])
+    }
+
+    row, fieldErrors := catalogRowFromCSV(line, field)
+    if len(fieldErrors) > 0 {
+      rowErrors = append(rowErrors, fieldErrors...)
+      continue
+    }
+
+    rows = append(rows, row)
+  }
+
+  return rows, rowErrors, nil
+}
+
+func catalogRowFromCSV(line int, field func(name string) string) (enProduct.CatalogRow, []enProduct.CatalogError) {
+  fieldErrors := make([]enProduct.CatalogError, 0)
+
+  row := enProduct.CatalogRow{
+    Line:       line,
+    Name:       field("name"),
+    SKU:        field("sku"),
+    Categories: make([]string, 0),
+    Options:    enProduct.VariantOptions{},
+  }
+
+  for _, slug := range strings.Split(field("categories"), "|") {
+    if slug = strings.TrimSpace(slug); slug != "" {
+      row.Categories = append(row.Categories, slug)
+    }
+  }
+
+  parseInt := func(name string, target *int64) {
+    value, err := strconv.ParseInt(field(name), 10, 64)
+    if err != nil {
+      fieldErrors = append(fieldErrors, enProduct.CatalogError{Line: line, Field: name, Message: "Must be a number"})
+      return
+    }
+    *target = value
+  }
+
+  parseInt("price", &row.Price)
+  parseInt("stock", &row.Stock)
+
+  if field("variant_price") != "" {
+    row.VariantPrice = new(int64)
+    parseInt("variant_price", row.VariantPrice)
+  }
+
+  // options are written as size=M;colour=red
+  if options := field("options"); options != "" {
+    for _, pair := range strings.Split(options, ";") {
+      key, value, found := strings.Cut(pair, "=")
+      if !found || strings.TrimSpace(key) == "" {
+        fieldErrors = append(fieldErrors, enProduct.CatalogError{Line: line, Field: "options", Message: "Options must be written as key=value;key=value"})
+        break
+      }
+      row.Options[strings.TrimSpace(key)] = strings.TrimSpace(value)
+    }
+  }
+
+  return row, fieldErrors
+}
+
+func parseCatalogJSONL(file io.Reader) ([]enProduct.CatalogRow, []enProduct.CatalogError, error) {
+  rows := make([]enProduct.CatalogRow, 0)
+  rowErrors := make([]enProduct.CatalogError, 0)
+
+  scanner := bufio.NewScanner(file)
+  scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+  line := 0
+  for scanner.Scan() {
+    line++
+
+    text := strings.TrimSpace(scanner.Text())
+    if text == "" {
+      continue
+    }
+
+    row := enProduct.CatalogRow{}
+    if err := json.Unmarshal([]byte(text), &row); err != nil {
+      rowErrors = append(rowErrors, enProduct.CatalogError{Line: line, Message: err.Error()})
+      continue
+    }
+
+    row.Line = line
+    row.Name = strings.TrimSpace(row.Name)
+    rows = append(rows, row)
+  }
+
+  return rows, rowErrors, scanner.Err()
+}
+
+func catalogRecord(row enProduct.CatalogRow) []string {
+  options := make([]string, 0, len(row.Options))
+  for key, value := range row.Options {
+    options = append(options, key+"="+value)
+  }
+  sort.Strings(options)
+
+  variantPrice := ""
+  if row.VariantPrice != nil {
+    variantPrice = strconv.FormatInt(*row.VariantPrice, 10)
+  }
+
+  return []string{
+    row.Name,
+    strings.Join(row.Categories, "|"),
+    strconv.FormatInt(row.Price, 10),
+    strconv.FormatInt(row.Stock, 10),
+    row.SKU,
+    strings.Join(options, ";"),
+    variantPrice,
+  }
+}
@@ -0,0 +1,137 @@
+package product
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	enProduct "ordent/internal/entity/product"
+	"ordent/internal/pkg/apperror"
+	"ordent/internal/pkg/encrypt"
+	"ordent/internal/pkg/imaging"
+)
+
+func (uc *Usecase) UploadImage(ctx context.Context, form enProduct.ImageUpload) (*enProduct.Image, error) {
+  if int64(len(form.Data)) > uc.storageCfg.MaxUploadSize {
+    return nil, apperror.Invalid("image", fmt.Sprintf("Image is larger than %d bytes", uc.storageCfg.MaxUploadSize))
+  }
+
+  contentType, err := imaging.DetectContentType(form.Data)
+  if err != nil {
+    return nil, apperror.Invalid("image", fmt.Sprintf("Image type %s is not supported", contentType))
+  }
+
+  product, err := uc.productRepo.GetProduct(ctx, form.ProductID)
+  if err != nil {
+    return nil, fmt.Errorf("Failed to check product. err: %w", err)
+  }
+
+  if product.ID == 0 {
+    return nil, enProduct.ErrNotFound
+  }
+
+  thumbnail, thumbnailType, err := imaging.Thumbnail(form.Data, uc.storageCfg.ThumbnailWidth)
+  if err != nil {
+    return nil, fmt.Errorf("Failed to read image. err: %w", err)
+  }
+
+  name, err := encrypt.GenerateUUID()
+  if err != nil {
+    return nil, fmt.Errorf("Failed to generate image name. err: %w", err)
+  }
+
+  image := enProduct.Image{
+    ProductID:    form.ProductID,
+    StorageKey:   fmt.Sprintf("products/%d/%s.%s", form.ProductID, name, imaging.Extensions[contentType]),
+    ThumbnailKey: fmt.Sprintf("products/%d/%s_thumb.%s", form.ProductID, name, imaging.Extensions[thumbnailType]),
+    ContentType:  contentType,
+    Size:         int64(len(form.Data)),
+    Position:     -1,
+  }
+  image.URL = uc.storage.URL(image.StorageKey)
+  image.ThumbnailURL = uc.storage.URL(image.ThumbnailKey)
+
+  if form.Position != nil && *form.Position >= 0 {
+    image.Position = *form.Position
+  }
+
+  if err = uc.storage.Put(ctx, image.StorageKey, form.Data, contentType); err != nil {
+    return nil, fmt.Errorf("Failed to store image. err: %w", err)
+  }
+
+  if err = uc.storage.Put(ctx, image.ThumbnailKey, thumbnail, thumbnailType); err != nil {
+    uc.removeBlobs(ctx, image.StorageKey)
+    return nil, fmt.Errorf("Failed to store thumbnail. err: %w", err)
+  }
+
+  image.ID, err = uc.productRepo.InsertImage(ctx, image)
+  if err != nil {
+    uc.removeBlobs(ctx, image.StorageKey, image.ThumbnailKey)
+    return nil, fmt.Errorf("Failed to save image. err: %w", err)
+  }
+
+  return &image, nil
+}
+
+func (uc *Usecase) DeleteImage(ctx context.Context, imageID int64) error {
+  image, err := uc.productRepo.GetImage(ctx, imageID)
+  if err != nil {
+    return fmt.Errorf("Failed to check image. err: %w", err)
+  }
+
+  if image.ID == 0 {
+    return enProduct.ErrImageNotFound
+  }
+
+  err = uc.productRepo.DeleteImage(ctx, *image)
+  if err != nil {
+    return fmt.Errorf("Failed to delete image. err: %w", err)
+  }
+
+  uc.removeBlobs(ctx, image.StorageKey, image.ThumbnailKey)
+
+  return nil
+}
+
+func (uc *Usecase) ReorderImages(ctx context.Context, form enProduct.ImageOrderRequest) error {
+  product, err := uc.productRepo.GetProduct(ctx, form.ProductID)
+  if err != nil {
+    return fmt.Errorf("Failed to check product. err: %w", err)
+  }
+
+  if product.ID == 0 {
+    return enProduct.ErrNotFound
+  }
+
+  if len(form.ImageIDs) != len(product.Images) {
+    return apperror.Invalid("imageIDs", "Image order must list every image of the product")
+  }
+
+  owned := make(map[int64]bool, len(product.Images))
+  for _, image := range product.Images {
+    owned[image.ID] = true
+  }
+
+  for _, id := range form.ImageIDs {
+    if !owned[id] {
+      return apperror.Invalid("imageIDs", fmt.Sprintf("Image %d does not belong to the product", id))
+    }
+    delete(owned, id)
+  }
+
+  err = uc.productRepo.ReorderImages(ctx, form.ProductID, form.ImageIDs)
+  if err != nil {
+    return fmt.Errorf("Failed to reorder images. err: %w", err)
+  }
+
+  return nil
+}
+
+// removeBlobs clean up stored files, failure only leave orphan files behind
+func (uc *Usecase) removeBlobs(ctx context.Context, keys ...string) {
+  for _, key := range keys {
+    if err := uc.storage.Delete(ctx, key); err != nil {
+      log.Printf("[removeBlobs] failed to delete %s. err: %+v", key, err)
+    }
+  }
+}
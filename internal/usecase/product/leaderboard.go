@@ -0,0 +1,136 @@
+package product
+
+import (
+	"context"
+	"fmt"
+
+	enProduct "ordent/internal/entity/product"
+)
+
+const (
+  defaultLeaderboardLimit = 10
+  maxLeaderboardLimit     = 50
+)
+
+// GetBestsellers rank products by units sold since launch, over the whole
+// catalog or the category of the slug and its sub categories
+func (uc *Usecase) GetBestsellers(ctx context.Context, slug string, limit int) ([]enProduct.Ranking, error) {
+  categoryID, err := uc.leaderboardScope(ctx, slug)
+  if err != nil || categoryID < 0 {
+    return make([]enProduct.Ranking, 0), err
+  }
+
+  limit = leaderboardLimit(limit)
+
+  rankings, err := uc.productRepo.GetBestsellers(ctx, categoryID, 2*limit)
+  if err != nil {
+    return make([]enProduct.Ranking, 0), fmt.Errorf("Failed to get bestsellers. err: %w", err)
+  }
+
+  return uc.withProducts(ctx, rankings, limit)
+}
+
+// GetTrending rank products by units sold over the last 24h or 7d
+func (uc *Usecase) GetTrending(ctx context.Context, window, slug string, limit int) ([]enProduct.Ranking, error) {
+  if window == "" {
+    window = enProduct.WindowDay
+  }
+
+  if window != enProduct.WindowDay && window != enProduct.WindowWeek {
+    return make([]enProduct.Ranking, 0), enProduct.ErrUnknownWindow
+  }
+
+  categoryID, err := uc.leaderboardScope(ctx, slug)
+  if err != nil || categoryID < 0 {
+    return make([]enProduct.Ranking, 0), err
+  }
+
+  limit = leaderboardLimit(limit)
+
+  rankings, err := uc.productRepo.GetTrending(ctx, window, categoryID, 2*limit)
+  if err != nil {
+    return make([]enProduct.Ranking, 0), fmt.Errorf("Failed to get trending products. err: %w", err)
+  }
+
+  return uc.withProducts(ctx, rankings, limit)
+}
+
+// leaderboardScope resolve the category slug, 0 for the whole catalog and -1
+// for an unknown category
+func (uc *Usecase) leaderboardScope(ctx context.Context, slug string) (int64, error) {
+  if slug == "" {
+    return 0, nil
+  }
+
+  category, err := uc.categoryRepo.GetCategoryBySlug(ctx, slug)
+  if err != nil {
+    return 0, fmt.Errorf("Failed to get category. err: %w", err)
+  }
+
+  if category.ID == 0 {
+    return -1, nil
+  }
+
+  return category.ID, nil
+}
+
+func leaderboardLimit(limit int) int {
+  if limit <= 0 {
+    return defaultLeaderboardLimit
+  }
+
+  if limit > maxLeaderboardLimit {
+    return maxLeaderboardLimit
+  }
+
+  return limit
+}
+
+// withProducts attach the listing of each ranked product, deleted products
+// are left out and the ranks closed up
+func (uc *Usecase) withProducts(ctx context.Context, rankings []enProduct.Ranking, limit int) ([]enProduct.Ranking, error) {
+  productIDs := make([]int64, len(rankings))
+  for i, ranking := range rankings {
+    productIDs[i] = ranking.ProductID
+  }
+
+  products, err := uc.productRepo.GetProductsByIDs(ctx, productIDs)
+  if err != nil {
+    return make([]enProduct.Ranking, 0), fmt.Errorf("Failed to get products. err: %w", err)
+  }
+
+  byID := make(map[int64]enProduct.Product, len(products))
+  for _, product := range listings(products) {
+    byID[product.ID] = product
+  }
+
+  ranked := make([]enProduct.Ranking, 0, limit)
+  for _, ranking := range rankings {
+    if len(ranked) == limit {
+      break
+    }
+
+    product, ok := byID[ranking.ProductID]
+    if !ok {
+      continue
+    }
+
+    ranking.Rank = len(ranked) + 1
+    ranking.Product = &product
+    ranked = append(ranked, ranking)
+  }
+
+  return ranked, nil
+}
+
+// listings turn product details into listings, the detail stays on
+// /product/one, and fill their current sale price
+func listings(products []enProduct.Product) []enProduct.Product {
+  for i := range products {
+    products[i].Variants, products[i].Availability = nil, nil
+  }
+
+  withSalePrices(products)
+
+  return products
+}
@@ -4,122 +4,468 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"math"
+	"time"
 
+	"ordent/internal/config"
+	enCategory "ordent/internal/entity/category"
+	enPagination "ordent/internal/entity/pagination"
 	enProduct "ordent/internal/entity/product"
+	"ordent/internal/pkg/apperror"
+	"ordent/internal/pkg/cursor"
+	"ordent/internal/pkg/storage"
 )
 
 
 type (
   productRepository interface {
     InsertProduct(ctx context.Context, form enProduct.ProductRequest) (int64, error)
-    UpdateProduct(ctx context.Context, form enProduct.Product) error
-    UpdateSoldAndStock(ctx context.Context, sold, stock int64, productID int64) error 
+    PatchProduct(ctx context.Context, productID int64, patch enProduct.ProductPatch, version int64) error
     DeleteProduct(ctx context.Context, productID int64) error
-    GetProducts(ctx context.Context, limit, offset int) ([]enProduct.Product, error)
-    GetProductsByType(ctx context.Context, limit, offset int, productType enProduct.ProductType) ([]enProduct.Product, error)
+    RestoreProduct(ctx context.Context, productID int64) error
+    PurgeProduct(ctx context.Context, productID int64) error
+    CountOrders(ctx context.Context, productID int64) (int64, error)
+    GetDeletedProduct(ctx context.Context, productID int64) (*enProduct.Product, error)
+    GetDeletedProducts(ctx context.Context, cursor enPagination.Cursor, limit int) ([]enProduct.Product, error)
+    GetProducts(ctx context.Context, cursor enPagination.Cursor, limit int, sort string) ([]enProduct.Product, error)
+    GetProductsByCategory(ctx context.Context, cursor enPagination.Cursor, limit int, sort string, categoryIDs []int64) ([]enProduct.Product, error)
     GetProduct(ctx context.Context, productID int64) (*enProduct.Product, error)
-    SearchProduct(ctx context.Context, query string, limit, offset int) ([]enProduct.Product, error)
+    GetProductsByIDs(ctx context.Context, productIDs []int64) ([]enProduct.Product, error)
+    SearchProduct(ctx context.Context, query string, cursor enPagination.Cursor, limit int, sort string) ([]enProduct.Product, error)
+    InsertVariant(ctx context.Context, form enProduct.VariantRequest) (int64, error)
+    UpdateVariant(ctx context.Context, form enProduct.VariantRequest) error
+    DeleteVariant(ctx context.Context, variant enProduct.Variant) error
+    GetVariant(ctx context.Context, variantID int64) (*enProduct.Variant, error)
+    GetVariantBySKU(ctx context.Context, sku string) (*enProduct.Variant, error)
+    InsertImage(ctx context.Context, image enProduct.Image) (int64, error)
+    DeleteImage(ctx context.Context, image enProduct.Image) error
+    GetImage(ctx context.Context, imageID int64) (*enProduct.Image, error)
+    ReorderImages(ctx context.Context, productID int64, imageIDs []int64) error
+    ImportProducts(ctx context.Context, rows []enProduct.CatalogRow, categoryIDs map[string]int64) (int, int, []enProduct.CatalogError, error)
+    GetSKUOwners(ctx context.Context, skus []string) ([]enProduct.CatalogRow, error)
+    ExportProducts(ctx context.Context, fn func(row enProduct.CatalogRow) error) error
+    GetProductAudits(ctx context.Context, productID int64, cursor enPagination.Cursor, limit int) ([]enProduct.Audit, error)
+    GetPriceAt(ctx context.Context, productID, variantID int64, at time.Time) (int64, bool, error)
+    HasPurchased(ctx context.Context, userID, productID int64) (bool, error)
+    UpsertReview(ctx context.Context, form enProduct.ReviewRequest) (int64, error)
+    ModerateReview(ctx context.Context, productID int64, form enProduct.ModerationRequest) error
+    GetReview(ctx context.Context, reviewID int64) (*enProduct.Review, error)
+    GetReviews(ctx context.Context, productID int64, status string, cursor enPagination.Cursor, limit int) ([]enProduct.Review, error)
+    GetReviewsByStatus(ctx context.Context, status string, cursor enPagination.Cursor, limit int) ([]enProduct.Review, error)
+    GetBestsellers(ctx context.Context, categoryID int64, limit int) ([]enProduct.Ranking, error)
+    GetTrending(ctx context.Context, window string, categoryID int64, limit int) ([]enProduct.Ranking, error)
+    EachBasket(ctx context.Context, maxBasket int, fn func(basket []int64)) error
+    ReplaceRelations(ctx context.Context, relations []enProduct.Relation) error
+    GetRelatedIDs(ctx context.Context, productID int64, limit int) ([]int64, error)
+    TrackView(ctx context.Context, userID, productID int64) error
+    GetRecentlyViewed(ctx context.Context, userID int64, limit int) ([]enProduct.Product, error)
+    SuggestNames(ctx context.Context, prefix string, limit int) ([]string, error)
+    SuggestQueries(ctx context.Context, prefix string, limit int) ([]string, error)
+    LogSearch(ctx context.Context, query string, results int) error
+    GetZeroResultQueries(ctx context.Context, cursor enPagination.Cursor, limit int) ([]enProduct.SearchQuery, error)
+  }
+
+  categoryRepository interface {
+    GetCategoryBySlug(ctx context.Context, slug string) (*enCategory.Category, error)
+    GetCategoriesByIDs(ctx context.Context, categoryIDs []int64) ([]enCategory.Category, error)
+    GetCategoriesBySlugs(ctx context.Context, slugs []string) ([]enCategory.Category, error)
+    GetDescendantIDs(ctx context.Context, categoryID int64) ([]int64, error)
   }
 )
 
+// maxBatchProducts bound the products of one batch request
+const maxBatchProducts = 100
+
 type Usecase struct {
-  productRepo productRepository
+  productRepo  productRepository
+  categoryRepo categoryRepository
+  storage      storage.Storage
+  storageCfg   config.Storage
+  relatedCfg   config.Recommendation
+  cursor       *cursor.Signer
 }
 
 func NewUsecase(
   productRepo productRepository,
+  categoryRepo categoryRepository,
+  storage storage.Storage,
+  storageCfg config.Storage,
+  relatedCfg config.Recommendation,
+  cursor *cursor.Signer,
 ) *Usecase {
   return &Usecase{
-    productRepo: productRepo,
+    productRepo:  productRepo,
+    categoryRepo: categoryRepo,
+    storage:      storage,
+    storageCfg:   storageCfg,
+    relatedCfg:   relatedCfg,
+    cursor:       cursor,
+  }
+}
+
+// deletedProductCursor position a product inside listings sorted by deletion time
+func deletedProductCursor(product enProduct.Product) enPagination.Cursor {
+  return enPagination.Cursor{Key: product.DeletedAt.UnixMicro(), ID: product.ID}
+}
+
+// productCursor position a product inside listings sorted by sold
+func productCursor(product enProduct.Product) enPagination.Cursor {
+  return enPagination.Cursor{Key: product.Sold, ID: product.ID}
+}
+
+// ratingCursor position a product inside listings sorted by average rating,
+// in hundredths like the repository key
+func ratingCursor(product enProduct.Product) enPagination.Cursor {
+  return enPagination.Cursor{Key: int64(math.Round(product.RatingAverage * 100)), ID: product.ID}
+}
+
+// listingSort validate the sort of a product listing, sold by default
+func listingSort(sort string) (string, func(enProduct.Product) enPagination.Cursor, error) {
+  switch sort {
+  case "", enProduct.SortSold:
+    return enProduct.SortSold, productCursor, nil
+  case enProduct.SortRating:
+    return enProduct.SortRating, ratingCursor, nil
   }
+
+  return "", nil, enProduct.ErrUnknownSort
 }
 
 func (uc *Usecase) InsertProduct(ctx context.Context, form enProduct.ProductRequest) (int64, error) {
+  if len(form.CategoryIDs) == 0 {
+    return 0, apperror.Invalid("categoryIDs", "Product requires at least one category")
+  }
+
+  if err := uc.checkCategories(ctx, form.CategoryIDs); err != nil {
+    return 0, err
+  }
+
   productID, err := uc.productRepo.InsertProduct(ctx, form)
   if err != nil {
-    return 0, errors.New(fmt.Sprintf("Failed to insert product. err: %+v", err))
+    return 0, fmt.Errorf("Failed to insert product. err: %w", err)
   }
 
   return productID, nil
 }
 
-func (uc *Usecase) UpdateProduct(ctx context.Context, form enProduct.Product) error {
+// UpdateProduct keep the full update working on top of PatchProduct, stock and
+// sold of the form are ignored
+func (uc *Usecase) UpdateProduct(ctx context.Context, form enProduct.Product) (*enProduct.Product, error) {
+  patch := enProduct.ProductPatch{
+    Name:  &form.Name,
+    Price: &form.Price,
+  }
+
+  if form.CategoryIDs != nil {
+    patch.CategoryIDs = &form.CategoryIDs
+  }
+
+  return uc.PatchProduct(ctx, form.ID, patch, form.Version)
+}
+
+// PatchProduct update the supplied fields of a product. A non zero version
+// must match the current one, otherwise ErrVersionConflict is returned.
+func (uc *Usecase) PatchProduct(ctx context.Context, productID int64, patch enProduct.ProductPatch, version int64) (*enProduct.Product, error) {
+  if patch.Stock != nil || patch.Sold != nil {
+    return nil, apperror.Validation("Stock and sold can not be updated directly")
+  }
+
+  if patch.Name != nil && *patch.Name == "" {
+    return nil, apperror.Invalid("name", "Product name can not be empty")
+  }
+
+  if patch.Price != nil && *patch.Price < 0 {
+    return nil, apperror.Invalid("price", "Product price can not be negative")
+  }
+
   // check existing product
-  product, err := uc.productRepo.GetProduct(ctx, form.ID)
+  product, err := uc.productRepo.GetProduct(ctx, productID)
   if err != nil {
-    return errors.New(fmt.Sprintf("Failed to check product. err: %+v", err))
+    return nil, fmt.Errorf("Failed to check product. err: %w", err)
   }
 
   if product.ID == 0 {
-    return errors.New(fmt.Sprintf("Product not existed"))
+    return nil, enProduct.ErrNotFound
+  }
+
+  if version != 0 && product.Version != version {
+    return nil, enProduct.ErrVersionConflict
+  }
+
+  if patch.CategoryIDs != nil {
+    if len(*patch.CategoryIDs) == 0 {
+      return nil, apperror.Invalid("categoryIDs", "Product requires at least one category")
+    }
+
+    if err = uc.checkCategories(ctx, *patch.CategoryIDs); err != nil {
+      return nil, err
+    }
   }
 
-  err = uc.productRepo.UpdateProduct(ctx, form)
+  err = uc.productRepo.PatchProduct(ctx, productID, patch, version)
   if err != nil {
-    return errors.New(fmt.Sprintf("Failed to update product. err: %+v", err))
+    if errors.Is(err, enProduct.ErrVersionConflict) {
+      return nil, err
+    }
+    return nil, fmt.Errorf("Failed to update product. err: %w", err)
   }
 
-  return nil
+  return uc.GetProduct(ctx, productID)
 }
 
 func (uc *Usecase) DeleteProduct(ctx context.Context, productID int64) error {
   // check existing product
   product, err := uc.productRepo.GetProduct(ctx, productID)
   if err != nil {
-    return errors.New(fmt.Sprintf("Failed to check product. err: %+v", err))
+    return fmt.Errorf("Failed to check product. err: %w", err)
   }
 
   if product.ID == 0 {
-    return errors.New(fmt.Sprintf("Product not existed"))
+    return enProduct.ErrNotFound
   }
 
   err = uc.productRepo.DeleteProduct(ctx, productID)
   if err != nil {
-    return errors.New(fmt.Sprintf("Failed to delete product. err: %+v", err))
+    return fmt.Errorf("Failed to delete product. err: %w", err)
+  }
+
+  return nil
+}
+
+func (uc *Usecase) RestoreProduct(ctx context.Context, productID int64) error {
+  product, err := uc.productRepo.GetDeletedProduct(ctx, productID)
+  if err != nil {
+    return fmt.Errorf("Failed to check product. err: %w", err)
+  }
+
+  if product.ID == 0 {
+    return enProduct.ErrDeletedNotFound
+  }
+
+  err = uc.productRepo.RestoreProduct(ctx, productID)
+  if err != nil {
+    return fmt.Errorf("Failed to restore product. err: %w", err)
+  }
+
+  return nil
+}
+
+// PurgeProduct permanently remove a deleted product that was never ordered
+func (uc *Usecase) PurgeProduct(ctx context.Context, productID int64) error {
+  product, err := uc.productRepo.GetDeletedProduct(ctx, productID)
+  if err != nil {
+    return fmt.Errorf("Failed to check product. err: %w", err)
+  }
+
+  if product.ID == 0 {
+    return enProduct.ErrDeletedNotFound
+  }
+
+  orders, err := uc.productRepo.CountOrders(ctx, productID)
+  if err != nil {
+    return fmt.Errorf("Failed to check order history. err: %w", err)
+  }
+
+  if orders > 0 {
+    return apperror.Conflict("product_has_orders", fmt.Sprintf("Product is part of %d orders and can not be purged", orders))
+  }
+
+  err = uc.productRepo.PurgeProduct(ctx, productID)
+  if err != nil {
+    return fmt.Errorf("Failed to purge product. err: %w", err)
+  }
+
+  for _, image := range product.Images {
+    uc.removeBlobs(ctx, image.StorageKey, image.ThumbnailKey)
   }
 
   return nil
 }
 
+func (uc *Usecase) GetDeletedProducts(ctx context.Context, page enPagination.Request) ([]enProduct.Product, enPagination.Paging, error) {
+  signer := uc.cursor.Scope("deleted-products")
+  position, err := signer.Decode(page.Cursor)
+  if err != nil {
+    return make([]enProduct.Product, 0), enPagination.Paging{}, err
+  }
+
+  limit := cursor.Limit(page.Limit)
+
+  products, err := uc.productRepo.GetDeletedProducts(ctx, position, limit)
+  if err != nil {
+    return make([]enProduct.Product, 0), enPagination.Paging{}, fmt.Errorf("Failed to get deleted products. err: %w", err)
+  }
+
+  products, paging := cursor.Page(signer, position, limit, products, deletedProductCursor)
+
+  return products, paging, nil
+}
+
 func (uc *Usecase) GetProduct(ctx context.Context, productID int64) (*enProduct.Product, error) {
   product, err := uc.productRepo.GetProduct(ctx, productID)
   if err != nil {
-    return nil, errors.New(fmt.Sprintf("Failed to get product. err: %+v", err))
+    return nil, fmt.Errorf("Failed to get product. err: %w", err)
   }
 
+  withVariantMatrix(product)
+  product.WithSalePrice(time.Now())
+
   return product, nil
 }
 
-func (uc *Usecase) GetProducts(ctx context.Context, page, limit int) ([]enProduct.Product, error) {
-  offset := (page - 1) * limit
+// GetProductsByIDs return the detail of several products at once in the
+// requested order, e.g. the items of a cart. Duplicates are returned once,
+// deleted and unknown products are left out.
+func (uc *Usecase) GetProductsByIDs(ctx context.Context, productIDs []int64) ([]enProduct.Product, error) {
+  if len(productIDs) > maxBatchProducts {
+    return make([]enProduct.Product, 0), enProduct.ErrTooManyProducts
+  }
+
+  seen := make(map[int64]bool, len(productIDs))
+  unique := make([]int64, 0, len(productIDs))
+  for _, productID := range productIDs {
+    if !seen[productID] {
+      seen[productID] = true
+      unique = append(unique, productID)
+    }
+  }
 
-  products, err := uc.productRepo.GetProducts(ctx, limit, offset)
+  products, err := uc.productRepo.GetProductsByIDs(ctx, unique)
   if err != nil {
-    return make([]enProduct.Product, 0), errors.New(fmt.Sprintf("Failed to get all products. err: %+v", err))
+    return make([]enProduct.Product, 0), fmt.Errorf("Failed to get products. err: %w", err)
+  }
+
+  now := time.Now()
+  for i := range products {
+    withVariantMatrix(&products[i])
+    products[i].WithSalePrice(now)
   }
 
   return products, nil
 }
 
-func (uc *Usecase) GetProductsByType(ctx context.Context, page, limit int, productType enProduct.ProductType) ([]enProduct.Product, error) {
-  offset := (page - 1) * limit
+func (uc *Usecase) GetProducts(ctx context.Context, page enPagination.Request) ([]enProduct.Product, enPagination.Paging, error) {
+  sort, keyOf, err := listingSort(page.Sort)
+  if err != nil {
+    return make([]enProduct.Product, 0), enPagination.Paging{}, err
+  }
 
-  products, err := uc.productRepo.GetProductsByType(ctx, limit, offset, productType)
+  signer := uc.cursor.Scope("products", sort)
+  position, err := signer.Decode(page.Cursor)
   if err != nil {
-    return make([]enProduct.Product, 0), errors.New(fmt.Sprintf("Failed to get products. err: %+v", err))
+    return make([]enProduct.Product, 0), enPagination.Paging{}, err
   }
 
-  return products, nil
+  limit := cursor.Limit(page.Limit)
+
+  products, err := uc.productRepo.GetProducts(ctx, position, limit, sort)
+  if err != nil {
+    return make([]enProduct.Product, 0), enPagination.Paging{}, fmt.Errorf("Failed to get all products. err: %w", err)
+  }
+
+  withSalePrices(products)
+
+  products, paging := cursor.Page(signer, position, limit, products, keyOf)
+
+  return products, paging, nil
 }
 
-func (uc *Usecase) SearchProduct(ctx context.Context, page, limit int, query string) ([]enProduct.Product, error) {
-  offset := (page - 1) * limit
+// GetProductsByCategory list products of the category with the given slug, including its sub categories
+func (uc *Usecase) GetProductsByCategory(ctx context.Context, page enPagination.Request, slug string) ([]enProduct.Product, enPagination.Paging, error) {
+  sort, keyOf, err := listingSort(page.Sort)
+  if err != nil {
+    return make([]enProduct.Product, 0), enPagination.Paging{}, err
+  }
 
-  products, err := uc.productRepo.SearchProduct(ctx, query, limit, offset)
+  signer := uc.cursor.Scope("category", slug, sort)
+  position, err := signer.Decode(page.Cursor)
   if err != nil {
-    return make([]enProduct.Product, 0), errors.New(fmt.Sprintf("Failed to get products. err: %+v", err))
+    return make([]enProduct.Product, 0), enPagination.Paging{}, err
   }
 
-  return products, nil
+  category, err := uc.categoryRepo.GetCategoryBySlug(ctx, slug)
+  if err != nil {
+    return make([]enProduct.Product, 0), enPagination.Paging{}, fmt.Errorf("Failed to get category. err: %w", err)
+  }
+
+  if category.ID == 0 {
+    return make([]enProduct.Product, 0), enPagination.Paging{}, nil
+  }
+
+  categoryIDs, err := uc.categoryRepo.GetDescendantIDs(ctx, category.ID)
+  if err != nil {
+    return make([]enProduct.Product, 0), enPagination.Paging{}, fmt.Errorf("Failed to get sub categories. err: %w", err)
+  }
+
+  limit := cursor.Limit(page.Limit)
 
+  products, err := uc.productRepo.GetProductsByCategory(ctx, position, limit, sort, categoryIDs)
+  if err != nil {
+    return make([]enProduct.Product, 0), enPagination.Paging{}, fmt.Errorf("Failed to get products. err: %w", err)
+  }
+
+  withSalePrices(products)
+
+  products, paging := cursor.Page(signer, position, limit, products, keyOf)
+
+  return products, paging, nil
+}
+
+func (uc *Usecase) SearchProduct(ctx context.Context, page enPagination.Request, query string) ([]enProduct.Product, enPagination.Paging, error) {
+  sort, keyOf, err := listingSort(page.Sort)
+  if err != nil {
+    return make([]enProduct.Product, 0), enPagination.Paging{}, err
+  }
+
+  signer := uc.cursor.Scope("search", query, sort)
+  position, err := signer.Decode(page.Cursor)
+  if err != nil {
+    return make([]enProduct.Product, 0), enPagination.Paging{}, err
+  }
+
+  limit := cursor.Limit(page.Limit)
+
+  products, err := uc.productRepo.SearchProduct(ctx, query, position, limit, sort)
+  if err != nil {
+    return make([]enProduct.Product, 0), enPagination.Paging{}, fmt.Errorf("Failed to get products. err: %w", err)
+  }
+
+  withSalePrices(products)
+
+  products, paging := cursor.Page(signer, position, limit, products, keyOf)
+
+  if position.IsZero() {
+    uc.logSearch(query, len(products))
+  }
+
+  return products, paging, nil
+}
+
+// withSalePrices fill the current sale price of listed products
+func withSalePrices(products []enProduct.Product) {
+  now := time.Now()
+  for i := range products {
+    products[i].WithSalePrice(now)
+  }
+}
+
+// checkCategories make sure every category id refer to an existing category
+func (uc *Usecase) checkCategories(ctx context.Context, categoryIDs []int64) error {
+  categories, err := uc.categoryRepo.GetCategoriesByIDs(ctx, categoryIDs)
+  if err != nil {
+    return fmt.Errorf("Failed to check categories. err: %w", err)
+  }
+
+  found := make(map[int64]bool, len(categories))
+  for _, category := range categories {
+    found[category.ID] = true
+  }
+
+  for _, id := range categoryIDs {
+    if !found[id] {
+      return apperror.NotFound("category_not_found", fmt.Sprintf("Category %d not existed", id))
+    }
+  }
+
+  return nil
 }
@@ -0,0 +1,124 @@
+package product
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	enProduct "ordent/internal/entity/product"
+)
+
+const (
+  defaultRelatedInterval = 24 * time.Hour
+  defaultRelatedTopN     = 10
+  // maxBasket bound the products of one customer paired together, the most
+  // recent ones are kept
+  maxBasket = 100
+)
+
+// BuildRelated mine the products bought by the same customers from the order
+// history and replace the stored relations, returning how many were stored
+func (uc *Usecase) BuildRelated(ctx context.Context) (int, error) {
+  topN := uc.relatedCfg.TopN
+  if topN <= 0 {
+    topN = defaultRelatedTopN
+  }
+
+  coPurchases := enProduct.NewCoPurchases()
+
+  err := uc.productRepo.EachBasket(ctx, maxBasket, coPurchases.Add)
+  if err != nil {
+    return 0, fmt.Errorf("Failed to read order history. err: %w", err)
+  }
+
+  relations := coPurchases.Top(topN)
+
+  err = uc.productRepo.ReplaceRelations(ctx, relations)
+  if err != nil {
+    return 0, fmt.Errorf("Failed to save related products. err: %w", err)
+  }
+
+  return len(relations), nil
+}
+
+// RunRelatedJob build the related products now and then every Interval until
+// ctx is done
+func (uc *Usecase) RunRelatedJob(ctx context.Context) {
+  interval := uc.relatedCfg.Interval
+  if interval <= 0 {
+    interval = defaultRelatedInterval
+  }
+
+  ticker := time.NewTicker(interval)
+  defer ticker.Stop()
+
+  for {
+    built, err := uc.BuildRelated(ctx)
+    if err != nil {
+      log.Printf("[RunRelatedJob] %+v", err)
+    } else {
+      log.Printf("[RunRelatedJob] stored %d related products", built)
+    }
+
+    select {
+    case <-ctx.Done():
+      return
+    case <-ticker.C:
+    }
+  }
+}
+
+// GetRelated list the products frequently bought with the product, topped up
+// with the bestsellers of its categories when the history is too thin
+func (uc *Usecase) GetRelated(ctx context.Context, productID int64, limit int) ([]enProduct.Product, error) {
+  product, err := uc.productRepo.GetProduct(ctx, productID)
+  if err != nil {
+    return make([]enProduct.Product, 0), fmt.Errorf("Failed to get product. err: %w", err)
+  }
+
+  if product.ID == 0 {
+    return make([]enProduct.Product, 0), enProduct.ErrNotFound
+  }
+
+  limit = leaderboardLimit(limit)
+
+  relatedIDs, err := uc.productRepo.GetRelatedIDs(ctx, productID, limit)
+  if err != nil {
+    return make([]enProduct.Product, 0), fmt.Errorf("Failed to get related products. err: %w", err)
+  }
+
+  seen := map[int64]bool{productID: true}
+  for _, relatedID := range relatedIDs {
+    seen[relatedID] = true
+  }
+
+  for _, category := range product.Categories {
+    if len(relatedIDs) >= limit {
+      break
+    }
+
+    rankings, err := uc.productRepo.GetBestsellers(ctx, category.ID, 2*limit)
+    if err != nil {
+      return make([]enProduct.Product, 0), fmt.Errorf("Failed to get bestsellers. err: %w", err)
+    }
+
+    for _, ranking := range rankings {
+      if len(relatedIDs) >= limit {
+        break
+      }
+
+      if !seen[ranking.ProductID] {
+        seen[ranking.ProductID] = true
+        relatedIDs = append(relatedIDs, ranking.ProductID)
+      }
+    }
+  }
+
+  products, err := uc.productRepo.GetProductsByIDs(ctx, relatedIDs)
+  if err != nil {
+    return make([]enProduct.Product, 0), fmt.Errorf("Failed to get products. err: %w", err)
+  }
+
+  return listings(products), nil
+}
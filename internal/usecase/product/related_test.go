@@ -0,0 +1,116 @@
+package product
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"ordent/internal/config"
+	enProduct "ordent/internal/entity/product"
+)
+
+// relatedRepository serve baskets from fixture data and keep the relations
+// stored by the job, the rest of productRepository is not used by it
+type relatedRepository struct {
+	productRepository
+
+	baskets   [][]int64
+	relations []enProduct.Relation
+	err       error
+}
+
+func (r *relatedRepository) EachBasket(ctx context.Context, maxBasket int, fn func(basket []int64)) error {
+	for _, basket := range r.baskets {
+		if len(basket) > maxBasket {
+			basket = basket[:maxBasket]
+		}
+		fn(basket)
+	}
+
+	return r.err
+}
+
+func (r *relatedRepository) ReplaceRelations(ctx context.Context, relations []enProduct.Relation) error {
+	r.relations = relations
+	return nil
+}
+
+func TestBuildRelated(t *testing.T) {
+	tests := []struct {
+		name    string
+		topN    int
+		baskets [][]int64
+		want    []enProduct.Relation
+	}{
+		{
+			name: "most shared customers first, lowest id on a tie",
+			topN: 2,
+			baskets: [][]int64{
+				{1, 2, 3},
+				{1, 2},
+				{1, 4},
+				{3, 4},
+			},
+			want: []enProduct.Relation{
+				{ProductID: 1, RelatedID: 2, Score: 2, Rank: 1},
+				{ProductID: 1, RelatedID: 3, Score: 1, Rank: 2},
+				{ProductID: 2, RelatedID: 1, Score: 2, Rank: 1},
+				{ProductID: 2, RelatedID: 3, Score: 1, Rank: 2},
+				{ProductID: 3, RelatedID: 1, Score: 1, Rank: 1},
+				{ProductID: 3, RelatedID: 2, Score: 1, Rank: 2},
+				{ProductID: 4, RelatedID: 1, Score: 1, Rank: 1},
+				{ProductID: 4, RelatedID: 3, Score: 1, Rank: 2},
+			},
+		},
+		{
+			name: "default top n and single product baskets",
+			baskets: [][]int64{
+				{5},
+				{5, 6},
+			},
+			want: []enProduct.Relation{
+				{ProductID: 5, RelatedID: 6, Score: 1, Rank: 1},
+				{ProductID: 6, RelatedID: 5, Score: 1, Rank: 1},
+			},
+		},
+		{
+			name:    "no history",
+			baskets: [][]int64{},
+			want:    []enProduct.Relation{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &relatedRepository{baskets: tt.baskets}
+			uc := &Usecase{productRepo: repo, relatedCfg: config.Recommendation{TopN: tt.topN}}
+
+			built, err := uc.BuildRelated(context.Background())
+			if err != nil {
+				t.Fatalf("BuildRelated() err: %v", err)
+			}
+
+			if built != len(tt.want) {
+				t.Errorf("BuildRelated() = %d, want %d", built, len(tt.want))
+			}
+
+			if !reflect.DeepEqual(repo.relations, tt.want) {
+				t.Errorf("stored relations = %+v, want %+v", repo.relations, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildRelatedKeepsRelationsOnError(t *testing.T) {
+	repo := &relatedRepository{baskets: [][]int64{{1, 2}}, err: errors.New("connection reset")}
+	uc := &Usecase{productRepo: repo}
+
+	if _, err := uc.BuildRelated(context.Background()); err == nil {
+		t.Fatal("BuildRelated() want error")
+	}
+
+	if repo.relations != nil {
+		t.Errorf("stored relations = %+v, want none", repo.relations)
+	}
+}
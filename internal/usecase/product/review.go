@@ -0,0 +1,134 @@
+package product
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	enPagination "ordent/internal/entity/pagination"
+	enProduct "ordent/internal/entity/product"
+	"ordent/internal/pkg/apperror"
+	"ordent/internal/pkg/cursor"
+)
+
+const maxReviewLength = 5000
+
+// reviewCursor position a review inside listings sorted by created time
+func reviewCursor(review enProduct.Review) enPagination.Cursor {
+  return enPagination.Cursor{Key: review.CreatedTime.UnixMicro(), ID: review.ID}
+}
+
+// ReviewProduct save the review of a verified buyer, writing again replace
+// the previous review of the user
+func (uc *Usecase) ReviewProduct(ctx context.Context, form enProduct.ReviewRequest) (int64, error) {
+  form.Body = strings.TrimSpace(form.Body)
+
+  if form.Rating < 1 || form.Rating > 5 {
+    return 0, apperror.Invalid("rating", "Rating must be between 1 and 5")
+  }
+
+  if utf8.RuneCountInString(form.Body) > maxReviewLength {
+    return 0, apperror.Invalid("body", fmt.Sprintf("Review can not be longer than %d characters", maxReviewLength))
+  }
+
+  product, err := uc.productRepo.GetProduct(ctx, form.ProductID)
+  if err != nil {
+    return 0, fmt.Errorf("Failed to check product. err: %w", err)
+  }
+
+  if product.ID == 0 {
+    return 0, enProduct.ErrNotFound
+  }
+
+  purchased, err := uc.productRepo.HasPurchased(ctx, form.UserID, form.ProductID)
+  if err != nil {
+    return 0, fmt.Errorf("Failed to check orders. err: %w", err)
+  }
+
+  if !purchased {
+    return 0, enProduct.ErrNotVerifiedBuyer
+  }
+
+  reviewID, err := uc.productRepo.UpsertReview(ctx, form)
+  if err != nil {
+    return 0, fmt.Errorf("Failed to save review. err: %w", err)
+  }
+
+  return reviewID, nil
+}
+
+func (uc *Usecase) ModerateReview(ctx context.Context, form enProduct.ModerationRequest) error {
+  if form.Status != enProduct.ReviewApproved && form.Status != enProduct.ReviewRejected && form.Status != enProduct.ReviewPending {
+    return apperror.Invalid("status", fmt.Sprintf("Unknown review status %s", form.Status))
+  }
+
+  review, err := uc.productRepo.GetReview(ctx, form.ID)
+  if err != nil {
+    return fmt.Errorf("Failed to check review. err: %w", err)
+  }
+
+  if review.ID == 0 {
+    return enProduct.ErrReviewNotFound
+  }
+
+  form.Note = strings.TrimSpace(form.Note)
+
+  err = uc.productRepo.ModerateReview(ctx, review.ProductID, form)
+  if err != nil {
+    return fmt.Errorf("Failed to moderate review. err: %w", err)
+  }
+
+  return nil
+}
+
+// GetReviews list the approved reviews of a product, newest first
+func (uc *Usecase) GetReviews(ctx context.Context, productID int64, page enPagination.Request) ([]enProduct.Review, enPagination.Paging, error) {
+  signer := uc.cursor.Scope("reviews", strconv.FormatInt(productID, 10))
+  position, err := signer.Decode(page.Cursor)
+  if err != nil {
+    return make([]enProduct.Review, 0), enPagination.Paging{}, err
+  }
+
+  limit := cursor.Limit(page.Limit)
+
+  reviews, err := uc.productRepo.GetReviews(ctx, productID, enProduct.ReviewApproved, position, limit)
+  if err != nil {
+    return make([]enProduct.Review, 0), enPagination.Paging{}, fmt.Errorf("Failed to get reviews. err: %w", err)
+  }
+
+  // moderation notes are for admins only
+  for i := range reviews {
+    reviews[i].ModerationNote = ""
+  }
+
+  reviews, paging := cursor.Page(signer, position, limit, reviews, reviewCursor)
+
+  return reviews, paging, nil
+}
+
+// GetReviewsByStatus list the reviews of every product with the status,
+// pending by default for the moderation queue
+func (uc *Usecase) GetReviewsByStatus(ctx context.Context, status string, page enPagination.Request) ([]enProduct.Review, enPagination.Paging, error) {
+  if status == "" {
+    status = enProduct.ReviewPending
+  }
+
+  signer := uc.cursor.Scope("review-queue", status)
+  position, err := signer.Decode(page.Cursor)
+  if err != nil {
+    return make([]enProduct.Review, 0), enPagination.Paging{}, err
+  }
+
+  limit := cursor.Limit(page.Limit)
+
+  reviews, err := uc.productRepo.GetReviewsByStatus(ctx, status, position, limit)
+  if err != nil {
+    return make([]enProduct.Review, 0), enPagination.Paging{}, fmt.Errorf("Failed to get reviews. err: %w", err)
+  }
+
+  reviews, paging := cursor.Page(signer, position, limit, reviews, reviewCursor)
+
+  return reviews, paging, nil
+}
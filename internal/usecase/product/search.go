@@ -0,0 +1,109 @@
+package product
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	enPagination "ordent/internal/entity/pagination"
+	enProduct "ordent/internal/entity/product"
+	"ordent/internal/pkg/cursor"
+)
+
+const (
+  minSuggestLength     = 2
+  defaultSuggestLimit  = 5
+  maxSuggestLimit      = 10
+  maxSearchQueryLength = 100
+  // logSearchTimeout bound the logging of one search, it runs after the
+  // results were served
+  logSearchTimeout = 5 * time.Second
+)
+
+// normalizeQuery lower case the query and collapse its spaces so the same
+// search is counted once however it was typed
+func normalizeQuery(query string) string {
+  return strings.ToLower(strings.Join(strings.Fields(query), " "))
+}
+
+// searchQueryCursor position a query inside the zero results report
+func searchQueryCursor(query enProduct.SearchQuery) enPagination.Cursor {
+  return enPagination.Cursor{Key: query.ZeroResults, ID: query.ID}
+}
+
+// Suggest complete the beginning of a search with product names and popular
+// past searches
+func (uc *Usecase) Suggest(ctx context.Context, prefix string, limit int) (*enProduct.Suggestion, error) {
+  suggestion := &enProduct.Suggestion{
+    Products: make([]string, 0),
+    Queries:  make([]string, 0),
+  }
+
+  prefix = normalizeQuery(prefix)
+  if utf8.RuneCountInString(prefix) < minSuggestLength {
+    return suggestion, nil
+  }
+
+  if limit <= 0 {
+    limit = defaultSuggestLimit
+  }
+
+  if limit > maxSuggestLimit {
+    limit = maxSuggestLimit
+  }
+
+  var err error
+  suggestion.Products, err = uc.productRepo.SuggestNames(ctx, prefix, limit)
+  if err != nil {
+    return nil, fmt.Errorf("Failed to suggest products. err: %w", err)
+  }
+
+  suggestion.Queries, err = uc.productRepo.SuggestQueries(ctx, prefix, limit)
+  if err != nil {
+    return nil, fmt.Errorf("Failed to suggest searches. err: %w", err)
+  }
+
+  return suggestion, nil
+}
+
+// logSearch count a search in the background, only first pages are counted
+// so paging through results is not a new search
+func (uc *Usecase) logSearch(query string, results int) {
+  query = normalizeQuery(query)
+  if query == "" || utf8.RuneCountInString(query) > maxSearchQueryLength {
+    return
+  }
+
+  go func() {
+    ctx, cancel := context.WithTimeout(context.Background(), logSearchTimeout)
+    defer cancel()
+
+    if err := uc.productRepo.LogSearch(ctx, query, results); err != nil {
+      log.Printf("[logSearch] failed to log search. err: %+v", err)
+    }
+  }()
+}
+
+// GetZeroResultQueries list the searches that found nothing for merchandisers,
+// most frequent first
+func (uc *Usecase) GetZeroResultQueries(ctx context.Context, page enPagination.Request) ([]enProduct.SearchQuery, enPagination.Paging, error) {
+  signer := uc.cursor.Scope("zero-results")
+  position, err := signer.Decode(page.Cursor)
+  if err != nil {
+    return make([]enProduct.SearchQuery, 0), enPagination.Paging{}, err
+  }
+
+  limit := cursor.Limit(page.Limit)
+
+  queries, err := uc.productRepo.GetZeroResultQueries(ctx, position, limit)
+  if err != nil {
+    return make([]enProduct.SearchQuery, 0), enPagination.Paging{}, fmt.Errorf("Failed to get searches. err: %w", err)
+  }
+
+  queries, paging := cursor.Page(signer, position, limit, queries, searchQueryCursor)
+
+  return queries, paging, nil
+}
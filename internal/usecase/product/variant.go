@@ -0,0 +1,141 @@
+package product
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	enProduct "ordent/internal/entity/product"
+	"ordent/internal/pkg/apperror"
+)
+
+func (uc *Usecase) InsertVariant(ctx context.Context, form enProduct.VariantRequest) (int64, error) {
+  product, err := uc.productRepo.GetProduct(ctx, form.ProductID)
+  if err != nil {
+    return 0, fmt.Errorf("Failed to check product. err: %w", err)
+  }
+
+  if product.ID == 0 {
+    return 0, enProduct.ErrNotFound
+  }
+
+  if err = uc.validateVariant(ctx, form); err != nil {
+    return 0, err
+  }
+
+  variantID, err := uc.productRepo.InsertVariant(ctx, form)
+  if err != nil {
+    return 0, fmt.Errorf("Failed to insert variant. err: %w", err)
+  }
+
+  return variantID, nil
+}
+
+func (uc *Usecase) UpdateVariant(ctx context.Context, form enProduct.VariantRequest) error {
+  variant, err := uc.productRepo.GetVariant(ctx, form.ID)
+  if err != nil {
+    return fmt.Errorf("Failed to check variant. err: %w", err)
+  }
+
+  if variant.ID == 0 {
+    return enProduct.ErrVariantNotFound
+  }
+
+  // a variant can not be moved to another product
+  form.ProductID = variant.ProductID
+
+  if err = uc.validateVariant(ctx, form); err != nil {
+    return err
+  }
+
+  err = uc.productRepo.UpdateVariant(ctx, form)
+  if err != nil {
+    return fmt.Errorf("Failed to update variant. err: %w", err)
+  }
+
+  return nil
+}
+
+func (uc *Usecase) DeleteVariant(ctx context.Context, variantID int64) error {
+  variant, err := uc.productRepo.GetVariant(ctx, variantID)
+  if err != nil {
+    return fmt.Errorf("Failed to check variant. err: %w", err)
+  }
+
+  if variant.ID == 0 {
+    return enProduct.ErrVariantNotFound
+  }
+
+  if variant.Sold > 0 {
+    return apperror.Conflict("variant_has_sales", "Variant already has sales, set its stock to 0 instead")
+  }
+
+  err = uc.productRepo.DeleteVariant(ctx, *variant)
+  if err != nil {
+    return fmt.Errorf("Failed to delete variant. err: %w", err)
+  }
+
+  return nil
+}
+
+func (uc *Usecase) validateVariant(ctx context.Context, form enProduct.VariantRequest) error {
+  if strings.TrimSpace(form.SKU) == "" {
+    return apperror.Invalid("sku", "SKU is required")
+  }
+
+  if form.Stock < 0 {
+    return apperror.Invalid("stock", "Stock can not be negative")
+  }
+
+  if form.Price != nil && *form.Price < 0 {
+    return apperror.Invalid("price", "Price can not be negative")
+  }
+
+  existing, err := uc.productRepo.GetVariantBySKU(ctx, form.SKU)
+  if err != nil {
+    return fmt.Errorf("Failed to check SKU. err: %w", err)
+  }
+
+  if existing.ID != 0 && existing.ID != form.ID {
+    return apperror.Conflict("sku_exists", "SKU already existed")
+  }
+
+  return nil
+}
+
+// withVariantMatrix fill the effective price and availability of each variant
+// and collect the available values of every option, e.g. size: [L, M, S]
+func withVariantMatrix(product *enProduct.Product) {
+  if len(product.Variants) == 0 {
+    return
+  }
+
+  values := make(map[string]map[string]bool)
+  for i := range product.Variants {
+    variant := &product.Variants[i]
+
+    variant.Price = product.Price
+    if variant.PriceOverride != nil {
+      variant.Price = *variant.PriceOverride
+    }
+    variant.Available = variant.Stock-variant.Reserved > 0
+
+    for option, value := range variant.Options {
+      if values[option] == nil {
+        values[option] = make(map[string]bool)
+      }
+      values[option][value] = true
+    }
+  }
+
+  product.VariantOptions = make(map[string][]string, len(values))
+  for option, set := range values {
+    list := make([]string, 0, len(set))
+    for value := range set {
+      list = append(list, value)
+    }
+    sort.Strings(list)
+    product.VariantOptions[option] = list
+  }
+}
@@ -0,0 +1,32 @@
+package product
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	enProduct "ordent/internal/entity/product"
+)
+
+const maxRecentlyViewed = 20
+
+// TrackView remember the user viewed the product, a failure is only logged
+func (uc *Usecase) TrackView(ctx context.Context, userID, productID int64) {
+  if err := uc.productRepo.TrackView(ctx, userID, productID); err != nil {
+    log.Printf("[TrackView] failed to track view of product %d. err: %+v", productID, err)
+  }
+}
+
+// GetRecentlyViewed list the products the user viewed last, latest first
+func (uc *Usecase) GetRecentlyViewed(ctx context.Context, userID int64, limit int) ([]enProduct.Product, error) {
+  if limit <= 0 || limit > maxRecentlyViewed {
+    limit = maxRecentlyViewed
+  }
+
+  products, err := uc.productRepo.GetRecentlyViewed(ctx, userID, limit)
+  if err != nil {
+    return make([]enProduct.Product, 0), fmt.Errorf("Failed to get recently viewed products. err: %w", err)
+  }
+
+  return listings(products), nil
+}
@@ -0,0 +1,261 @@
+package promotion
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"ordent/internal/config"
+	enPagination "ordent/internal/entity/pagination"
+	enPromotion "ordent/internal/entity/promotion"
+	"ordent/internal/pkg/apperror"
+	"ordent/internal/pkg/cursor"
+)
+
+type (
+	promotionRepository interface {
+		InsertPromotion(ctx context.Context, form enPromotion.PromotionRequest) (int64, error)
+		UpdatePromotion(ctx context.Context, form enPromotion.PromotionRequest) error
+		DeletePromotion(ctx context.Context, promotionID int64) error
+		GetPromotion(ctx context.Context, promotionID int64) (*enPromotion.Promotion, error)
+		GetPromotions(ctx context.Context, cursor enPagination.Cursor, limit int) ([]enPromotion.Promotion, error)
+		GetProductIDs(ctx context.Context, promotionID int64) ([]int64, error)
+		AdvanceSchedule(ctx context.Context) ([]int64, error)
+	}
+
+	productRepository interface {
+		InvalidateProducts(ctx context.Context, productIDs []int64) error
+	}
+)
+
+const defaultSweepInterval = time.Minute
+
+type Usecase struct {
+  promotionRepo promotionRepository
+  productRepo   productRepository
+  promotionCfg  config.Promotion
+  cursor        *cursor.Signer
+}
+
+func NewUsecase(
+  promotionRepo promotionRepository,
+  productRepo productRepository,
+  promotionCfg config.Promotion,
+  cursor *cursor.Signer,
+) *Usecase {
+  return &Usecase{
+    promotionRepo: promotionRepo,
+    productRepo:   productRepo,
+    promotionCfg:  promotionCfg,
+    cursor:        cursor,
+  }
+}
+
+// promotionCursor position a promotion inside listings sorted by created time
+func promotionCursor(promotion enPromotion.Promotion) enPagination.Cursor {
+  return enPagination.Cursor{Key: promotion.CreatedTime.UnixMicro(), ID: promotion.ID}
+}
+
+func (uc *Usecase) InsertPromotion(ctx context.Context, form enPromotion.PromotionRequest) (int64, error) {
+  form.ID = 0
+
+  if err := validate(&form); err != nil {
+    return 0, err
+  }
+
+  promotionID, err := uc.promotionRepo.InsertPromotion(ctx, form)
+  if err != nil {
+    return 0, fmt.Errorf("Failed to insert promotion. err: %w", err)
+  }
+
+  uc.invalidate(ctx, promotionID)
+
+  return promotionID, nil
+}
+
+func (uc *Usecase) UpdatePromotion(ctx context.Context, form enPromotion.PromotionRequest) error {
+  promotion, err := uc.promotionRepo.GetPromotion(ctx, form.ID)
+  if err != nil {
+    return fmt.Errorf("Failed to check promotion. err: %w", err)
+  }
+
+  if promotion.ID == 0 {
+    return enPromotion.ErrNotFound
+  }
+
+  if err = validate(&form); err != nil {
+    return err
+  }
+
+  // products leaving the promotion need a fresh price as well
+  uc.invalidate(ctx, form.ID)
+
+  err = uc.promotionRepo.UpdatePromotion(ctx, form)
+  if err != nil {
+    return fmt.Errorf("Failed to update promotion. err: %w", err)
+  }
+
+  uc.invalidate(ctx, form.ID)
+
+  return nil
+}
+
+func (uc *Usecase) DeletePromotion(ctx context.Context, promotionID int64) error {
+  promotion, err := uc.promotionRepo.GetPromotion(ctx, promotionID)
+  if err != nil {
+    return fmt.Errorf("Failed to check promotion. err: %w", err)
+  }
+
+  if promotion.ID == 0 {
+    return enPromotion.ErrNotFound
+  }
+
+  productIDs, err := uc.promotionRepo.GetProductIDs(ctx, promotionID)
+  if err != nil {
+    return fmt.Errorf("Failed to get promotion products. err: %w", err)
+  }
+
+  err = uc.promotionRepo.DeletePromotion(ctx, promotionID)
+  if err != nil {
+    return fmt.Errorf("Failed to delete promotion. err: %w", err)
+  }
+
+  uc.productRepo.InvalidateProducts(ctx, productIDs)
+
+  return nil
+}
+
+func (uc *Usecase) GetPromotion(ctx context.Context, promotionID int64) (*enPromotion.Promotion, error) {
+  promotion, err := uc.promotionRepo.GetPromotion(ctx, promotionID)
+  if err != nil {
+    return nil, fmt.Errorf("Failed to get promotion. err: %w", err)
+  }
+
+  if promotion.ID == 0 {
+    return nil, enPromotion.ErrNotFound
+  }
+
+  return promotion, nil
+}
+
+func (uc *Usecase) GetPromotions(ctx context.Context, page enPagination.Request) ([]enPromotion.Promotion, enPagination.Paging, error) {
+  signer := uc.cursor.Scope("promotions")
+  position, err := signer.Decode(page.Cursor)
+  if err != nil {
+    return make([]enPromotion.Promotion, 0), enPagination.Paging{}, err
+  }
+
+  limit := cursor.Limit(page.Limit)
+
+  promotions, err := uc.promotionRepo.GetPromotions(ctx, position, limit)
+  if err != nil {
+    return make([]enPromotion.Promotion, 0), enPagination.Paging{}, fmt.Errorf("Failed to get promotions. err: %w", err)
+  }
+
+  promotions, paging := cursor.Page(signer, position, limit, promotions, promotionCursor)
+
+  return promotions, paging, nil
+}
+
+// AdvancePromotions pick up the promotions that started or ended since the
+// last run and drop the cached prices of their products
+func (uc *Usecase) AdvancePromotions(ctx context.Context) (int, error) {
+  promotionIDs, err := uc.promotionRepo.AdvanceSchedule(ctx)
+  if err != nil {
+    return 0, fmt.Errorf("Failed to advance promotions. err: %w", err)
+  }
+
+  for _, promotionID := range promotionIDs {
+    uc.invalidate(ctx, promotionID)
+  }
+
+  return len(promotionIDs), nil
+}
+
+// RunPromotionScheduler advance promotions every SweepInterval until ctx is done
+func (uc *Usecase) RunPromotionScheduler(ctx context.Context) {
+  interval := uc.promotionCfg.SweepInterval
+  if interval <= 0 {
+    interval = defaultSweepInterval
+  }
+
+  ticker := time.NewTicker(interval)
+  defer ticker.Stop()
+
+  for {
+    select {
+    case <-ctx.Done():
+      return
+    case <-ticker.C:
+      advanced, err := uc.AdvancePromotions(ctx)
+      if err != nil {
+        log.Printf("[RunPromotionScheduler] %+v", err)
+      }
+
+      if advanced > 0 {
+        log.Printf("[RunPromotionScheduler] %d promotions started or ended", advanced)
+      }
+    }
+  }
+}
+
+// invalidate drop the cached detail of every product of the promotion, a
+// failure only delays the new price until the cache expires
+func (uc *Usecase) invalidate(ctx context.Context, promotionID int64) {
+  productIDs, err := uc.promotionRepo.GetProductIDs(ctx, promotionID)
+  if err != nil {
+    log.Printf("[invalidate] failed to get products of promotion %d. err: %+v", promotionID, err)
+    return
+  }
+
+  uc.productRepo.InvalidateProducts(ctx, productIDs)
+}
+
+func validate(form *enPromotion.PromotionRequest) error {
+  form.Name = strings.TrimSpace(form.Name)
+
+  if form.Name == "" {
+    return apperror.Invalid("name", "Promotion name is required")
+  }
+
+  if form.StartsAt.IsZero() || form.EndsAt.IsZero() {
+    return apperror.Invalid("startsAt", "Promotion startsAt and endsAt are required")
+  }
+
+  if !form.StartsAt.Before(form.EndsAt) {
+    return apperror.Invalid("startsAt", "Promotion must start before it ends")
+  }
+
+  if len(form.ProductIDs) == 0 && len(form.CategoryIDs) == 0 {
+    return apperror.Invalid("productIDs", "Promotion needs at least one product or category")
+  }
+
+  switch form.Kind {
+  case enPromotion.KindSale:
+    form.BuyQuantity, form.FreeQuantity = nil, nil
+
+    if (form.PercentOff == nil) == (form.SalePrice == nil) {
+      return apperror.Invalid("percentOff", "Sale needs either percentOff or salePrice")
+    }
+
+    if form.PercentOff != nil && (*form.PercentOff <= 0 || *form.PercentOff > 100) {
+      return apperror.Invalid("percentOff", "Percent off must be between 1 and 100")
+    }
+
+    if form.SalePrice != nil && *form.SalePrice < 0 {
+      return apperror.Invalid("salePrice", "Sale price can not be negative")
+    }
+  case enPromotion.KindBundle:
+    form.PercentOff, form.SalePrice = nil, nil
+
+    if form.BuyQuantity == nil || form.FreeQuantity == nil || *form.BuyQuantity <= 0 || *form.FreeQuantity <= 0 {
+      return apperror.Invalid("buyQuantity", "Bundle needs buyQuantity and freeQuantity more than 0")
+    }
+  default:
+    return apperror.Invalid("kind", fmt.Sprintf("Unknown promotion kind %s", form.Kind))
+  }
+
+  return nil
+}
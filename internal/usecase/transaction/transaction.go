@@ -2,77 +2,214 @@ package transaction
 
 import (
 	"context"
-	"errors"
 	"fmt"
+	"log"
+	"ordent/internal/config"
+	enCoupon "ordent/internal/entity/coupon"
+	enInventory "ordent/internal/entity/inventory"
+	enPagination "ordent/internal/entity/pagination"
 	enProduct "ordent/internal/entity/product"
 	enTransaction "ordent/internal/entity/transaction"
+	"ordent/internal/pkg/apperror"
+	"ordent/internal/pkg/cursor"
+	"strconv"
+	"time"
 )
 
 type (
 	transactionRepository interface {
-		CreateTransaction(ctx context.Context, form enTransaction.TransactionRequest) (int64, error)
-		GetTransactionsByUser(ctx context.Context, userID int64) ([]enTransaction.Transaction, error)
-		GetAllTransactions(ctx context.Context) ([]enTransaction.Transaction, error)
+		CreateTransaction(ctx context.Context, form enTransaction.TransactionRequest, allocation enInventory.Allocation) (int64, error)
+		GetTransactionsByUser(ctx context.Context, userID int64, cursor enPagination.Cursor, limit int) ([]enTransaction.Transaction, error)
+		GetAllTransactions(ctx context.Context, cursor enPagination.Cursor, limit int) ([]enTransaction.Transaction, error)
 	}
 
 	productRepository interface {
-		UpdateSoldAndStock(ctx context.Context, sold, stock int64, productID int64) error
     GetProduct(ctx context.Context, productID int64) (*enProduct.Product, error)
+    GetVariantBySKU(ctx context.Context, sku string) (*enProduct.Variant, error)
+    GetReservation(ctx context.Context, reservationID int64) (*enInventory.Reservation, error)
+    RecordSale(ctx context.Context, productID, amount int64) error
+	}
+
+	stockAlerter interface {
+		CheckLowStock(ctx context.Context, productID int64)
+	}
+
+	restockWatcher interface {
+		CheckRestock(ctx context.Context, productID int64)
+	}
+
+	couponPricer interface {
+		Quote(ctx context.Context, code string, userID, productID, subtotal int64) (*enCoupon.Quote, error)
 	}
 )
 
 type Usecase struct {
 	transactionRepo transactionRepository
 	productRepo     productRepository
+	stockAlerter    stockAlerter
+	restockWatcher  restockWatcher
+	couponPricer    couponPricer
+	inventoryCfg    config.Inventory
+	cursor          *cursor.Signer
 }
 
 func NewUsecase(
 	transactionRepo transactionRepository,
 	productRepo productRepository,
+	stockAlerter stockAlerter,
+	restockWatcher restockWatcher,
+	couponPricer couponPricer,
+	inventoryCfg config.Inventory,
+	cursor *cursor.Signer,
 ) *Usecase {
 	return &Usecase{
 		transactionRepo: transactionRepo,
 		productRepo:     productRepo,
+		stockAlerter:    stockAlerter,
+		restockWatcher:  restockWatcher,
+		couponPricer:    couponPricer,
+		inventoryCfg:    inventoryCfg,
+		cursor:          cursor,
 	}
 }
 
+// transactionCursor position a transaction inside listings sorted by created time
+func transactionCursor(transaction enTransaction.Transaction) enPagination.Cursor {
+	return enPagination.Cursor{Key: transaction.CreatedTime.UnixMicro(), ID: transaction.Id}
+}
+
 func (uc *Usecase) CreateTransaction(ctx context.Context, form enTransaction.TransactionRequest) (int64, error) {
+  if form.ReservationID != 0 {
+    reservation, err := uc.productRepo.GetReservation(ctx, form.ReservationID)
+    if err != nil {
+      return 0, fmt.Errorf("Failed to get reservation. err: %w", err)
+    }
+
+    if reservation.ID == 0 || reservation.UserID != form.UserID {
+      return 0, enInventory.ErrReservationNotFound
+    }
+
+    if reservation.Status != enInventory.ReservationHeld {
+      return 0, apperror.Conflict("reservation_not_held", fmt.Sprintf("Reservation is %s", reservation.Status))
+    }
+
+    form.ProductID = reservation.ProductID
+    form.ItemAmount = reservation.Quantity
+    form.SKU = ""
+    if reservation.VariantID != nil {
+      form.VariantID = *reservation.VariantID
+    }
+  }
+
+  if form.ItemAmount <= 0 {
+    return 0, apperror.Invalid("itemAmount", "Item amount must be more than 0")
+  }
+
+  if form.SKU != "" {
+    variant, err := uc.productRepo.GetVariantBySKU(ctx, form.SKU)
+    if err != nil {
+      return 0, fmt.Errorf("Failed to get SKU. err: %w", err)
+    }
+
+    if variant.ID == 0 {
+      return 0, enProduct.ErrSKUNotFound
+    }
+
+    form.ProductID = variant.ProductID
+    form.VariantID = variant.ID
+  }
+
   product, err := uc.productRepo.GetProduct(ctx, form.ProductID)
   if err != nil {
-    return 0, errors.New(fmt.Sprintf("Failed to get product. err: %+v", err))
+    return 0, fmt.Errorf("Failed to get product. err: %w", err)
   }
 
   if product.ID == 0 {
-    return 0, errors.New(fmt.Sprintf("Product not exist. err: %+v", err))
+    return 0, enProduct.ErrNotFound
   }
 
-  transactionID, err := uc.transactionRepo.CreateTransaction(ctx, form)
-  if err != nil {
-    return 0, errors.New(fmt.Sprintf("failed to create transaction. err: %+v", err))
+  if len(product.Variants) > 0 && form.VariantID == 0 {
+    return 0, apperror.Invalid("sku", "Product has variants, SKU is required")
   }
 
-  err = uc.productRepo.UpdateSoldAndStock(ctx, form.ItemAmount, form.ItemAmount, form.ProductID)
+  line := product.Quote(form.VariantID, form.ItemAmount, time.Now())
+  form.UnitPrice = line.UnitPrice
+  form.FreeItems = line.FreeItems
+  form.Subtotal = line.Subtotal
+  form.Total = form.Subtotal
+
+  // the coupon is checked before the order is placed, the repository redeems
+  // it again atomically with the order
+  form.CouponID, form.Discount = 0, 0
+  if form.CouponCode != "" {
+    quote, err := uc.couponPricer.Quote(ctx, form.CouponCode, form.UserID, form.ProductID, form.Subtotal)
+    if err != nil {
+      return 0, err
+    }
+
+    form.CouponID = quote.CouponID
+    form.Discount = quote.Discount
+    form.Total = quote.Total
+  }
+
+  allocation := enInventory.Allocation{
+    Strategy:      uc.inventoryCfg.Allocation,
+    Latitude:      form.Latitude,
+    Longitude:     form.Longitude,
+    ReservationID: form.ReservationID,
+  }
+
+  transactionID, err := uc.transactionRepo.CreateTransaction(ctx, form, allocation)
   if err != nil {
-    return 0, errors.New(fmt.Sprintf("failed to create transaction. err: %+v", err))
+    return 0, fmt.Errorf("failed to create transaction. err: %w", err)
+  }
+
+  uc.stockAlerter.CheckLowStock(ctx, form.ProductID)
+  // a sold out product has to leave stock before its next restock counts
+  uc.restockWatcher.CheckRestock(ctx, form.ProductID)
+
+  // leaderboards are best effort, a failure does not undo the order
+  if err = uc.productRepo.RecordSale(ctx, form.ProductID, form.ItemAmount); err != nil {
+    log.Printf("[CreateTransaction] failed to record sale of product %d. err: %+v", form.ProductID, err)
   }
 
   return transactionID, nil
 }
 
-func (uc *Usecase) GetTransactionsByUser(ctx context.Context, userID int64) ([]enTransaction.Transaction, error) {
-  transactions, err := uc.transactionRepo.GetTransactionsByUser(ctx, userID)
+func (uc *Usecase) GetTransactionsByUser(ctx context.Context, userID int64, page enPagination.Request) ([]enTransaction.Transaction, enPagination.Paging, error) {
+  signer := uc.cursor.Scope("user-transactions", strconv.FormatInt(userID, 10))
+  position, err := signer.Decode(page.Cursor)
   if err != nil {
-    return make([]enTransaction.Transaction, 0), errors.New(fmt.Sprintf("failed to get transactions. err: %+v", err))
+    return make([]enTransaction.Transaction, 0), enPagination.Paging{}, err
   }
 
-  return transactions, nil
+  limit := cursor.Limit(page.Limit)
+
+  transactions, err := uc.transactionRepo.GetTransactionsByUser(ctx, userID, position, limit)
+  if err != nil {
+    return make([]enTransaction.Transaction, 0), enPagination.Paging{}, fmt.Errorf("failed to get transactions. err: %w", err)
+  }
+
+  transactions, paging := cursor.Page(signer, position, limit, transactions, transactionCursor)
+
+  return transactions, paging, nil
 }
 
-func (uc *Usecase) GetAllTransactions(ctx context.Context) ([]enTransaction.Transaction, error) {
-  transactions, err := uc.transactionRepo.GetAllTransactions(ctx)
+func (uc *Usecase) GetAllTransactions(ctx context.Context, page enPagination.Request) ([]enTransaction.Transaction, enPagination.Paging, error) {
+  signer := uc.cursor.Scope("transactions")
+  position, err := signer.Decode(page.Cursor)
   if err != nil {
-    return make([]enTransaction.Transaction, 0), errors.New(fmt.Sprintf("failed to get transactions. err: %+v", err))
+    return make([]enTransaction.Transaction, 0), enPagination.Paging{}, err
   }
 
-  return transactions, nil
+  limit := cursor.Limit(page.Limit)
+
+  transactions, err := uc.transactionRepo.GetAllTransactions(ctx, position, limit)
+  if err != nil {
+    return make([]enTransaction.Transaction, 0), enPagination.Paging{}, fmt.Errorf("failed to get transactions. err: %w", err)
+  }
+
+  transactions, paging := cursor.Page(signer, position, limit, transactions, transactionCursor)
+
+  return transactions, paging, nil
 }
@@ -2,11 +2,12 @@ package user
 
 import (
 	"context"
-	"errors"
 	"fmt"
 	"log"
+	"strings"
 	enUser "ordent/internal/entity/user"
 
+	"ordent/internal/pkg/apperror"
 	"ordent/internal/pkg/encrypt"
 	"ordent/internal/pkg/redigo"
 )
@@ -39,31 +40,37 @@ func NewUsecase(
 
 func (uc *Usecase) RegisterUser(ctx context.Context, form enUser.RegisterForm) (*enUser.RegisterResponse, error) {
 
-	var errs []error
+	var fields []apperror.FieldError
 
 	// Field rule validation
 	if len(form.Username) < 6 {
-		errs = append(errs, errors.New("Username required to be more than 6 chars"))
+		fields = append(fields, apperror.FieldError{Field: "username", Message: "Username required to be more than 6 chars"})
 	}
 
 	if len(form.Password) < 6 {
-		errs = append(errs, errors.New("Password required to be more than 6 chars"))
+		fields = append(fields, apperror.FieldError{Field: "password", Message: "Password required to be more than 6 chars"})
+	}
+
+	// Email is optional, it is where back in stock notifications are sent
+	form.Email = strings.TrimSpace(form.Email)
+	if form.Email != "" && !strings.Contains(form.Email, "@") {
+		fields = append(fields, apperror.FieldError{Field: "email", Message: "Email is not valid"})
+	}
+
+	if len(fields) != 0 {
+		return nil, apperror.Validation(fields[0].Message, fields...)
 	}
 
 	// Check username exist
 	userID, err := uc.userRepo.CheckUsername(ctx, form.Username)
 	if err != nil {
-		return nil, errors.New(fmt.Sprintf("Failed to check username availabilty. err %v", err.Error()))
+		return nil, fmt.Errorf("Failed to check username availabilty. err %w", err)
 	}
 
 	if userID != 0 {
-		errs = append(errs, errors.New("Username already existed"))
+		return nil, apperror.Conflict("username_exists", "Username already existed")
 	}
 
-  if len(errs) != 0 {
-    return nil, errs[0]
-  }
-
 	form.Salt, err = encrypt.GenerateUUID()
 	if err != nil {
 		log.Printf("[RegisterUser] failed to generate UUID. Err: %v", err)
@@ -91,35 +98,35 @@ func (uc *Usecase) RegisterUser(ctx context.Context, form enUser.RegisterForm) (
 
 func (uc *Usecase) Login(ctx context.Context, form enUser.LoginRequest) (*enUser.RegisterResponse, error) {
 
-  var errs []error
+  var fields []apperror.FieldError
 
 	// Field rule validation
 	if len(form.Username) < 6 {
-		errs = append(errs, errors.New("Username required to be more than 6 chars"))
+		fields = append(fields, apperror.FieldError{Field: "username", Message: "Username required to be more than 6 chars"})
 	}
 
 	if len(form.Password) < 6 {
-		errs = append(errs, errors.New("Password required to be more than 6 chars"))
+		fields = append(fields, apperror.FieldError{Field: "password", Message: "Password required to be more than 6 chars"})
 	}
 
+  if len(fields) != 0 {
+    return nil, apperror.Validation(fields[0].Message, fields...)
+  }
+
   user, err := uc.userRepo.GetByUsername(ctx, form.Username)
   if err != nil {
-    return nil, errors.New(fmt.Sprintf("Failed to get user. err %v", err.Error()))
+    return nil, fmt.Errorf("Failed to get user. err %w", err)
   }
 
   if user.ID == 0 {
-    errs = append(errs, errors.New("Username does not exist"))
+    return nil, apperror.New(apperror.KindUnauthorized, "invalid_credentials", "Username does not exist")
   } else if user.Password != encrypt.EncodeSHA1(form.Password+user.Salt) {
-    errs = append(errs, errors.New("Wrong Password"))
-  }
-
-  if len(errs) != 0 {
-    return nil, errs[0]
+    return nil, apperror.New(apperror.KindUnauthorized, "invalid_credentials", "Wrong Password")
   }
 
   token, err := uc.SaveSession(user)
   if err != nil {
-    return nil, errors.New(fmt.Sprintf("Failed to save session. err %v", err.Error()))
+    return nil, fmt.Errorf("Failed to save session. err %w", err)
   }
 
   return &enUser.RegisterResponse{
@@ -133,7 +140,7 @@ func (uc *Usecase) Logout(sess enUser.Session) error {
 
   err := uc.userRepo.RemoveSession(sess)
   if err != nil {
-    return errors.New(fmt.Sprintf("[Logout] Failed to log out. err: %v", err.Error()))
+    return fmt.Errorf("[Logout] Failed to log out. err: %w", err)
   }
 
   return nil
@@ -142,7 +149,7 @@ func (uc *Usecase) Logout(sess enUser.Session) error {
 func (uc *Usecase) GetUserWallet(ctx context.Context, username string) (*enUser.UserWallet, error) {
   user, err := uc.userRepo.GetUserWallet(ctx, username)
   if err != nil {
-    return nil, errors.New(fmt.Sprintf("[GetUserWallet] Failed to get user. err: %v", err.Error()))
+    return nil, fmt.Errorf("[GetUserWallet] Failed to get user. err: %w", err)
   }
 
   return user, nil
@@ -151,7 +158,7 @@ func (uc *Usecase) GetUserWallet(ctx context.Context, username string) (*enUser.
 func (uc *Usecase) AddWallet(ctx context.Context, amount int64, userID int64) error {
   err := uc.userRepo.AddWallet(ctx, amount, userID)
   if err != nil {
-    return errors.New(fmt.Sprintf("[AddWallet] Failed to add wallet. err: %v", err.Error()))
+    return fmt.Errorf("[AddWallet] Failed to add wallet. err: %w", err)
   }
 
   return nil
@@ -0,0 +1,196 @@
+package wishlist
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"ordent/internal/config"
+	enPagination "ordent/internal/entity/pagination"
+	enProduct "ordent/internal/entity/product"
+	enWishlist "ordent/internal/entity/wishlist"
+	"ordent/internal/pkg/cursor"
+	"ordent/internal/pkg/notifier"
+)
+
+type (
+	wishlistRepository interface {
+		InsertItem(ctx context.Context, form enWishlist.ItemRequest) error
+		DeleteItem(ctx context.Context, form enWishlist.ItemRequest) error
+		GetItems(ctx context.Context, userID int64, cursor enPagination.Cursor, limit int) ([]enWishlist.Item, error)
+		SyncRestock(ctx context.Context, productID int64) (int64, error)
+		DeliverNotifications(ctx context.Context, limit int, deliver func(notification enWishlist.Notification) error) (int, error)
+	}
+
+	productRepository interface {
+		GetProduct(ctx context.Context, productID int64) (*enProduct.Product, error)
+	}
+)
+
+const (
+  defaultNotifyInterval = time.Minute
+  defaultBatchSize      = 100
+  // notifyTimeout bound the delivery of one notification
+  notifyTimeout = 30 * time.Second
+)
+
+type Usecase struct {
+  wishlistRepo wishlistRepository
+  productRepo  productRepository
+  notifier     notifier.Notifier
+  wishlistCfg  config.Wishlist
+  cursor       *cursor.Signer
+}
+
+func NewUsecase(
+  wishlistRepo wishlistRepository,
+  productRepo productRepository,
+  notifier notifier.Notifier,
+  wishlistCfg config.Wishlist,
+  cursor *cursor.Signer,
+) *Usecase {
+  return &Usecase{
+    wishlistRepo: wishlistRepo,
+    productRepo:  productRepo,
+    notifier:     notifier,
+    wishlistCfg:  wishlistCfg,
+    cursor:       cursor,
+  }
+}
+
+// itemCursor position an item inside a wishlist sorted by saved time
+func itemCursor(item enWishlist.Item) enPagination.Cursor {
+  return enPagination.Cursor{Key: item.CreatedTime.UnixMicro(), ID: item.ProductID}
+}
+
+func (uc *Usecase) InsertItem(ctx context.Context, form enWishlist.ItemRequest) error {
+  product, err := uc.productRepo.GetProduct(ctx, form.ProductID)
+  if err != nil {
+    return fmt.Errorf("Failed to check product. err: %w", err)
+  }
+
+  if product.ID == 0 {
+    return enProduct.ErrNotFound
+  }
+
+  err = uc.wishlistRepo.InsertItem(ctx, form)
+  if err != nil {
+    return fmt.Errorf("Failed to add to wishlist. err: %w", err)
+  }
+
+  return nil
+}
+
+func (uc *Usecase) DeleteItem(ctx context.Context, form enWishlist.ItemRequest) error {
+  err := uc.wishlistRepo.DeleteItem(ctx, form)
+  if err != nil {
+    return fmt.Errorf("Failed to remove from wishlist. err: %w", err)
+  }
+
+  return nil
+}
+
+func (uc *Usecase) GetItems(ctx context.Context, userID int64, page enPagination.Request) ([]enWishlist.Item, enPagination.Paging, error) {
+  signer := uc.cursor.Scope("wishlist", strconv.FormatInt(userID, 10))
+  position, err := signer.Decode(page.Cursor)
+  if err != nil {
+    return make([]enWishlist.Item, 0), enPagination.Paging{}, err
+  }
+
+  limit := cursor.Limit(page.Limit)
+
+  items, err := uc.wishlistRepo.GetItems(ctx, userID, position, limit)
+  if err != nil {
+    return make([]enWishlist.Item, 0), enPagination.Paging{}, fmt.Errorf("Failed to get wishlist. err: %w", err)
+  }
+
+  items, paging := cursor.Page(signer, position, limit, items, itemCursor)
+
+  return items, paging, nil
+}
+
+// CheckRestock track the product going out of and back in stock right after
+// its stock changed, a restock queues the notifications of its wishlists
+func (uc *Usecase) CheckRestock(ctx context.Context, productID int64) {
+  queued, err := uc.wishlistRepo.SyncRestock(ctx, productID)
+  if err != nil {
+    log.Printf("[CheckRestock] failed to check product %d. err: %+v", productID, err)
+    return
+  }
+
+  if queued > 0 {
+    log.Printf("[CheckRestock] queued %d back in stock notifications for product %d", queued, productID)
+  }
+}
+
+// NotifyRestocks sync every product, catching stock changes without a
+// direct check like imports, then deliver a batch of queued notifications
+func (uc *Usecase) NotifyRestocks(ctx context.Context) (int, error) {
+  if _, err := uc.wishlistRepo.SyncRestock(ctx, 0); err != nil {
+    return 0, fmt.Errorf("Failed to sync restocks. err: %w", err)
+  }
+
+  limit := uc.wishlistCfg.BatchSize
+  if limit <= 0 {
+    limit = defaultBatchSize
+  }
+
+  sent, err := uc.wishlistRepo.DeliverNotifications(ctx, limit, func(notification enWishlist.Notification) error {
+    ctx, cancel := context.WithTimeout(ctx, notifyTimeout)
+    defer cancel()
+
+    return uc.notifier.Notify(ctx, backInStockMessage(notification))
+  })
+  if err != nil {
+    return 0, fmt.Errorf("Failed to deliver notifications. err: %w", err)
+  }
+
+  return sent, nil
+}
+
+// RunRestockNotifier deliver back in stock notifications every
+// NotifyInterval until ctx is done
+func (uc *Usecase) RunRestockNotifier(ctx context.Context) {
+  interval := uc.wishlistCfg.NotifyInterval
+  if interval <= 0 {
+    interval = defaultNotifyInterval
+  }
+
+  ticker := time.NewTicker(interval)
+  defer ticker.Stop()
+
+  for {
+    select {
+    case <-ctx.Done():
+      return
+    case <-ticker.C:
+      sent, err := uc.NotifyRestocks(ctx)
+      if err != nil {
+        log.Printf("[RunRestockNotifier] %+v", err)
+      }
+
+      if sent > 0 {
+        log.Printf("[RunRestockNotifier] sent %d back in stock notifications", sent)
+      }
+    }
+  }
+}
+
+// backInStockMessage address the customer by email when known, otherwise
+// the notifier falls back to its configured recipients
+func backInStockMessage(notification enWishlist.Notification) notifier.Message {
+  msg := notifier.Message{
+    Event:   enWishlist.EventBackInStock,
+    Subject: fmt.Sprintf("Back in stock: %s", notification.ProductName),
+    Body:    fmt.Sprintf("Hi %s, %s from your wishlist is back in stock.", notification.Username, notification.ProductName),
+    Data:    notification,
+  }
+
+  if notification.Email != nil && *notification.Email != "" {
+    msg.To = []string{*notification.Email}
+  }
+
+  return msg
+}
@@ -4,6 +4,7 @@ import (
 	"log"
 	"ordent/internal/app"
 	"ordent/internal/config"
+	"os"
 )
 
 func main() {
@@ -11,6 +12,14 @@ func main() {
 
 	config := getConfig()
 
+	// command line tasks, e.g. catalog import and export
+	if len(os.Args) > 1 {
+		if err := app.RunCommand(config, os.Args[1:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
 	httpServer := app.InitHTTPServer(config)
 
 	httpServer.ListenAndServe()